@@ -0,0 +1,68 @@
+// Package buildinfo reports the version and build metadata of the mag
+// binaries, for identifying exactly which binary produced an export
+
+package buildinfo
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// Version, Commit, and Date may be set at build time, e.g.
+//
+//	go build -ldflags "-X github.com/gavincarr/mag/buildinfo.Version=v1.2.0"
+//
+// Otherwise they are taken from the build information embedded by the
+// go tool, where available.
+var (
+	Version = ""
+	Commit  = ""
+	Date    = ""
+)
+
+// Info returns the version, git commit, and build (or commit) date of
+// the running binary, with "unknown" for any that aren't available
+func Info() (version, commit, date string) {
+	version, commit, date = Version, Commit, Date
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if version == "" && bi.Main.Version != "" {
+			version = bi.Main.Version
+		}
+		// Only mark commits taken from the build information as dirty: a
+		// Commit set with -ldflags says what it is
+		vcsCommit, modified := false, false
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if commit == "" {
+					commit, vcsCommit = s.Value, true
+				}
+			case "vcs.time":
+				if date == "" {
+					date = s.Value
+				}
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+		if vcsCommit && modified {
+			commit += "-dirty"
+		}
+	}
+	if version == "" {
+		version = "unknown"
+	}
+	if commit == "" {
+		commit = "unknown"
+	}
+	if date == "" {
+		date = "unknown"
+	}
+	return version, commit, date
+}
+
+// String returns a version string for tool, including build metadata
+func String(tool string) string {
+	version, commit, date := Info()
+	return fmt.Sprintf("%s %s (commit %s, built %s)", tool, version, commit, date)
+}
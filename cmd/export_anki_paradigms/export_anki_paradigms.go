@@ -0,0 +1,428 @@
+// mag utility to export the paradigms.yml dataset as an Anki-format CSV,
+// with cards for each paradigm cell and/or each full paradigm table.
+// Noun and verb paradigms may give just the noun's entry or the verb's
+// principal parts, to generate their cells.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	flags "github.com/jessevdk/go-flags"
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/gavincarr/mag/buildinfo"
+	"github.com/gavincarr/mag/dataset"
+	"github.com/gavincarr/mag/export"
+	"github.com/gavincarr/mag/logging"
+	"github.com/gavincarr/mag/morph"
+	"github.com/gavincarr/mag/watch"
+)
+
+const (
+	defaultFilename = "paradigms.yml"
+	deckname        = "Mastronarde AtticGreek Paradigms"
+	deckColumnPos   = 5
+	modeCells       = "cells"
+	modeTables      = "tables"
+	modeAll         = "all"
+)
+
+var csvColumns = []string{"ID", "Front", "Back", "Tags", "DeckName"}
+
+// Cell is a single form of a paradigm
+type Cell struct {
+	Key  string // cell key, e.g. "3pl" or "nom sg"
+	Form string
+}
+
+// Cells are the forms of a paradigm, in dataset (table) order
+type Cells []Cell
+
+// UnmarshalYAML decodes a cells mapping, preserving its order
+func (c *Cells) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: cells must be a mapping of cell keys to forms",
+			value.Line)
+	}
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		key, form := value.Content[i], value.Content[i+1]
+		if form.Kind != yaml.ScalarNode {
+			return fmt.Errorf("line %d: cell %q must be a single form",
+				form.Line, key.Value)
+		}
+		*c = append(*c, Cell{Key: key.Value, Form: form.Value})
+	}
+	return nil
+}
+
+// Paradigm is a declension or conjugation table of a word
+type Paradigm struct {
+	Name  string // e.g. "aorist active subjunctive"
+	Lemma string // e.g. "λύω"
+	Noun  string // noun entry to generate cells from, e.g. "λόγος, -ου, ὁ"
+	Verb  string // principal parts to generate cells from, e.g. "λύω, λύσω, ἔλυσα"
+	Cells Cells
+	Smyth string // Smyth grammar section references
+	Ref   string // Mastronarde section/page references
+	Line  int    `yaml:"-"` // source line number
+}
+
+// UnmarshalYAML decodes a Paradigm, recording its source line
+func (p *Paradigm) UnmarshalYAML(value *yaml.Node) error {
+	type paradigm Paradigm
+	err := value.Decode((*paradigm)(p))
+	if err != nil {
+		return err
+	}
+	p.Line = value.Line
+	return nil
+}
+
+type UnitParadigms struct {
+	Name      string
+	Unit      int
+	Paradigms []Paradigm
+}
+
+// Options
+type Options struct {
+	Verbose bool   `short:"v" long:"verbose" description:"display verbose output"`
+	Version func() `long:"version" description:"print version and build information and exit"`
+	Quiet   bool   `short:"q" long:"quiet" description:"do not report export progress on stderr"`
+	logging.Options
+	Unit         int    `short:"u" long:"unit" env:"MAG_UNIT" description:"export only this unit number"`
+	Mode         string `short:"m" long:"mode" choice:"cells" choice:"tables" choice:"all" default:"all" description:"card mode (cells: one card per paradigm cell, e.g. \"aorist active subjunctive 3pl of λύω\"; tables: one card per paradigm, with its full table on the back; all: both)"`
+	DumpNotetype bool   `long:"dump-notetype" description:"print the definition of the notetype used, for creating it in Anki, and exit"`
+	Separator    string `long:"separator" choice:"comma" choice:"semicolon" choice:"tab" default:"comma" env:"MAG_SEPARATOR" description:"CSV field separator"`
+	BOM          bool   `long:"bom" description:"prefix output with a UTF-8 byte order mark (for spreadsheet apps)"`
+	Strict       bool   `long:"strict" description:"treat warnings as errors, aborting the export"`
+	DryRun       bool   `short:"n" long:"dry-run" description:"parse and validate the dataset and print a summary of the cards per deck, without writing any output"`
+	DeckName     string `long:"deckname" env:"MAG_DECKNAME" description:"base Anki deck name (default: \"Mastronarde AtticGreek Paradigms\")"`
+	Watch        bool   `short:"w" long:"watch" description:"watch the datasets and re-export whenever they change"`
+	Outfile      string `short:"o" long:"outfile" env:"MAG_OUTFILE" description:"path to output filename (use stdout if not set)"`
+	Compress     bool   `short:"z" long:"compress" description:"gzip-compress the output (the default if --outfile ends in .gz)"`
+	Args         struct {
+		Filenames []string `positional-arg-name:"filename" description:"paradigms yml datasets or directories to read (- for stdin; default: $MAG_PARADIGMS or paradigms.yml)"`
+	} `positional-args:"yes"`
+}
+
+// declineNoun fills in the cells of the noun paradigm p from its noun
+// entry (and its name and lemma, if unset)
+func declineNoun(p *Paradigm) error {
+	n, err := morph.ParseNoun(p.Noun)
+	if err != nil {
+		return err
+	}
+	decl, err := morph.Decline(n)
+	if err != nil {
+		return err
+	}
+	if p.Name == "" {
+		p.Name = decl.Name
+	}
+	if p.Lemma == "" {
+		p.Lemma = n.Nom
+	}
+	for _, f := range decl.Forms {
+		if f.Uncertain {
+			slog.Warn("generated form may be mis-accented (vowel length not shown by the nominative)",
+				"noun", p.Noun, "cell", f.Cell, "form", f.Form)
+		}
+	}
+	p.Cells = paradigmCells(decl)
+	return nil
+}
+
+// paradigmCells returns the cells of the generated paradigm mp
+func paradigmCells(mp morph.Paradigm) Cells {
+	var cells Cells
+	for _, f := range mp.Forms {
+		cells = append(cells, Cell{Key: f.Cell, Form: f.Form})
+	}
+	return cells
+}
+
+// conjugateVerb returns the paradigms generated from the principal
+// parts of the verb paradigm p: all its present, future, and aorist
+// indicatives, or just the one named by p's name, if set. Paradigms
+// generated are returned even if an error is.
+func conjugateVerb(p Paradigm) ([]Paradigm, error) {
+	v, err := morph.ParseVerb(p.Verb)
+	if err != nil {
+		return nil, err
+	}
+	lemma := p.Lemma
+	if lemma == "" {
+		lemma = strings.Fields(v.Pr)[0]
+	}
+	generated, err := morph.Conjugate(v)
+	var paradigms []Paradigm
+	for _, mp := range generated {
+		if p.Name != "" && mp.Name != p.Name {
+			continue
+		}
+		gp := p
+		gp.Name, gp.Lemma, gp.Cells = mp.Name, lemma, paradigmCells(mp)
+		paradigms = append(paradigms, gp)
+	}
+	if err == nil && len(paradigms) == 0 {
+		err = fmt.Errorf("no %q paradigm generated from %q", p.Name, p.Verb)
+	}
+	return paradigms, err
+}
+
+// expandParadigm returns the paradigms of the dataset paradigm p: p
+// itself, or those generated from its noun or verb if it has no cells
+func expandParadigm(p Paradigm) ([]Paradigm, error) {
+	switch {
+	case len(p.Cells) > 0:
+	case p.Noun != "":
+		if err := declineNoun(&p); err != nil {
+			return nil, err
+		}
+	case p.Verb != "":
+		return conjugateVerb(p)
+	}
+	return []Paradigm{p}, nil
+}
+
+// paradigmRefs returns the reference lines for the card backs of p
+// (as HTML), and their tags
+func paradigmRefs(p Paradigm) (string, []string) {
+	var refs string
+	var tags []string
+	smyth, smythTags := export.SmythRefs(p.Smyth)
+	ref, refTags := export.MastronardeRefs(p.Ref)
+	for _, line := range []string{smyth, ref} {
+		if line != "" {
+			refs += "<br>" + html.EscapeString(line)
+		}
+	}
+	tags = append(tags, smythTags...)
+	tags = append(tags, refTags...)
+	return refs, tags
+}
+
+// joinTags returns the Anki tags string for tag plus any extra tags
+func joinTags(tag string, extra []string) string {
+	return strings.Join(append([]string{tag}, extra...), " ")
+}
+
+// formatTable formats the cells of p as an HTML table
+func formatTable(p Paradigm) string {
+	var b strings.Builder
+	b.WriteString(`<table class="paradigm">`)
+	for _, c := range p.Cells {
+		fmt.Fprintf(&b, "<tr><th>%s</th><td>%s</td></tr>",
+			html.EscapeString(c.Key), html.EscapeString(c.Form))
+	}
+	b.WriteString("</table>")
+	return b.String()
+}
+
+// exportParadigm exports the cards for p to deck: one per cell (e.g.
+// "aorist active subjunctive 3pl of λύω" → "λύσωσι(ν)") and/or one for
+// the full table, depending on mode
+func exportParadigm(cwtr *export.Writer, deck string, p Paradigm, mode string) error {
+	id := p.Lemma + " " + p.Name
+	refs, tags := paradigmRefs(p)
+	if mode != modeTables {
+		for _, c := range p.Cells {
+			front := html.EscapeString(fmt.Sprintf("%s %s of %s", p.Name, c.Key, p.Lemma))
+			back := html.EscapeString(c.Form) + refs
+			err := cwtr.Write([]string{
+				id + " " + c.Key, front, back, joinTags("paradigm::cell", tags), deck,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if mode != modeCells {
+		front := html.EscapeString(fmt.Sprintf("%s of %s", p.Name, p.Lemma))
+		back := formatTable(p) + refs
+		err := cwtr.Write([]string{
+			id, front, back, joinTags("paradigm::table", tags), deck,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportParadigms exports the paradigm units read from dec in Anki CSV
+// format to wtr. Bad entries are skipped, and returned as a
+// dataset.Errors after the export completes.
+func exportParadigms(wtr io.Writer, cwtr *export.Writer, dec *dataset.Decoder, opts Options) error {
+	sep := export.Separators[opts.Separator]
+	idmap := make(map[string]struct{})
+	var errs dataset.Errors
+
+	name := deckname
+	if opts.DeckName != "" {
+		name = opts.DeckName
+	}
+
+	// Output file headers
+	fmt.Fprintln(wtr, "# "+name+" Anki CSV export")
+	fmt.Fprintln(wtr, sep.Header())
+	fmt.Fprintln(wtr, sep.Columns(csvColumns))
+	fmt.Fprintf(wtr, "#notetype:%s\n", export.NotetypeParadigm)
+	fmt.Fprintf(wtr, "#deck column:%d\n", deckColumnPos)
+	fmt.Fprintln(wtr, "#html:true")
+
+	for {
+		var u UnitParadigms
+		err := dec.Decode(&u)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if opts.Unit > 0 && u.Unit != opts.Unit {
+			continue
+		}
+		deck := name + "::" + u.Name
+		entryErr := func(p Paradigm, err error) {
+			entry := p.Lemma + " " + p.Name
+			if p.Lemma == "" {
+				entry = p.Noun + p.Verb
+			}
+			errs = append(errs, &dataset.EntryError{
+				File: dec.Filename(), Line: p.Line,
+				Unit: u.Name, Entry: entry, Err: err,
+			})
+		}
+		var paradigms []Paradigm
+		for _, p := range u.Paradigms {
+			expanded, err := expandParadigm(p)
+			if err != nil {
+				entryErr(p, err)
+			}
+			paradigms = append(paradigms, expanded...)
+		}
+		for _, p := range paradigms {
+			if p.Name == "" || p.Lemma == "" {
+				entryErr(p, errors.New("paradigm missing name or lemma (and noun or verb)"))
+				continue
+			}
+			if len(p.Cells) == 0 {
+				entryErr(p, errors.New("paradigm has no cells"))
+				continue
+			}
+
+			// Make sure ids are unique
+			id := p.Lemma + " " + p.Name
+			if _, exists := idmap[id]; exists {
+				entryErr(p, fmt.Errorf("duplicate paradigm %q", id))
+				continue
+			}
+			idmap[id] = struct{}{}
+
+			err = exportParadigm(cwtr, deck, p, opts.Mode)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	cwtr.Flush()
+	if err := cwtr.Error(); err != nil {
+		return err
+	}
+
+	return errs.Err()
+}
+
+func RunCLI(wtr io.Writer, opts Options) error {
+	if opts.DumpNotetype {
+		notetype, err := export.FindNotetype(export.NotetypeParadigm, export.DefaultStyle())
+		if err != nil {
+			return err
+		}
+		notetype.Write(wtr)
+		return nil
+	}
+	paths := dataset.DefaultPaths(opts.Args.Filenames, defaultFilename)
+	filenames, err := dataset.ExpandPaths(paths)
+	if err != nil {
+		return err
+	}
+	dec := dataset.OpenFiles(filenames)
+	defer dec.Close()
+
+	// In dry-run mode, just report what would be exported
+	out := wtr
+	if opts.DryRun {
+		out = io.Discard
+	}
+	cwtr := export.NewWriter(out, export.Separators[opts.Separator], deckColumnPos)
+	cwtr.Strict = opts.Strict
+	cwtr.Progress = export.StderrProgress(opts.Quiet || opts.DryRun)
+	err = exportParadigms(out, cwtr, dec, opts)
+	cwtr.Progress.Done()
+	if opts.DryRun {
+		cwtr.WriteSummary(wtr)
+	} else {
+		for _, warning := range cwtr.Warnings {
+			slog.Warn(warning)
+		}
+	}
+	return err
+}
+
+// run runs a single export to the configured output
+func run(opts Options) error {
+	wtr, err := export.Create(opts.Outfile, opts.Compress, opts.BOM)
+	if err != nil {
+		return fmt.Errorf("opening outfile: %w", err)
+	}
+	err = RunCLI(wtr, opts)
+	if cerr := wtr.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func main() {
+	// Parse default options are HelpFlag | PrintErrors | PassDoubleDash
+	var opts Options
+	opts.Version = func() {
+		fmt.Println(buildinfo.String("export_anki_paradigms"))
+		os.Exit(0)
+	}
+	parser := flags.NewParser(&opts, flags.Default)
+	_, err := parser.Parse()
+	if err != nil {
+		if flags.WroteHelp(err) {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %s\n\n", err.Error())
+		parser.WriteHelp(os.Stderr)
+		os.Exit(2)
+	}
+	logging.Setup(opts.Options, opts.Verbose)
+
+	// Dry runs only write a summary, to stdout
+	if opts.DryRun {
+		opts.Outfile, opts.Compress, opts.BOM = "", false, false
+	}
+	if opts.Watch {
+		paths := dataset.DefaultPaths(opts.Args.Filenames, defaultFilename)
+		err = watch.Run(paths, func() error { return run(opts) })
+	} else {
+		err = run(opts)
+	}
+	if err != nil {
+		logging.Fatal(err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// alternate is one of the alternate forms of a principal part entry
+type alternate struct {
+	form string // the form, parenthesized if it was in the entry
+	conj string // "or" or "and", joining it to the other alternates
+	rare bool   // marked "(rare)"
+}
+
+// meaning returns the note added to card backs for a, distinguishing
+// same-meaning ("or") and different-meaning ("and") alternates
+func (a alternate) meaning() string {
+	var notes []string
+	switch a.conj {
+	case "and":
+		notes = append(notes, "diff. meaning")
+	case "or":
+		notes = append(notes, "same meaning")
+	}
+	if a.rare {
+		notes = append(notes, "rare")
+	}
+	if len(notes) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(notes, ", ") + ")"
+}
+
+// splitAlternates splits the principal part entry ppstr into its
+// alternate forms, joined by "or" or "and" (e.g. "ἔλιπον or ἔλειψα or
+// ἔλειπα"), dropping any "(stem X-)" annotation. A parenthesized group
+// of alternates (e.g. "(ἔφθην or ἔφθασα)") has each of its forms
+// parenthesized. Each form takes the conjunction preceding it, or (for
+// the first) following it.
+func splitAlternates(ppstr string) ([]alternate, error) {
+	if loc := reStemNote.FindStringIndex(ppstr); loc != nil {
+		ppstr = ppstr[:loc[0]] + ppstr[loc[1]:]
+	}
+
+	var alts []alternate
+	var conj string
+	rare, inParens := false, false
+	for _, tok := range strings.Fields(ppstr) {
+		switch tok {
+		case "or", "and":
+			conj = tok
+			if len(alts) == 1 && alts[0].conj == "" {
+				alts[0].conj = tok
+			}
+			continue
+		case "(rare)":
+			rare = true
+			continue
+		}
+
+		open := strings.HasPrefix(tok, "(")
+		close := strings.HasSuffix(tok, ")")
+		form := strings.TrimSuffix(strings.TrimPrefix(tok, "("), ")")
+		if open {
+			if inParens {
+				return nil, fmt.Errorf("%w: nested parenthesis in alternate %q",
+					errBadEntry, ppstr)
+			}
+			inParens = true
+		}
+		if inParens {
+			form = "(" + form + ")"
+		}
+		if close {
+			if !inParens {
+				return nil, fmt.Errorf("%w: unmatched closing parenthesis in alternate %q",
+					errBadEntry, ppstr)
+			}
+			inParens = false
+		}
+		if form == "" || form == "()" {
+			continue
+		}
+		alts = append(alts, alternate{form: form, conj: conj, rare: rare})
+		rare = false
+	}
+	if inParens {
+		return nil, fmt.Errorf("%w: missing closing parenthesis in alternate %q",
+			errBadEntry, ppstr)
+	}
+	return alts, nil
+}
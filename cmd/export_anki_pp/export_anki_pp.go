@@ -4,45 +4,79 @@
 package main
 
 import (
-	"encoding/csv"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 
 	flags "github.com/jessevdk/go-flags"
 	yaml "gopkg.in/yaml.v3"
+
+	"github.com/gavincarr/mag/buildinfo"
+	"github.com/gavincarr/mag/dataset"
+	"github.com/gavincarr/mag/export"
+	"github.com/gavincarr/mag/greek"
+	"github.com/gavincarr/mag/httpclient"
+	"github.com/gavincarr/mag/logging"
+	"github.com/gavincarr/mag/tts"
+	"github.com/gavincarr/mag/watch"
 )
 
 const (
+	defaultFilename  = "pp.yml"
 	deckname         = "Mastronarde AtticGreek Principal Parts"
-	csvHeader        = "ID,Front,Back,Tags,DeckName"
 	incrementalLabel = "Incr"
 	pp1              = "PPA"
 	pp2              = "PPB"
 	pp3              = "PPC"
 	deckColumnPos    = 5
+	lintCommand      = "lint_pp"
+	formatMnemosyne  = "mnemosyne"
 )
 
 var (
+	csvColumns  = []string{"ID", "Front", "Back", "Tags", "DeckName"}
 	notetypeMap = map[bool]string{
-		false: "MAG PP GrEn",
-		true:  "MAG PP EnGr",
+		false: export.NotetypePPGrEn,
+		true:  export.NotetypePPEnGr,
 	}
-	reAlternates = regexp.MustCompile(`(\()?(\p{Greek}+)\pZ+(or|and)\pZ+(\p{Greek}+)(\))?`)
-	reSpace      = regexp.MustCompile(`\pZ+`)
+	// errBadEntry is wrapped by errors for malformed entries, which are
+	// skipped and reported after the export completes
+	errBadEntry = errors.New("bad entry")
+
+	reSpace = regexp.MustCompile(`\pZ+`)
 )
 
 type Parts struct {
-	Present string `yaml:"pr"`
-	Future  string `yaml:"fu"`
-	Aorist  string `yaml:"ao"`
-	Perfect string `yaml:"pf"`
-	PerfMid string `yaml:"pm"`
-	AorPass string `yaml:"ap"`
+	Present   string            `yaml:"pr"`
+	Future    string            `yaml:"fu"`
+	Aorist    string            `yaml:"ao"`
+	Perfect   string            `yaml:"pf"`
+	PerfMid   string            `yaml:"pm"`
+	AorPass   string            `yaml:"ap"`
+	VClass    string            `yaml:"vclass"` // verb class(es), overriding inference
+	Stems     map[string]string // tense stems by part key (e.g. ao: λαβ-)
+	Irregular *bool             // irregular verb, overriding inference
+	Smyth     string            // Smyth grammar section references
+	Ref       string            // Mastronarde section/page references
+	Line      int               `yaml:"-"` // source line number
+}
+
+// UnmarshalYAML decodes a Parts, recording its source line
+func (p *Parts) UnmarshalYAML(value *yaml.Node) error {
+	type parts Parts
+	err := value.Decode((*parts)(p))
+	if err != nil {
+		return err
+	}
+	p.Line = value.Line
+	return nil
 }
 
 type UnitPP struct {
@@ -51,91 +85,183 @@ type UnitPP struct {
 	PP   []Parts
 }
 
+// cardData is the data passed to --front-template/--back-template: the
+// verb's principal parts, plus its unit name, id, the label and form of
+// the part being tested (if any), and the default card content. The
+// parts fields are raw text, to be escaped in templates with the html
+// function where the card is HTML.
+type cardData struct {
+	Parts
+	Unit  string
+	ID    string
+	Label string
+	Part  string
+	Front string
+	Back  string
+}
+
+// cardTemplater applies any front/back templates to a card for the
+// principal part label and form part, returning the final front and back
+type cardTemplater func(label, part, front, back string) (string, string, error)
+
 // Options
 type Options struct {
-	Verbose     bool   `short:"v" long:"verbose" description:"display verbose output"`
-	Unit        int    `short:"u" long:"unit" description:"export only this unit number"`
-	Incremental bool   `short:"i" long:"incr" description:"split into incremental subdecks of pp 1-3,6,4-5"`
-	Reverse     bool   `short:"r" long:"rev" description:"export in reverse output format i.e. English-to-Greek"`
-	Outfile     string `short:"o" long:"outfile" description:"path to output filename (use stdout if not set)"`
-	Args        struct {
-		Filename string `description:"pp yml dataset to read" default:"pp.yml"`
+	Verbose bool   `short:"v" long:"verbose" description:"display verbose output"`
+	Version func() `long:"version" description:"print version and build information and exit"`
+	Quiet   bool   `short:"q" long:"quiet" description:"do not report export progress on stderr"`
+	logging.Options
+	httpclient.Config
+	Unit            int    `short:"u" long:"unit" env:"MAG_UNIT" description:"export only this unit number"`
+	Incremental     bool   `short:"i" long:"incr" description:"split into incremental subdecks of pp 1-3,6,4-5"`
+	Groups          string `long:"groups" env:"MAG_PP_GROUPS" description:"incremental subdeck grouping of principal parts, implying --incr (e.g. \"A=fu,ao;B=ap;C=pf,pm\"; parts not listed are not exported)"`
+	Mode            string `short:"m" long:"mode" choice:"parts" choice:"combined" choice:"chant" choice:"cloze" choice:"stems" default:"parts" description:"card mode (parts: one card per principal part; combined: one card per verb, with all its parts on the back; chant: one card per verb, with its parts as a recitation chant on the back; cloze: one cloze note per verb, with a deletion for each part; stems: one card per tense stem, from \"(stem X-)\" annotations or the part's ending and augment, warning of irregular or compound aorists needing an annotation)"`
+	TTSEngine       string `long:"tts-engine" choice:"espeak" choice:"azure" choice:"command" env:"MAG_TTS_ENGINE" description:"text-to-speech engine for generating chant audio in --mode chant (espeak: offline, using espeak-ng; azure: Azure Speech, configured by $AZURE_SPEECH_KEY and $AZURE_SPEECH_REGION; command: --audio-cmd)"`
+	Voice           string `long:"voice" env:"MAG_TTS_VOICE" description:"text-to-speech voice (default: engine default, e.g. grc for espeak, el-GR-NestorasNeural for azure)"`
+	AudioCmd        string `long:"audio-cmd" env:"MAG_AUDIO_CMD" description:"text-to-speech command for --tts-engine command (implied if set), with {text} and {file} placeholders"`
+	AudioFilename   string `long:"audio-filename" env:"MAG_AUDIO_FILENAME" default:"mag-pp-chant-{{.ID}}.wav" description:"Go text/template for generated audio filenames, executed with .ID and .Text"`
+	MediaDir        string `long:"media-dir" env:"MAG_MEDIA_DIR" default:"media" description:"directory to write generated audio files to, with a manifest"`
+	Offline         bool   `long:"offline" env:"MAG_OFFLINE" description:"don't access the network: with a network --tts-engine (azure), use only audio files already in --media-dir, warning of cards whose audio would need generating"`
+	PruneMedia      bool   `long:"prune-media" description:"remove media files from --media-dir generated by the previous export but no longer referenced"`
+	Typed           bool   `long:"typed" description:"export notes for a type-in-the-answer notetype, requiring the back of each card to be typed"`
+	DumpNotetype    bool   `long:"dump-notetype" description:"print the definition of the notetype used with the given options, for creating it in Anki, and exit"`
+	FrontTemplate   string `long:"front-template" description:"Go text/template file for the card front, executed with the principal parts fields, .Unit, .ID, .Label, .Part, and the default .Front and .Back"`
+	BackTemplate    string `long:"back-template" description:"Go text/template file for the card back, executed with the principal parts fields, .Unit, .ID, .Label, .Part, and the default .Front and .Back"`
+	OnlyIrregular   bool   `long:"only-irregular" description:"export only irregular verbs (set by irregular: true, or inferred from suppletive stems)"`
+	Format          string `short:"f" long:"format" choice:"anki" choice:"mnemosyne" default:"anki" env:"MAG_FORMAT" description:"output format (anki: Anki CSV; mnemosyne: Mnemosyne 1.x XML, with a category per deck, for File > Import in Mnemosyne 2)"`
+	Reverse         bool   `short:"r" long:"rev" description:"export in reverse output format i.e. English-to-Greek"`
+	Sort            string `short:"s" long:"sort" choice:"alpha" env:"MAG_SORT" description:"sort entries within each unit (alpha: Greek dictionary order)"`
+	Separator       string `long:"separator" choice:"comma" choice:"semicolon" choice:"tab" default:"comma" env:"MAG_SEPARATOR" description:"CSV field separator"`
+	BOM             bool   `long:"bom" description:"prefix output with a UTF-8 byte order mark (for spreadsheet apps)"`
+	Strict          bool   `long:"strict" description:"treat warnings as errors, aborting the export"`
+	DryRun          bool   `short:"n" long:"dry-run" description:"parse and validate the dataset and print a summary of the cards per deck, without writing any output"`
+	DeckName        string `long:"deckname" env:"MAG_DECKNAME" description:"base Anki deck name, before the direction suffix (default: \"Mastronarde AtticGreek Principal Parts\")"`
+	DeckDescription string `long:"deck-description" env:"MAG_DECK_DESCRIPTION" description:"Markdown file (or yml file with description and new_per_day fields) giving the description and options of the exported decks, for --decks-file"`
+	NewPerDay       int    `long:"new-per-day" env:"MAG_NEW_PER_DAY" description:"new cards per day for the exported decks, for --decks-file (overrides new_per_day in --deck-description)"`
+	DecksFile       string `long:"decks-file" env:"MAG_DECKS_FILE" description:"write a JSON file listing the exported decks with their description and options, for applying in Anki after import (which CSV import can't do)"`
+	Watch           bool   `short:"w" long:"watch" description:"watch the datasets and re-export whenever they change"`
+	Outfile         string `short:"o" long:"outfile" env:"MAG_OUTFILE" description:"path to output filename (use stdout if not set)"`
+	Compress        bool   `short:"z" long:"compress" description:"gzip-compress the output (the default if --outfile ends in .gz)"`
+	Args            struct {
+		Filenames []string `positional-arg-name:"filename" description:"pp yml datasets or directories to read (- for stdin; default: $MAG_PP or pp.yml)"`
 	} `positional-args:"yes"`
 }
 
 func exportSingleEntry(
-	cwtr *csv.Writer,
-	deck, id, label, ppstr, conj string,
+	cwtr *export.Writer,
+	deck, id, label, ppstr, meaning string,
 	n int,
+	stem string,
+	verb verbInfo,
 	reverse bool,
+	apply cardTemplater,
 ) error {
 	labeltag := reSpace.ReplaceAllString(strings.ToLower(label), "_")
-	tagstr := "pp::" + labeltag
+	tagstr := joinTags("pp::"+labeltag, verb.tags)
 
 	nstr := ""
 	if n > 0 {
 		nstr = fmt.Sprintf(" #%d", n)
 	}
-	meaning := ""
-	switch conj {
-	case "and":
-		meaning = " (diff. meaning)"
-	case "or":
-		meaning = " (same meaning)"
-	}
-	back := fmt.Sprintf("%s%s of %s%s", label, nstr, id, meaning)
+	back := fmt.Sprintf("%s%s of %s%s%s", label, nstr, id, verb.note(), meaning)
 
-	var err error
-	if !reverse {
-		err = cwtr.Write([]string{ppstr, ppstr, back, tagstr, deck})
-	} else {
-		err = cwtr.Write([]string{ppstr, back, ppstr, tagstr, deck})
+	front := ppstr
+	if reverse {
+		front, back = back, ppstr
 	}
+	// Parts cards are plain text, so the stem is set off with a dash
+	if stem != "" {
+		back += " — stem " + stem
+	}
+	back += verb.refsText()
+	front, back, err := apply(label, ppstr, front, back)
 	if err != nil {
 		return err
 	}
+	return cwtr.Write([]string{ppstr, front, back, tagstr, deck})
+}
 
-	return nil
+// verbInfo is the information derived about a verb for its cards
+type verbInfo struct {
+	tags     []string // extra tags
+	deponent bool     // middle-only present
+	refs     []string // reference lines for card backs
+}
+
+// newVerbInfo returns the verbInfo for a verb of the given classes,
+// tagged with vclass:: tags, and dep::true if deponent
+func newVerbInfo(classes []string) verbInfo {
+	var v verbInfo
+	for _, class := range classes {
+		v.tags = append(v.tags, "vclass::"+class)
+		if class == greek.VerbDeponent {
+			v.deponent = true
+		}
+	}
+	if v.deponent {
+		v.tags = append(v.tags, "dep::true")
+	}
+	return v
+}
+
+// note returns the note added to card backs after the verb id, marking
+// deponents so their missing active parts aren't confusing
+func (v verbInfo) note() string {
+	if v.deponent {
+		return " (deponent)"
+	}
+	return ""
+}
+
+// refsText returns the reference lines of v for plain text card backs
+func (v verbInfo) refsText() string {
+	if len(v.refs) == 0 {
+		return ""
+	}
+	return " — " + strings.Join(v.refs, "; ")
+}
+
+// refsHTML returns the reference lines of v for HTML card backs
+func (v verbInfo) refsHTML() string {
+	var s string
+	for _, ref := range v.refs {
+		s += "<br>" + html.EscapeString(ref)
+	}
+	return s
+}
+
+// joinTags returns the Anki tags string for tag plus any extra tags
+func joinTags(tag string, extra []string) string {
+	return strings.Join(append([]string{tag}, extra...), " ")
 }
 
 func exportEntry(
-	cwtr *csv.Writer,
+	cwtr *export.Writer,
 	deckslice []string,
-	id, label, ppstr string,
+	id, label, ppstr, stem string,
+	verb verbInfo,
 	reverse bool,
+	apply cardTemplater,
 ) error {
 	if id == "" {
-		return fmt.Errorf("empty id for %q %q", label, ppstr)
+		return fmt.Errorf("%w: empty id for %q %q", errBadEntry, label, ppstr)
 	}
 	deck := strings.Join(deckslice, "::")
-	matches := reAlternates.FindStringSubmatch(ppstr)
-	if matches == nil {
-		return exportSingleEntry(cwtr, deck, id, label, ppstr, "", 0, reverse)
-	}
-
-	paren1 := matches[1]
-	part1 := matches[2]
-	conj := matches[3]
-	part2 := matches[4]
-	paren2 := matches[5]
-
-	if paren1 != "" {
-		if paren2 == "" {
-			log.Fatal("missing paren2 in alternate:", ppstr)
-		}
-		part1 = "(" + part1 + ")"
-		part2 = "(" + part2 + ")"
-	}
-
-	err := exportSingleEntry(cwtr, deck, id, label, part1, conj, 1, reverse)
+	alts, err := splitAlternates(ppstr)
 	if err != nil {
 		return err
 	}
-	err = exportSingleEntry(cwtr, deck, id, label, part2, conj, 2, reverse)
-	if err != nil {
-		return err
+	if len(alts) < 2 || alts[0].conj == "" {
+		return exportSingleEntry(cwtr, deck, id, label, ppstr, "", 0, stem, verb, reverse, apply)
 	}
 
+	// Export a numbered card for each alternate
+	for i, alt := range alts {
+		err = exportSingleEntry(cwtr, deck, id, label, alt.form, alt.meaning(), i+1,
+			stem, verb, reverse, apply)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -144,40 +270,97 @@ func formatDeckname(opts Options) string {
 	if opts.Reverse {
 		direction = "EnGr"
 	}
+	name := deckname
+	if opts.DeckName != "" {
+		name = opts.DeckName
+	}
 	if !opts.Incremental {
-		return fmt.Sprintf("%s (%s)", deckname, direction)
+		return fmt.Sprintf("%s (%s)", name, direction)
+	}
+	return fmt.Sprintf("%s (%s,%s)", name, incrementalLabel, direction)
+}
+
+// formatNotetype returns the name of the notetype for opts
+func formatNotetype(opts Options) string {
+	if opts.Mode == modeCloze {
+		return export.NotetypePPCloze
+	}
+	if opts.Typed {
+		return notetypeMap[opts.Reverse] + export.TypedSuffix
 	}
-	return fmt.Sprintf("%s (%s,%s)", deckname, incrementalLabel, direction)
+	return notetypeMap[opts.Reverse]
 }
 
 func formatComment(deckname string) string {
 	return "# " + deckname + " Anki CSV export"
 }
 
-// exportPP exports principal parts in Anki CSV format to wtr
-func exportPP(wtr io.Writer, upp []UnitPP, opts Options) error {
-	cwtr := csv.NewWriter(wtr)
+// sortKey returns the form used to order pp in dictionary order
+// (the present, or the aorist for verbs without a present)
+func sortKey(pp Parts) string {
+	if pp.Present != "" {
+		return pp.Present
+	}
+	return pp.Aorist
+}
+
+// sortParts returns a copy of parts sorted in Greek dictionary order
+func sortParts(parts []Parts) []Parts {
+	sorted := make([]Parts, len(parts))
+	copy(sorted, parts)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return greek.Less(sortKey(sorted[i]), sortKey(sorted[j]))
+	})
+	return sorted
+}
+
+// exportPP exports the principal parts units read from dec in Anki CSV
+// format to wtr. Bad entries are skipped, and returned as a
+// dataset.Errors after the export completes.
+func exportPP(wtr io.Writer, cwtr *export.Writer, dec *dataset.Decoder, groups map[string]string, tmpl *export.Templates, audio *export.Audio, opts Options) error {
+	sep := export.Separators[opts.Separator]
 	idmap := make(map[string]struct{})
+	var errs dataset.Errors
 
 	deckname := formatDeckname(opts)
 	comment := formatComment(deckname)
-	notetype := notetypeMap[opts.Reverse]
+	notetype := formatNotetype(opts)
+	columns := csvColumns
+	if opts.Mode == modeCloze {
+		columns = clozeColumns
+	}
 
 	// Output file headers
 	fmt.Fprintln(wtr, comment)
-	fmt.Fprintln(wtr, "#separator:Comma")
-	fmt.Fprintf(wtr, "#columns:%s\n", csvHeader)
+	fmt.Fprintln(wtr, sep.Header())
+	fmt.Fprintln(wtr, sep.Columns(columns))
 	fmt.Fprintf(wtr, "#notetype:%s\n", notetype)
 	fmt.Fprintf(wtr, "#deck column:%d\n", deckColumnPos)
-	fmt.Fprintln(wtr, "#html:false")
+	if opts.Mode == modeParts {
+		fmt.Fprintln(wtr, "#html:false")
+	} else {
+		fmt.Fprintln(wtr, "#html:true")
+	}
 
 	// Output pp entries
-	for _, u := range upp {
+	for {
+		var u UnitPP
+		err := dec.Decode(&u)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
 		deckslice := []string{deckname, u.Name}
 		if opts.Incremental {
 			deckslice = []string{deckname, pp1, u.Name}
 		}
-		for _, pp := range u.PP {
+		parts := u.PP
+		if opts.Sort == "alpha" {
+			parts = sortParts(parts)
+		}
+		for _, pp := range parts {
 			if opts.Unit > 0 && u.Unit != opts.Unit {
 				continue
 			}
@@ -189,32 +372,126 @@ func exportPP(wtr io.Writer, upp []UnitPP, opts Options) error {
 			}
 			if id != "" {
 				if _, exists := idmap[id]; exists {
-					log.Fatal("duplicate ids found: ", id)
+					errs = append(errs, &dataset.EntryError{
+						File: dec.Filename(), Line: pp.Line,
+						Unit: u.Name, Entry: id,
+						Err: fmt.Errorf("duplicate id %q (run %s for a full report)",
+							id, lintCommand),
+					})
+					continue
 				}
 				idmap[id] = struct{}{}
 			}
 
-			// Export entries for each principal part
-			var err error
-			if pp.Future != "" {
-				if opts.Incremental {
-					deckslice[1] = pp1
+			// Tag cards with the verb class(es)
+			classes := greek.VerbClasses(pp.Present)
+			if pp.VClass != "" {
+				classes, err = greek.ParseVerbClasses(pp.VClass)
+				if err != nil {
+					errs = append(errs, &dataset.EntryError{
+						File: dec.Filename(), Line: pp.Line,
+						Unit: u.Name, Entry: id, Err: err,
+					})
+					continue
+				}
+			}
+			verb := newVerbInfo(classes)
+			if line, tags := export.SmythRefs(pp.Smyth); line != "" {
+				verb.refs = append(verb.refs, line)
+				verb.tags = append(verb.tags, tags...)
+			}
+			if line, tags := export.MastronardeRefs(pp.Ref); line != "" {
+				verb.refs = append(verb.refs, line)
+				verb.tags = append(verb.tags, tags...)
+			}
+			if isIrregular(pp) {
+				verb.tags = append(verb.tags, "irregular::true")
+			} else if opts.OnlyIrregular {
+				continue
+			}
+
+			// checkErr records bad entry errors, returning any others
+			checkErr := func(err error) error {
+				if errors.Is(err, errBadEntry) {
+					errs = append(errs, &dataset.EntryError{
+						File: dec.Filename(), Line: pp.Line,
+						Unit: u.Name, Entry: sortKey(pp), Err: err,
+					})
+					return nil
+				}
+				return err
+			}
+
+			// apply applies any templates to a card for pp
+			apply := func(label, part, front, back string) (string, string, error) {
+				data := cardData{
+					Parts: pp, Unit: u.Name, ID: id,
+					Label: label, Part: part, Front: front, Back: back,
+				}
+				front, back, err := tmpl.Apply(data, front, back)
+				if err != nil {
+					return "", "", fmt.Errorf("%s: %w", id, err)
+				}
+				return front, back, nil
+			}
+
+			switch opts.Mode {
+			case modeCombined:
+				err := checkErr(exportCombined(cwtr, deckslice, id, pp, verb, apply))
+				if err != nil {
+					return err
+				}
+				continue
+			case modeStems:
+				err := checkErr(exportStems(cwtr, deckslice, id, pp, verb, apply))
+				if err != nil {
+					return err
+				}
+				continue
+			case modeCloze:
+				err := checkErr(exportCloze(cwtr, deckslice, id, pp, verb))
+				if err != nil {
+					return err
+				}
+				continue
+			case modeChant:
+				err := checkErr(exportChant(cwtr, deckslice, id, pp, verb, audio, apply))
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			// inGroup sets the incremental subdeck for the part key,
+			// returning false if it is not in any group
+			inGroup := func(key string) bool {
+				if !opts.Incremental {
+					return true
 				}
-				err = exportEntry(cwtr, deckslice, id, "Future",
-					pp.Future, opts.Reverse)
+				deckslice[1] = groups[key]
+				return deckslice[1] != ""
+			}
+
+			// Export entries for each principal part, with any stems
+			// (except for typed answers, which must match exactly)
+			stems := pp.Stems
+			if opts.Typed {
+				stems = nil
+			}
+			var err error
+			if pp.Future != "" && inGroup("fu") {
+				err = checkErr(exportEntry(cwtr, deckslice, id, "Future",
+					pp.Future, stems["fu"], verb, opts.Reverse, apply))
 				if err != nil {
 					return err
 				}
 			}
-			if pp.Aorist != "" {
+			if pp.Aorist != "" && inGroup("ao") {
 				if id == pp.Aorist {
 					id = pp.Future
 				}
-				if opts.Incremental {
-					deckslice[1] = pp1
-				}
-				err = exportEntry(cwtr, deckslice, id, "Aorist",
-					pp.Aorist, opts.Reverse)
+				err = checkErr(exportEntry(cwtr, deckslice, id, "Aorist",
+					pp.Aorist, stems["ao"], verb, opts.Reverse, apply))
 				if err != nil {
 					return err
 				}
@@ -222,32 +499,23 @@ func exportPP(wtr io.Writer, upp []UnitPP, opts Options) error {
 					id = pp.Aorist
 				}
 			}
-			if pp.Perfect != "" {
-				if opts.Incremental {
-					deckslice[1] = pp3
-				}
-				err = exportEntry(cwtr, deckslice, id, "Perfect",
-					pp.Perfect, opts.Reverse)
+			if pp.Perfect != "" && inGroup("pf") {
+				err = checkErr(exportEntry(cwtr, deckslice, id, "Perfect",
+					pp.Perfect, stems["pf"], verb, opts.Reverse, apply))
 				if err != nil {
 					return err
 				}
 			}
-			if pp.PerfMid != "" {
-				if opts.Incremental {
-					deckslice[1] = pp3
-				}
-				err = exportEntry(cwtr, deckslice, id, "Perfect Middle",
-					pp.PerfMid, opts.Reverse)
+			if pp.PerfMid != "" && inGroup("pm") {
+				err = checkErr(exportEntry(cwtr, deckslice, id, "Perfect Middle",
+					pp.PerfMid, stems["pm"], verb, opts.Reverse, apply))
 				if err != nil {
 					return err
 				}
 			}
-			if pp.AorPass != "" {
-				if opts.Incremental {
-					deckslice[1] = pp2
-				}
-				err = exportEntry(cwtr, deckslice, id, "Aorist Passive",
-					pp.AorPass, opts.Reverse)
+			if pp.AorPass != "" && inGroup("ap") {
+				err = checkErr(exportEntry(cwtr, deckslice, id, "Aorist Passive",
+					pp.AorPass, stems["ap"], verb, opts.Reverse, apply))
 				if err != nil {
 					return err
 				}
@@ -260,43 +528,168 @@ func exportPP(wtr io.Writer, upp []UnitPP, opts Options) error {
 		return err
 	}
 
-	return nil
+	return errs.Err()
 }
 
 func RunCLI(wtr io.Writer, opts Options) error {
-	dataset := opts.Args.Filename
-	data, err := os.ReadFile(dataset)
+	// A custom grouping implies incremental mode
+	if opts.Groups != "" {
+		opts.Incremental = true
+	} else {
+		opts.Groups = defaultGroups
+	}
+	if opts.Mode != modeParts && (opts.Incremental || opts.Reverse) {
+		return fmt.Errorf("--mode %s cannot be used with --incr or --rev", opts.Mode)
+	}
+	tmpl, err := export.LoadTemplates(opts.FrontTemplate, opts.BackTemplate)
 	if err != nil {
 		return err
 	}
-
-	var pp []UnitPP
-	err = yaml.Unmarshal(data, &pp)
+	if tmpl != nil && opts.Mode == modeCloze {
+		return errors.New("templates cannot be used with --mode cloze")
+	}
+	if opts.Typed && opts.Mode != modeParts {
+		return fmt.Errorf("--typed cannot be used with --mode %s", opts.Mode)
+	}
+	if (opts.DeckDescription != "" || opts.NewPerDay != 0) && opts.DecksFile == "" {
+		return errors.New("--deck-description and --new-per-day require --decks-file")
+	}
+	if opts.NewPerDay < 0 {
+		return fmt.Errorf("invalid --new-per-day %d", opts.NewPerDay)
+	}
+	deckOpts, err := export.NewDeckOptions(opts.DeckDescription, opts.NewPerDay)
 	if err != nil {
 		return err
 	}
-
-	stats := make(map[string]int)
-	err = exportPP(wtr, pp, opts)
+	if opts.DumpNotetype {
+		notetype, err := export.FindNotetype(formatNotetype(opts), export.DefaultStyle())
+		if err != nil {
+			return err
+		}
+		notetype.Write(wtr)
+		return nil
+	}
+	if opts.AudioCmd != "" && opts.TTSEngine == "" {
+		opts.TTSEngine = "command"
+	}
+	if opts.TTSEngine != "" && opts.Mode != modeChant {
+		return fmt.Errorf("--tts-engine requires --mode chant")
+	}
+	if opts.Format == formatMnemosyne {
+		switch {
+		case opts.Mode == modeCloze:
+			return fmt.Errorf("--mode %s cannot be used with --format %s", opts.Mode, opts.Format)
+		case opts.Typed:
+			return fmt.Errorf("--typed cannot be used with --format %s", opts.Format)
+		case opts.DecksFile != "":
+			return fmt.Errorf("--decks-file cannot be used with --format %s", opts.Format)
+		case opts.BOM:
+			return fmt.Errorf("--bom cannot be used with --format %s", opts.Format)
+		}
+	}
+	paths := dataset.DefaultPaths(opts.Args.Filenames, defaultFilename)
+	filenames, err := dataset.ExpandPaths(paths)
 	if err != nil {
 		return err
 	}
+	var groups map[string]string
+	if opts.Incremental {
+		groups, err = parseGroups(opts.Groups)
+		if err != nil {
+			return err
+		}
+	}
+	dec := dataset.OpenFiles(filenames)
+	defer dec.Close()
 
-	if len(stats) > 0 {
-		jstats, err := json.MarshalIndent(stats, "", "  ")
+	// In dry-run mode, just report what would be exported
+	out := wtr
+	if opts.DryRun {
+		out = io.Discard
+	}
+	cwtr := export.NewWriter(out, export.Separators[opts.Separator], deckColumnPos)
+	cwtr.Strict = opts.Strict
+	cwtr.Progress = export.StderrProgress(opts.Quiet || opts.DryRun)
+	// Mnemosyne cards are collected from the CSV records, without headers
+	hdr := out
+	if opts.Format == formatMnemosyne {
+		cwtr.Collect = true
+		hdr = io.Discard
+	}
+	var audio *export.Audio
+	if opts.TTSEngine != "" && !opts.DryRun {
+		// Network engines aren't created offline, as they may lack credentials
+		offline := opts.Offline && slices.Contains(tts.NetworkEngines, opts.TTSEngine)
+		var engine tts.Engine
+		if !offline {
+			engine, err = tts.New(tts.Config{
+				Engine: opts.TTSEngine, Voice: opts.Voice, Command: opts.AudioCmd,
+				Client: httpclient.New(opts.Config),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		media := export.NewMedia(opts.MediaDir, "export_anki_pp")
+		audio, err = export.NewAudio(engine, opts.AudioFilename, media)
+		if err != nil {
+			return err
+		}
+		audio.Offline = offline
+	}
+	err = exportPP(hdr, cwtr, dec, groups, tmpl, audio, opts)
+	cwtr.Progress.Done()
+	if cwtr.Collect && !opts.DryRun {
+		items := export.MnemosyneItems(cwtr.Records, opts.Mode != modeParts)
+		if werr := export.WriteMnemosyne(wtr, items); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	if audio != nil {
+		if merr := audio.Media.Finish(opts.PruneMedia); merr != nil && err == nil {
+			err = merr
+		}
+	}
+	if opts.DryRun {
+		cwtr.WriteSummary(wtr)
+	} else {
+		for _, warning := range cwtr.Warnings {
+			slog.Warn(warning)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if opts.DecksFile != "" && !opts.DryRun {
+		err = export.WriteDecksFile(opts.DecksFile, cwtr.Decks, deckOpts)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-		fmt.Fprintln(wtr, string(jstats))
 	}
 
 	return nil
 }
 
+// run runs a single export to the configured output
+func run(opts Options) error {
+	wtr, err := export.Create(opts.Outfile, opts.Compress, opts.BOM)
+	if err != nil {
+		return fmt.Errorf("opening outfile: %w", err)
+	}
+	err = RunCLI(wtr, opts)
+	if cerr := wtr.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
 func main() {
-	log.SetFlags(0)
 	// Parse default options are HelpFlag | PrintErrors | PassDoubleDash
 	var opts Options
+	opts.Version = func() {
+		fmt.Println(buildinfo.String("export_anki_pp"))
+		os.Exit(0)
+	}
 	parser := flags.NewParser(&opts, flags.Default)
 	_, err := parser.Parse()
 	if err != nil {
@@ -309,16 +702,19 @@ func main() {
 		parser.WriteHelp(os.Stderr)
 		os.Exit(2)
 	}
+	logging.Setup(opts.Options, opts.Verbose)
 
-	wtr := os.Stdout
-	if opts.Outfile != "" {
-		wtr, err = os.Create(opts.Outfile)
-		if err != nil {
-			log.Fatal("opening outfile: ", err)
-		}
+	// Dry runs only write a summary, to stdout
+	if opts.DryRun {
+		opts.Outfile, opts.Compress, opts.BOM = "", false, false
+	}
+	if opts.Watch {
+		paths := dataset.DefaultPaths(opts.Args.Filenames, defaultFilename)
+		err = watch.Run(paths, func() error { return run(opts) })
+	} else {
+		err = run(opts)
 	}
-	err = RunCLI(wtr, opts)
 	if err != nil {
-		log.Fatal(err)
+		logging.Fatal(err)
 	}
 }
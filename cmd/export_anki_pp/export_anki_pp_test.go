@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunCLIIncremental(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "pp.yml")
+	data := "- name: Unit 3\n  unit: 3\n  pp:\n    - pr: λύω\n      fu: λύσω\n      ao: ἔλυσα\n"
+	if err := os.WriteFile(filename, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Library callers get the default grouping without setting Groups
+	opts := Options{
+		Quiet: true, Incremental: true, Mode: modeParts,
+		Format: "anki", Separator: "comma",
+	}
+	opts.Args.Filenames = []string{filename}
+	var out strings.Builder
+	if err := RunCLI(&out, opts); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "λύσω") {
+		t.Errorf("RunCLI: output doesn't contain the future λύσω:\n%s", out.String())
+	}
+}
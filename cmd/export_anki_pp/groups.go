@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// defaultGroups is the default incremental grouping of principal parts:
+// future and aorist, then aorist passive, then the perfects
+var defaultGroups = fmt.Sprintf("%s=fu,ao;%s=ap;%s=pf,pm", pp1, pp2, pp3)
+
+// partKeys are the principal part keys that may be grouped
+var partKeys = map[string]bool{
+	"fu": true, "ao": true, "pf": true, "pm": true, "ap": true,
+}
+
+// parseGroups parses an incremental grouping spec of the form
+// "NAME=part,part;NAME=part", returning a map of part key to subdeck
+// name. Parts not assigned to any group are not exported.
+func parseGroups(spec string) (map[string]string, error) {
+	groups := make(map[string]string)
+	for _, gspec := range strings.Split(spec, ";") {
+		name, parts, ok := strings.Cut(gspec, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid group %q in --groups spec (want NAME=part,part)", gspec)
+		}
+		for _, key := range strings.Split(parts, ",") {
+			key = strings.TrimSpace(key)
+			if !partKeys[key] {
+				return nil, fmt.Errorf("invalid principal part %q in --groups spec (want fu, ao, pf, pm, or ap)", key)
+			}
+			if prev, exists := groups[key]; exists {
+				return nil, fmt.Errorf("principal part %q is in both groups %q and %q", key, prev, name)
+			}
+			groups[key] = name
+		}
+	}
+	return groups, nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/gavincarr/mag/export"
+)
+
+const (
+	modeParts    = "parts"
+	modeCombined = "combined"
+	modeChant    = "chant"
+	modeCloze    = "cloze"
+	modeStems    = "stems"
+)
+
+// clozeColumns are the CSV columns for cloze notes
+var clozeColumns = []string{"ID", "Text", "Back Extra", "Tags", "DeckName"}
+
+// partLabels are the labels of the six principal parts, in order
+var partLabels = []string{
+	"Present", "Future", "Aorist", "Perfect", "Perfect Middle", "Aorist Passive",
+}
+
+// partList returns the six principal parts of pp, in order
+func partList(pp Parts) []string {
+	return []string{
+		pp.Present, pp.Future, pp.Aorist, pp.Perfect, pp.PerfMid, pp.AorPass,
+	}
+}
+
+// plainParts returns the principal parts of pp in order, without any
+// "(stem X-)" annotations, as chanted or drilled
+func plainParts(pp Parts) []string {
+	parts := partList(pp)
+	for i, part := range parts {
+		parts[i] = strings.Join(strings.Fields(reStemNote.ReplaceAllString(part, "")), " ")
+	}
+	return parts
+}
+
+// givenStems returns the given tense stems of the principal parts of pp
+// in order, from its stems: field or else a "(stem X-)" annotation, with
+// "" for parts without one
+func givenStems(pp Parts) []string {
+	stems := make([]string, len(partKeyList))
+	for i, part := range partList(pp) {
+		stems[i] = pp.Stems[partKeyList[i]]
+		if m := reStemNote.FindStringSubmatch(part); m != nil && stems[i] == "" {
+			stems[i] = m[1] + "-"
+		}
+	}
+	return stems
+}
+
+// formatStems formats the given tense stems of pp as an HTML line (e.g.
+// "<br>Stems: Aorist λαβ-"), or returns "" if it has none
+func formatStems(pp Parts) string {
+	var stems []string
+	for i, stem := range givenStems(pp) {
+		if stem != "" {
+			stems = append(stems, partLabels[i]+" "+html.EscapeString(stem))
+		}
+	}
+	if len(stems) == 0 {
+		return ""
+	}
+	return "<br>Stems: " + strings.Join(stems, ", ")
+}
+
+// formatPartsTable formats the six principal parts of pp as an HTML
+// table, with the stem from its stems: field after any part that has
+// one (as on parts cards), and a dash for any missing parts
+func formatPartsTable(pp Parts) string {
+	parts := partList(pp)
+	cells := make([]string, len(parts))
+	for i, part := range parts {
+		cells[i] = html.EscapeString(part)
+		if stem := pp.Stems[partKeyList[i]]; stem != "" && part != "" {
+			cells[i] += " — stem " + html.EscapeString(stem)
+		}
+	}
+	return partsTableHTML(cells)
+}
+
+// partsTableHTML formats the HTML cells of the six principal parts as
+// an HTML table, with a dash for any missing parts
+func partsTableHTML(cells []string) string {
+	var b strings.Builder
+	b.WriteString("<table>")
+	for i, cell := range cells {
+		if cell == "" {
+			cell = "—"
+		}
+		fmt.Fprintf(&b, "<tr><th>%s</th><td>%s</td></tr>", partLabels[i], cell)
+	}
+	b.WriteString("</table>")
+	return b.String()
+}
+
+// exportCombined exports a single card for the verb pp, with the verb
+// id on the front and all its principal parts (and stems) on the back
+func exportCombined(cwtr *export.Writer, deckslice []string, id string, pp Parts, verb verbInfo, apply cardTemplater) error {
+	if id == "" {
+		return fmt.Errorf("%w: empty id for combined card", errBadEntry)
+	}
+	back := formatPartsTable(pp)
+	if verb.deponent {
+		back += "<br>" + strings.TrimSpace(verb.note())
+	}
+	back += verb.refsHTML()
+	front, back, err := apply("", "", html.EscapeString(id), back)
+	if err != nil {
+		return err
+	}
+	deck := strings.Join(deckslice, "::")
+	return cwtr.Write([]string{id, front, back, joinTags("pp::combined", verb.tags), deck})
+}
+
+// formatChant formats the principal parts of pp as a recitation chant
+// in HTML, comma-separated in order, with a dash for any missing parts
+func formatChant(pp Parts) string {
+	parts := plainParts(pp)
+	for i, part := range parts {
+		if part == "" {
+			parts[i] = "—"
+		}
+	}
+	return html.EscapeString(strings.Join(parts, ", "))
+}
+
+// exportChant exports a single card for the verb pp, with the verb id
+// on the front and the chant of its principal parts on the back, then
+// any given stems, followed by audio of the chant if audio is set
+func exportChant(cwtr *export.Writer, deckslice []string, id string, pp Parts, verb verbInfo, audio *export.Audio, apply cardTemplater) error {
+	if id == "" {
+		return fmt.Errorf("%w: empty id for chant card", errBadEntry)
+	}
+	back := formatChant(pp) + verb.note() + formatStems(pp) + verb.refsHTML()
+	if audio != nil {
+		// Speak only the parts that exist
+		var parts []string
+		for _, part := range plainParts(pp) {
+			if part != "" {
+				parts = append(parts, part)
+			}
+		}
+		sound, err := audio.Generate(id, strings.Join(parts, ", "))
+		switch {
+		case errors.Is(err, export.ErrOffline):
+			// Export the card without audio, reporting it needs a fetch
+			err = cwtr.Warnf("%s: %v", id, err)
+			if err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			back += "<br>" + sound
+		}
+	}
+	front, back, err := apply("", "", html.EscapeString(id), back)
+	if err != nil {
+		return err
+	}
+	deck := strings.Join(deckslice, "::")
+	return cwtr.Write([]string{id, front, back, joinTags("pp::chant", verb.tags), deck})
+}
+
+// exportCloze exports a single cloze note for the verb pp, with each of
+// its principal parts (without "(stem X-)" annotations) as a separate
+// cloze deletion (c1 to c6, by part), and any given stems in the back
+// extra field
+func exportCloze(cwtr *export.Writer, deckslice []string, id string, pp Parts, verb verbInfo) error {
+	if id == "" {
+		return fmt.Errorf("%w: empty id for cloze note", errBadEntry)
+	}
+	cells := plainParts(pp)
+	for i, part := range cells {
+		if part != "" {
+			cells[i] = fmt.Sprintf("{{c%d::%s}}", i+1, html.EscapeString(part))
+		}
+	}
+	deck := strings.Join(deckslice, "::")
+	return cwtr.Write([]string{
+		id, partsTableHTML(cells),
+		"Principal parts of " + html.EscapeString(id) + verb.note() + formatStems(pp) + verb.refsHTML(),
+		joinTags("pp::cloze", verb.tags), deck})
+}
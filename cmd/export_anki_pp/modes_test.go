@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gavincarr/mag/export"
+)
+
+func TestFormatChant(t *testing.T) {
+	pp := Parts{Present: "εἶμι (stem ἰ-)", Future: "—", Aorist: "ἤνεγκα or ἤνεγκον"}
+	want := "εἶμι, —, ἤνεγκα or ἤνεγκον, —, —, —"
+	if got := formatChant(pp); got != want {
+		t.Errorf("formatChant: got %q, want %q", got, want)
+	}
+}
+
+func TestFormatStems(t *testing.T) {
+	pp := Parts{
+		Present: "εἶμι (stem ἰ-)", Aorist: "ἔλαβον",
+		Stems: map[string]string{"ao": "λαβ-"},
+	}
+	want := "<br>Stems: Present ἰ-, Aorist λαβ-"
+	if got := formatStems(pp); got != want {
+		t.Errorf("formatStems: got %q, want %q", got, want)
+	}
+	if got := formatStems(Parts{Present: "λύω"}); got != "" {
+		t.Errorf("formatStems without stems: got %q, want none", got)
+	}
+	want = "<tr><th>Aorist</th><td>ἔλαβον — stem λαβ-</td></tr>"
+	if got := formatPartsTable(pp); !strings.Contains(got, want) {
+		t.Errorf("formatPartsTable: %q doesn't contain %q", got, want)
+	}
+}
+
+func TestExportCloze(t *testing.T) {
+	cwtr := export.NewWriter(io.Discard, export.Separators["comma"], 5)
+	cwtr.Collect = true
+	pp := Parts{
+		Present: "εἶμι (stem ἰ-)", Aorist: "ἤνεγκα or <ἤνεγκον>",
+		Stems: map[string]string{"ao": "ἐνεγκ-"},
+	}
+	err := exportCloze(cwtr, []string{"pp"}, "εἶμι", pp, verbInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := cwtr.Records[0][1]
+	for _, want := range []string{"{{c1::εἶμι}}", "{{c3::ἤνεγκα or &lt;ἤνεγκον&gt;}}"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("exportCloze: %q doesn't contain %q", text, want)
+		}
+	}
+	want := "Stems: Present ἰ-, Aorist ἐνεγκ-"
+	if extra := cwtr.Records[0][2]; !strings.Contains(extra, want) {
+		t.Errorf("exportCloze: back extra %q doesn't contain %q", extra, want)
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/gavincarr/mag/export"
+	"github.com/gavincarr/mag/greek"
+)
+
+// reStemNote matches the "(stem X-)" annotations in principal parts
+var reStemNote = regexp.MustCompile(`\(stem (\p{Greek}+)-\)`)
+
+// partKeyList are the principal part keys, in order (matching partList)
+var partKeyList = []string{"pr", "fu", "ao", "pf", "pm", "ap"}
+
+// stemEndings are the endings (without accents) stripped from each
+// principal part to find its tense stem, longest first
+var stemEndings = map[string][]string{
+	"pr": {"ομαι", "μαι", "μι", "ω"},
+	"fu": {"ουμαι", "ομαι", "ω"},
+	"ao": {"αμην", "ομην", "ον", "ην", "α"},
+	"pf": {"α"},
+	"pm": {"μαι"},
+	"ap": {"ην"},
+}
+
+// augmentedParts are the principal parts whose augment is dropped from
+// their stems
+var augmentedParts = map[string]bool{"ao": true, "ap": true}
+
+// temporalAugments maps the long vowels of temporal augments to the
+// present-stem vowels they lengthen
+var temporalAugments = map[rune]string{'η': "αε", 'ω': "ο"}
+
+// firstLetter returns the first letter of s, lowercase and without
+// diacritics, or 0 if s is empty
+func firstLetter(s string) rune {
+	for _, r := range strings.ToLower(greek.StripDiacritics(s)) {
+		return r
+	}
+	return 0
+}
+
+// dropAugment returns stem without its augment, given the verb's
+// present: a syllabic augment (ἐ- before a consonant) is dropped, and a
+// temporal augment (η for α or ε, ω for ο) is undone to the present's
+// initial vowel. It returns false if the augment can't be undone with
+// confidence (e.g. ἠνεχθ- with the present φέρω).
+func dropAugment(stem, present string) (string, bool) {
+	runes := []rune(norm.NFD.String(stem))
+	if len(runes) < 3 {
+		return stem, true
+	}
+	initial, pinitial := runes[0], firstLetter(present)
+	if initial == 'ε' && runes[1] == '̓' &&
+		!strings.ContainsRune("αεηιουω", firstLetter(string(runes[2:]))) {
+		stem = norm.NFC.String(string(runes[2:]))
+		// The ρ doubled after the augment is initial ῥ (e.g. ἔρριψα)
+		if rest, ok := strings.CutPrefix(stem, "ρρ"); ok {
+			stem = "ῥ" + rest
+		}
+		return stem, true
+	}
+	vowels, ok := temporalAugments[initial]
+	if !ok || initial == pinitial {
+		return stem, true
+	}
+	// ᾐ- and ᾠ- (for αἰ-, εἰ-, οἰ-) aren't undone
+	if !strings.ContainsRune(vowels, pinitial) ||
+		strings.ContainsRune(string(runes[:3]), 'ͅ') {
+		return "", false
+	}
+	runes[0] = pinitial
+	return norm.NFC.String(string(runes)), true
+}
+
+// hasInternalAugment reports whether form (the augmented part of the
+// verb present) is a compound with its augment after its preverb (e.g.
+// ἀπέβαλον, ἐξέβαλον), which dropAugment can't find
+func hasInternalAugment(present, form string) bool {
+	prefix, _, ok := greek.SplitCompound(present, func(string) bool { return true })
+	if !ok {
+		return false
+	}
+	key := greek.StripKey(form)
+	for _, p := range greek.Preverbs {
+		if p.Prefix != prefix {
+			continue
+		}
+		for _, f := range p.Forms {
+			if strings.HasPrefix(key, f) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// formatStem formats the tense stem stem as HTML, styled distinctly
+func formatStem(stem string) string {
+	return `<span class="stem">stem ` + html.EscapeString(stem) + `</span>`
+}
+
+// partStem returns the tense stem of the principal part form for key of
+// the verb pp, from its "(stem X-)" annotation if it has one, or else by
+// stripping its ending and any augment (e.g. "λυσ-" for the aorist
+// ἔλυσα). Distinct alternates are stemmed separately, joined by " or ".
+// It returns false if the stem can't be derived with confidence: the
+// aorists of irregular verbs and of compounds augmented after their
+// preverb need an annotation.
+func partStem(pp Parts, key, form string) (string, bool) {
+	if m := reStemNote.FindStringSubmatch(form); m != nil {
+		return m[1] + "-", true
+	}
+	if augmentedParts[key] && isIrregular(pp) {
+		return "", false
+	}
+	var stems []string
+	seen := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(form, func(r rune) bool {
+		return !greek.IsGreekLetter(r)
+	}) {
+		stem := greek.StripAccents(word)
+		for _, ending := range stemEndings[key] {
+			if s, ok := strings.CutSuffix(stem, ending); ok && s != "" {
+				stem = s
+				break
+			}
+		}
+		if augmentedParts[key] {
+			if hasInternalAugment(pp.Present, word) {
+				return "", false
+			}
+			var ok bool
+			stem, ok = dropAugment(stem, pp.Present)
+			if !ok {
+				return "", false
+			}
+		}
+		if !seen[stem] {
+			seen[stem] = true
+			stems = append(stems, stem+"-")
+		}
+	}
+	return strings.Join(stems, " or "), true
+}
+
+// exportStems exports a card for each tense stem of the verb pp (from
+// its stems: field, or else partStem), with the tense and verb id on the
+// front and the stem on the back. Stems partStem can't derive are
+// skipped with a warning.
+func exportStems(cwtr *export.Writer, deckslice []string, id string, pp Parts, verb verbInfo, apply cardTemplater) error {
+	if id == "" {
+		return fmt.Errorf("%w: empty id for stem cards", errBadEntry)
+	}
+	deck := strings.Join(deckslice, "::")
+	for i, form := range partList(pp) {
+		if form == "" {
+			continue
+		}
+		label := partLabels[i]
+		labeltag := reSpace.ReplaceAllString(strings.ToLower(label), "_")
+		stem := pp.Stems[partKeyList[i]]
+		if stem == "" {
+			var ok bool
+			stem, ok = partStem(pp, partKeyList[i], form)
+			if !ok {
+				err := cwtr.Warnf("%s: can't derive the %s stem of %q: add a (stem X-) annotation or stems: entry",
+					id, strings.ToLower(label), form)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		front := html.EscapeString(fmt.Sprintf("%s stem of %s%s", label, id, verb.note()))
+		front, back, err := apply(label, stem, front, formatStem(stem)+verb.refsHTML())
+		if err != nil {
+			return err
+		}
+		err = cwtr.Write([]string{
+			fmt.Sprintf("%s (%s stem)", id, strings.ToLower(label)),
+			front, back, joinTags("pp::stem::"+labeltag, verb.tags), deck,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// consonantClasses maps stops (and the double consonants they form with
+// σ) to their class, so sound changes like ἄγω, ἄξω aren't irregular
+var consonantClasses = map[rune]rune{
+	'γ': 'κ', 'κ': 'κ', 'χ': 'κ', 'ξ': 'κ',
+	'π': 'π', 'β': 'π', 'φ': 'π', 'ψ': 'π',
+	'τ': 'τ', 'δ': 'τ', 'θ': 'τ', 'ζ': 'τ', 'σ': 'τ',
+}
+
+// firstConsonant returns the first consonant of the first word of form
+// (ignoring diacritics, and with stops by class), or 0 if there is none
+func firstConsonant(form string) rune {
+	for _, r := range greek.StripDiacritics(strings.ToLower(form)) {
+		if r == ' ' {
+			break
+		}
+		if greek.IsGreekLetter(r) && !strings.ContainsRune("αεηιουω", r) {
+			if class, ok := consonantClasses[r]; ok {
+				return class
+			}
+			return r
+		}
+	}
+	return 0
+}
+
+// isIrregular reports whether the verb pp is irregular: as set by its
+// irregular: field, or else if it is suppletive, with a future or
+// aorist (stem) whose first consonant differs from the present's (e.g.
+// φέρω, οἴσω, ἤνεγκα)
+func isIrregular(pp Parts) bool {
+	if pp.Irregular != nil {
+		return *pp.Irregular
+	}
+	stem := func(key, form string) string {
+		if s := pp.Stems[key]; s != "" {
+			return s
+		}
+		return form
+	}
+	present := firstConsonant(stem("pr", pp.Present))
+	if present == 0 {
+		return false
+	}
+	for _, key := range []string{"fu", "ao"} {
+		form := pp.Future
+		if key == "ao" {
+			form = pp.Aorist
+		}
+		c := firstConsonant(stem(key, form))
+		if c != 0 && c != present {
+			return true
+		}
+	}
+	return false
+}
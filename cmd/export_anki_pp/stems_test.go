@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPartStem(t *testing.T) {
+	tests := []struct {
+		pr, key, form string
+		want          string
+		ok            bool
+	}{
+		{"λύω", "pr", "λύω", "λυ-", true},
+		{"λύω", "fu", "λύσω", "λυσ-", true},
+		{"λύω", "ao", "ἔλυσα", "λυσ-", true},
+		{"λύω", "ap", "ἐλύθην", "λυθ-", true},
+		{"γράφω", "ao", "ἔγραψα", "γραψ-", true},
+		{"ῥίπτω", "ao", "ἔρριψα", "ῥιψ-", true},
+		{"ἔρχομαι", "ao", "ἦλθον", "", false},
+		{"ἔρχομαι", "ao", "ἦλθον (stem ἐλθ-)", "ἐλθ-", true},
+		{"ἄγω", "ao", "ἤγαγον", "ἀγαγ-", true},
+		{"ἄγω", "ap", "ἤχθην", "ἀχθ-", true},
+		{"ὀνομάζω", "ao", "ὠνόμασα", "ὀνομασ-", true},
+		{"ἡγέομαι", "ao", "ἡγησάμην", "ἡγησ-", true},
+		{"ἐλαύνω", "ao", "ἤλασα", "ἐλασ-", true},
+		{"εὑρίσκω", "ao", "ηὗρον", "εὑρ-", true},
+		{"λείπω", "ao", "ἔλιπον or ἔλειψα", "λιπ- or λειψ-", true},
+		{"εἶμι", "pr", "εἶμι (stem ἰ-)", "ἰ-", true},
+		{"αἰτέω", "ao", "ᾔτησα", "", false},
+		{"φέρω", "ao", "ἤνεγκα or ἤνεγκον", "", false},
+		{"φέρω", "ap", "ἠνέχθην", "", false},
+		{"φέρω", "ao", "ἤνεγκα (stem ἐνεγκ-)", "ἐνεγκ-", true},
+		{"ἀποβάλλω", "ao", "ἀπέβαλον", "", false},
+		{"ἐκβάλλω", "ao", "ἐξέβαλον", "", false},
+		{"ἀποβάλλω", "fu", "ἀποβαλῶ", "ἀποβαλ-", true},
+		{"διδάσκω", "ao", "ἐδίδαξα", "διδαξ-", true},
+	}
+
+	for _, tc := range tests {
+		pp := Parts{Present: tc.pr}
+		switch tc.key {
+		case "ao":
+			pp.Aorist = tc.form
+		case "ap":
+			pp.AorPass = tc.form
+		}
+		got, ok := partStem(pp, tc.key, tc.form)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("partStem(%s, %q): got %q, %v, want %q, %v",
+				tc.key, tc.form, got, ok, tc.want, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// mag utility to export the sentences.yml dataset as an Anki-format CSV,
+// with a sentence-translation card for each example sentence
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	flags "github.com/jessevdk/go-flags"
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/gavincarr/mag/buildinfo"
+	"github.com/gavincarr/mag/dataset"
+	"github.com/gavincarr/mag/export"
+	"github.com/gavincarr/mag/logging"
+	"github.com/gavincarr/mag/watch"
+)
+
+const (
+	defaultFilename = "sentences.yml"
+	deckname        = "Mastronarde AtticGreek Sentences"
+	deckColumnPos   = 5
+)
+
+var csvColumns = []string{"ID", "Front", "Back", "Tags", "DeckName"}
+
+// Sentence is an example sentence
+type Sentence struct {
+	Gr    string
+	En    string
+	Words []string // ids of the vocab words used
+	Ref   string   // Mastronarde section/page references
+	Line  int      `yaml:"-"` // source line number
+}
+
+// UnmarshalYAML decodes a Sentence, recording its source line
+func (s *Sentence) UnmarshalYAML(value *yaml.Node) error {
+	type sentence Sentence
+	err := value.Decode((*sentence)(s))
+	if err != nil {
+		return err
+	}
+	s.Line = value.Line
+	return nil
+}
+
+type UnitSentences struct {
+	Name      string
+	Unit      int
+	Sentences []Sentence
+}
+
+// Options
+type Options struct {
+	Verbose bool   `short:"v" long:"verbose" description:"display verbose output"`
+	Version func() `long:"version" description:"print version and build information and exit"`
+	Quiet   bool   `short:"q" long:"quiet" description:"do not report export progress on stderr"`
+	logging.Options
+	Unit         int    `short:"u" long:"unit" env:"MAG_UNIT" description:"export only this unit number"`
+	Reverse      bool   `short:"r" long:"rev" description:"export in reverse output format i.e. English-to-Greek"`
+	DumpNotetype bool   `long:"dump-notetype" description:"print the definition of the notetype used, for creating it in Anki, and exit"`
+	Separator    string `long:"separator" choice:"comma" choice:"semicolon" choice:"tab" default:"comma" env:"MAG_SEPARATOR" description:"CSV field separator"`
+	BOM          bool   `long:"bom" description:"prefix output with a UTF-8 byte order mark (for spreadsheet apps)"`
+	Strict       bool   `long:"strict" description:"treat warnings as errors, aborting the export"`
+	DryRun       bool   `short:"n" long:"dry-run" description:"parse and validate the dataset and print a summary of the cards per deck, without writing any output"`
+	DeckName     string `long:"deckname" env:"MAG_DECKNAME" description:"base Anki deck name, before the direction suffix (default: \"Mastronarde AtticGreek Sentences\")"`
+	Watch        bool   `short:"w" long:"watch" description:"watch the datasets and re-export whenever they change"`
+	Outfile      string `short:"o" long:"outfile" env:"MAG_OUTFILE" description:"path to output filename (use stdout if not set)"`
+	Compress     bool   `short:"z" long:"compress" description:"gzip-compress the output (the default if --outfile ends in .gz)"`
+	Args         struct {
+		Filenames []string `positional-arg-name:"filename" description:"sentences yml datasets or directories to read (- for stdin; default: $MAG_SENTENCES or sentences.yml)"`
+	} `positional-args:"yes"`
+}
+
+func formatDeckname(opts Options) string {
+	direction := "GrEn"
+	if opts.Reverse {
+		direction = "EnGr"
+	}
+	name := deckname
+	if opts.DeckName != "" {
+		name = opts.DeckName
+	}
+	return fmt.Sprintf("%s (%s)", name, direction)
+}
+
+// exportSentence exports a card for s to deck, with the Greek on the
+// front and the translation on the back (or vice versa if reverse),
+// followed by the vocab words it uses and any references
+func exportSentence(cwtr *export.Writer, deck string, s Sentence, reverse bool) error {
+	front, back := html.EscapeString(s.Gr), html.EscapeString(s.En)
+	if reverse {
+		front, back = back, front
+	}
+	if len(s.Words) > 0 {
+		back += "<br>" + html.EscapeString("Vocab: "+strings.Join(s.Words, ", "))
+	}
+	tags := []string{"sentence"}
+	if line, refTags := export.MastronardeRefs(s.Ref); line != "" {
+		back += "<br>" + html.EscapeString(line)
+		tags = append(tags, refTags...)
+	}
+	return cwtr.Write([]string{s.Gr, front, back, strings.Join(tags, " "), deck})
+}
+
+// exportSentences exports the sentence units read from dec in Anki CSV
+// format to wtr. Bad entries are skipped, and returned as a
+// dataset.Errors after the export completes.
+func exportSentences(wtr io.Writer, cwtr *export.Writer, dec *dataset.Decoder, opts Options) error {
+	sep := export.Separators[opts.Separator]
+	idmap := make(map[string]struct{})
+	var errs dataset.Errors
+
+	name := formatDeckname(opts)
+
+	// Output file headers
+	fmt.Fprintln(wtr, "# "+name+" Anki CSV export")
+	fmt.Fprintln(wtr, sep.Header())
+	fmt.Fprintln(wtr, sep.Columns(csvColumns))
+	fmt.Fprintf(wtr, "#notetype:%s\n", export.NotetypeSentence)
+	fmt.Fprintf(wtr, "#deck column:%d\n", deckColumnPos)
+	fmt.Fprintln(wtr, "#html:true")
+
+	for {
+		var u UnitSentences
+		err := dec.Decode(&u)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if opts.Unit > 0 && u.Unit != opts.Unit {
+			continue
+		}
+		deck := name + "::" + u.Name
+		for _, s := range u.Sentences {
+			entryErr := func(err error) {
+				errs = append(errs, &dataset.EntryError{
+					File: dec.Filename(), Line: s.Line,
+					Unit: u.Name, Entry: s.Gr, Err: err,
+				})
+			}
+			if s.Gr == "" || s.En == "" {
+				entryErr(errors.New("sentence missing gr or en"))
+				continue
+			}
+
+			// Make sure ids are unique
+			if _, exists := idmap[s.Gr]; exists {
+				entryErr(errors.New("duplicate sentence"))
+				continue
+			}
+			idmap[s.Gr] = struct{}{}
+
+			err = exportSentence(cwtr, deck, s, opts.Reverse)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	cwtr.Flush()
+	if err := cwtr.Error(); err != nil {
+		return err
+	}
+
+	return errs.Err()
+}
+
+func RunCLI(wtr io.Writer, opts Options) error {
+	if opts.DumpNotetype {
+		notetype, err := export.FindNotetype(export.NotetypeSentence, export.DefaultStyle())
+		if err != nil {
+			return err
+		}
+		notetype.Write(wtr)
+		return nil
+	}
+	paths := dataset.DefaultPaths(opts.Args.Filenames, defaultFilename)
+	filenames, err := dataset.ExpandPaths(paths)
+	if err != nil {
+		return err
+	}
+	dec := dataset.OpenFiles(filenames)
+	defer dec.Close()
+
+	// In dry-run mode, just report what would be exported
+	out := wtr
+	if opts.DryRun {
+		out = io.Discard
+	}
+	cwtr := export.NewWriter(out, export.Separators[opts.Separator], deckColumnPos)
+	cwtr.Strict = opts.Strict
+	cwtr.Progress = export.StderrProgress(opts.Quiet || opts.DryRun)
+	err = exportSentences(out, cwtr, dec, opts)
+	cwtr.Progress.Done()
+	if opts.DryRun {
+		cwtr.WriteSummary(wtr)
+	} else {
+		for _, warning := range cwtr.Warnings {
+			slog.Warn(warning)
+		}
+	}
+	return err
+}
+
+// run runs a single export to the configured output
+func run(opts Options) error {
+	wtr, err := export.Create(opts.Outfile, opts.Compress, opts.BOM)
+	if err != nil {
+		return fmt.Errorf("opening outfile: %w", err)
+	}
+	err = RunCLI(wtr, opts)
+	if cerr := wtr.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func main() {
+	// Parse default options are HelpFlag | PrintErrors | PassDoubleDash
+	var opts Options
+	opts.Version = func() {
+		fmt.Println(buildinfo.String("export_anki_sentences"))
+		os.Exit(0)
+	}
+	parser := flags.NewParser(&opts, flags.Default)
+	_, err := parser.Parse()
+	if err != nil {
+		if flags.WroteHelp(err) {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %s\n\n", err.Error())
+		parser.WriteHelp(os.Stderr)
+		os.Exit(2)
+	}
+	logging.Setup(opts.Options, opts.Verbose)
+
+	// Dry runs only write a summary, to stdout
+	if opts.DryRun {
+		opts.Outfile, opts.Compress, opts.BOM = "", false, false
+	}
+	if opts.Watch {
+		paths := dataset.DefaultPaths(opts.Args.Filenames, defaultFilename)
+		err = watch.Run(paths, func() error { return run(opts) })
+	} else {
+		err = run(opts)
+	}
+	if err != nil {
+		logging.Fatal(err)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gavincarr/mag/export"
+	"github.com/gavincarr/mag/greek"
+)
+
+var (
+	// clozeColumns are the CSV columns for cloze notes
+	clozeColumns = []string{"ID", "Text", "Back Extra", "Tags", "DeckName"}
+	reGreekWord  = regexp.MustCompile(`[\p{Greek}\p{M}]+`)
+)
+
+// clozeKey returns the form of word used to match headwords in example
+// phrases, ignoring case and diacritics
+func clozeKey(word string) string {
+	return strings.ToLower(greek.StripDiacritics(word))
+}
+
+// formatCloze returns the example phrase in w.EnExt as HTML, with
+// occurrences of the headword of w blanked as a cloze deletion, or ""
+// if the headword does not appear in it
+func formatCloze(w Word) string {
+	if w.EnExt == "" {
+		return ""
+	}
+	headword := clozeKey(reCommaStar.ReplaceAllString(w.Gr, ""))
+	found := false
+	text := reGreekWord.ReplaceAllStringFunc(export.Markdown(w.EnExt), func(word string) string {
+		if clozeKey(word) != headword {
+			return word
+		}
+		found = true
+		return "{{c1::" + word + "}}"
+	})
+	if !found {
+		return ""
+	}
+	return text
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"html"
+
+	"github.com/gavincarr/mag/greek"
+)
+
+// simplexIndex maps the keys (see greek.StripKey) of the dataset verbs
+// to their headwords, for linking compound verbs to their simplexes
+type simplexIndex map[string]string
+
+// newSimplexIndex returns the simplexIndex for the verbs in units
+func newSimplexIndex(units []UnitVocab) simplexIndex {
+	index := make(simplexIndex)
+	for _, u := range units {
+		for _, w := range u.Vocab {
+			if w.Pos != "v" {
+				continue
+			}
+			headword := reCommaStar.ReplaceAllString(w.Gr, "")
+			key := greek.StripKey(headword)
+			if _, exists := index[key]; !exists {
+				index[key] = headword
+			}
+		}
+	}
+	return index
+}
+
+// compoundNote returns an HTML note for the verb w if it is a compound
+// of a simplex verb in the index (e.g. "compound of βάλλω (ἀπο-)"), or
+// "" if not
+func (index simplexIndex) compoundNote(w Word) string {
+	if index == nil || w.Pos != "v" {
+		return ""
+	}
+	headword := reCommaStar.ReplaceAllString(w.Gr, "")
+	prefix, key, ok := greek.SplitCompound(headword, func(key string) bool {
+		_, exists := index[key]
+		return exists
+	})
+	if !ok {
+		return ""
+	}
+	return html.EscapeString("compound of " + index[key] + " (" + prefix + ")")
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"html"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/gavincarr/mag/enrich"
+)
+
+// morpheusNotes maps dataset filenames to the Morpheus analyses of
+// their headwords, as written by mag enrich --morpheus
+type morpheusNotes map[string]map[string][]enrich.Analysis
+
+// note returns an HTML note describing the Morpheus analyses of the
+// word w from the dataset filename (e.g. "Morpheus: λόγος: noun, 2nd
+// declension, masculine"), preferring those of its own headword as
+// lemma, or "" if it has none
+func (notes morpheusNotes) note(filename string, w Word) string {
+	headword := enrich.Headword(w.Gr)
+	analyses := notes[filename][headword]
+	var own []enrich.Analysis
+	for _, a := range analyses {
+		if enrich.Headword(a.Lemma) == headword {
+			own = append(own, a)
+		}
+	}
+	if len(own) > 0 {
+		analyses = own
+	}
+	var descs []string
+	for _, a := range analyses {
+		if desc := a.Describe(); !slices.Contains(descs, desc) {
+			descs = append(descs, desc)
+		}
+	}
+	if len(descs) == 0 {
+		return ""
+	}
+	return html.EscapeString("Morpheus: " + strings.Join(descs, "; "))
+}
+
+// lsjNotes maps dataset filenames to the short LSJ definitions of their
+// headwords, as written by mag enrich --lsj
+type lsjNotes map[string]map[string]string
+
+// note returns an HTML note giving the LSJ definition of the word w from
+// the dataset filename (e.g. "LSJ: computation, reckoning"), or "" if it
+// has none
+func (notes lsjNotes) note(filename string, w Word) string {
+	def := notes[filename][enrich.Headword(w.Gr)]
+	if def == "" {
+		return ""
+	}
+	return html.EscapeString("LSJ: " + def)
+}
+
+// dictionaryLink returns an HTML link to the entry for the headword of
+// the word w on the dictionary site (logeion or perseus), or "" if site
+// is empty or w has no Greek headword
+func dictionaryLink(site string, w Word) string {
+	headword := enrich.Headword(w.Gr)
+	if site == "" || headword == "" {
+		return ""
+	}
+	var href, label string
+	switch site {
+	case "logeion":
+		href = "https://logeion.uchicago.edu/" + url.PathEscape(headword)
+		label = "Logeion"
+	case "perseus":
+		params := url.Values{"l": {headword}, "la": {"greek"}}
+		href = "https://www.perseus.tufts.edu/hopper/morph?" + params.Encode()
+		label = "Perseus"
+	default:
+		return ""
+	}
+	return `<a href="` + html.EscapeString(href) + `">` + label + `</a>`
+}
@@ -3,64 +3,101 @@
 package main
 
 import (
-	"encoding/csv"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"io"
-	"log"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	flags "github.com/jessevdk/go-flags"
 	yaml "gopkg.in/yaml.v3"
+
+	"github.com/gavincarr/mag/buildinfo"
+	"github.com/gavincarr/mag/dataset"
+	"github.com/gavincarr/mag/enrich"
+	"github.com/gavincarr/mag/export"
+	"github.com/gavincarr/mag/greek"
+	"github.com/gavincarr/mag/logging"
+	"github.com/gavincarr/mag/watch"
 )
 
 const (
-	deckNameGrEn   = "Mastronarde AtticGreek Vocab (GrEn)"
-	csvCommentGrEn = "# This is an export of the MAG vocab dataset in Anki CSV format (Greek-to-English)"
-	notetypeGrEn   = "MAG Vocab GrEn"
-	csvHeader      = "ID,Front,Back,Tags,DeckName"
-	deckColumnPos  = 5
+	defaultFilename = "vocab.yml"
+	deckNameGrEn    = "Mastronarde AtticGreek Vocab (GrEn)"
+	csvCommentGrEn  = "# This is an export of the MAG vocab dataset in Anki CSV format (Greek-to-English)"
+	deckColumnPos   = 5
+	lintCommand     = "lint_vocab"
+	subdeckPOS      = "pos"
 )
 
 var (
+	csvColumns             = []string{"ID", "Front", "Back", "Tags", "DeckName"}
 	reCommaStar            = regexp.MustCompile(`,.*$`)
 	reSemicolon            = regexp.MustCompile(`\pZ*;\pZ*`)
 	reSemicolonParenthesis = regexp.MustCompile(`\pZ*;\pZ*\(`)
 	reCaseMarker           = regexp.MustCompile(`^\(\+\pZ*(acc|gen|dat)\.?\)`)
 	reVoiceMarker          = regexp.MustCompile(`^\([^(]*(mid|pass)\.[^)]*\)`)
 	rePluralMarker         = regexp.MustCompile(`^\(pl\.\)`)
-
-	posMap = map[string]string{
-		"adj":      "adjective",
-		"adv":      "adverb",
-		"conj":     "conjunction",
-		"n":        "noun",
-		"part":     "participle",
-		"particle": "particle",
-		"prep":     "preposition",
-		"pron":     "pronoun",
-		"v":        "verb",
-	}
 )
 
 type Word struct {
-	Gr    string
-	GrMP  string `yaml:"gr_mp"`
-	GrPl  string `yaml:"gr_pl"`
-	GrExt string `yaml:"gr_ext"`
-	Id    string
-	En    string
-	EnExt string `yaml:"en_ext"`
-	Cog   string
-	Pos   string
+	Gr      string
+	GrMP    string `yaml:"gr_mp"`
+	GrPl    string `yaml:"gr_pl"`
+	GrExt   string `yaml:"gr_ext"`
+	Id      string
+	Homonym string // gloss hint distinguishing homographs, numbered on export
+	En      string
+	EnExt   string `yaml:"en_ext"`
+	Cog     string
+	Pos     string
+	Decl    string            // declension(s), e.g. "2" or "1/2", overriding inference
+	VClass  string            `yaml:"vclass"` // verb class(es), overriding inference
+	Smyth   string            // Smyth grammar section references
+	Ref     string            // Mastronarde section/page references
+	Img     string            // image file, relative to the dataset
+	Fields  map[string]string // extra fields, mapped by --field-map
+	Line    int               `yaml:"-"` // source line number
+}
+
+// UnmarshalYAML decodes a Word, recording its source line
+func (w *Word) UnmarshalYAML(value *yaml.Node) error {
+	type word Word
+	err := value.Decode((*word)(w))
+	if err != nil {
+		return err
+	}
+	w.Line = value.Line
+	return nil
 }
 
 type UnitVocab struct {
-	Name  string
-	Unit  int
-	Vocab []Word
+	Name     string
+	Unit     int
+	Vocab    []Word
+	Extended bool `yaml:"-"` // unit added from an overlay dataset
+}
+
+// cardData is the data passed to --front-template/--back-template: the
+// word's fields, plus its unit name and the default card content. Front
+// and Back are HTML; the word fields are raw text, to be escaped in
+// templates with the html function.
+type cardData struct {
+	Word
+	Unit  string
+	Front string
+	Back  string
+}
+
+// posSubdeck returns the --subdeck-by pos subdeck name for the part of
+// speech pos (e.g. "Noun" for "noun")
+func posSubdeck(pos string) string {
+	return strings.ToUpper(pos[:1]) + pos[1:]
 }
 
 type CaseVoiceGloss struct {
@@ -74,11 +111,42 @@ type CaseVoiceGloss struct {
 // Options
 type Options struct {
 	Verbose bool   `short:"v" long:"verbose" description:"display verbose output"`
-	Unit    int    `short:"u" long:"unit" description:"export only this unit number"`
-	Count   int    `short:"c" long:"count" description:"export only this many entries"`
-	Outfile string `short:"o" long:"outfile" description:"path to output filename (use stdout if not set)"`
-	Args    struct {
-		Filename string `description:"vocab yml dataset to read" default:"vocab.yml"`
+	Version func() `long:"version" description:"print version and build information and exit"`
+	Quiet   bool   `short:"q" long:"quiet" description:"do not report export progress on stderr"`
+	logging.Options
+	Unit            int    `short:"u" long:"unit" env:"MAG_UNIT" description:"export only this unit number"`
+	Count           int    `short:"c" long:"count" description:"export only this many entries"`
+	Sort            string `short:"s" long:"sort" choice:"alpha" env:"MAG_SORT" description:"sort entries within each unit (alpha: Greek dictionary order)"`
+	Overlay         string `long:"overlay" description:"personal vocab yml dataset to merge over the main dataset (adding words, or overriding them by id)"`
+	Typed           bool   `long:"typed" description:"export notes for a type-in-the-answer notetype, requiring the English to be typed"`
+	DumpNotetype    bool   `long:"dump-notetype" description:"print the definition of the notetype used with the given options, for creating it in Anki, and exit"`
+	FrontTemplate   string `long:"front-template" description:"Go text/template file for the card front, executed with the word fields, .Unit, and the default .Front and .Back"`
+	BackTemplate    string `long:"back-template" description:"Go text/template file for the card back, executed with the word fields, .Unit, and the default .Front and .Back"`
+	FieldMap        string `long:"field-map" env:"MAG_FIELD_MAP" description:"map extra word fields (from fields:, or derived: gender, decl, vclass) to Anki fields, as field=Column pairs (e.g. \"mnemonic=Back,example=Example\"); Front/Back have the field appended, other columns are added to the CSV"`
+	MediaDir        string `long:"media-dir" env:"MAG_MEDIA_DIR" default:"media" description:"directory to copy referenced media files (img:) to, with a manifest"`
+	PruneMedia      bool   `long:"prune-media" description:"remove media files from --media-dir used by the previous export but no longer referenced"`
+	Compounds       bool   `long:"compounds" description:"add a \"compound of X\" note to the back of compound verb cards whose simplex verb X is in the dataset (e.g. ἀποβάλλω and βάλλω)"`
+	Morpheus        bool   `long:"morpheus" description:"add each word's Morpheus analyses (from the <dataset>.morpheus.yml sidecars written by mag enrich --morpheus) to the back of its card"`
+	LSJ             bool   `long:"lsj" description:"add each word's short LSJ definition (from the <dataset>.lsj.yml sidecars written by mag enrich --lsj) to the back of its card"`
+	Format          string `short:"f" long:"format" choice:"anki" choice:"memrise" choice:"mochi" choice:"mnemosyne" choice:"plain" default:"anki" env:"MAG_FORMAT" description:"output format (anki: Anki CSV; memrise: tab-separated word, definition, part of speech, and pronunciation rows, for Memrise bulk-add; mochi: a Mochi .mochi deck file, with a deck per unit; mnemosyne: Mnemosyne 1.x XML, with a category per deck, for File > Import in Mnemosyne 2; plain: tab-separated headword and gloss rows, as plain text)"`
+	Links           string `long:"links" choice:"logeion" choice:"perseus" env:"MAG_LINKS" description:"add a link to each word's dictionary entry on this site (logeion: Logeion; perseus: the Perseus Greek Word Study Tool) to the back of its card"`
+	Cloze           bool   `long:"cloze" description:"export cloze notes from en_ext example phrases containing the headword (blanking the headword), instead of word cards"`
+	Separator       string `long:"separator" choice:"comma" choice:"semicolon" choice:"tab" default:"comma" env:"MAG_SEPARATOR" description:"CSV field separator"`
+	BOM             bool   `long:"bom" description:"prefix output with a UTF-8 byte order mark (for spreadsheet apps)"`
+	Strict          bool   `long:"strict" description:"treat warnings as errors, aborting the export"`
+	DryRun          bool   `short:"n" long:"dry-run" description:"parse and validate the dataset and print a summary of the cards per deck, without writing any output"`
+	Incremental     string `short:"i" long:"incr" optional:"yes" optional-value:"half" value-name:"GROUPING" description:"split into incremental subdecks grouped by GROUPING: core (main vs. --overlay vocab), half (first/second half of units), or a comma-separated list of first unit numbers (e.g. 1,11,21)"`
+	Flat            bool   `long:"flat" description:"export all cards into a single deck, without per-unit subdecks (filter by the unit:: tags instead)"`
+	SubdeckBy       string `long:"subdeck-by" choice:"pos" env:"MAG_SUBDECK_BY" description:"nest cards in a further subdeck within each unit, by pos (part of speech, e.g. ::Noun, ::Verb)"`
+	DeckName        string `long:"deckname" env:"MAG_DECKNAME" description:"Anki deck name (default: \"Mastronarde AtticGreek Vocab (GrEn)\")"`
+	DeckDescription string `long:"deck-description" env:"MAG_DECK_DESCRIPTION" description:"Markdown file (or yml file with description and new_per_day fields) giving the description and options of the exported decks, for --decks-file"`
+	NewPerDay       int    `long:"new-per-day" env:"MAG_NEW_PER_DAY" description:"new cards per day for the exported decks, for --decks-file (overrides new_per_day in --deck-description)"`
+	DecksFile       string `long:"decks-file" env:"MAG_DECKS_FILE" description:"write a JSON file listing the exported decks with their description and options, for applying in Anki after import (which CSV import can't do)"`
+	Watch           bool   `short:"w" long:"watch" description:"watch the datasets and re-export whenever they change"`
+	Outfile         string `short:"o" long:"outfile" env:"MAG_OUTFILE" description:"path to output filename (use stdout if not set)"`
+	Compress        bool   `short:"z" long:"compress" description:"gzip-compress the output (the default if --outfile ends in .gz)"`
+	Args            struct {
+		Filenames []string `positional-arg-name:"filename" description:"vocab yml datasets or directories to read (- for stdin; default: $MAG_VOCAB or vocab.yml)"`
 	} `positional-args:"yes"`
 }
 
@@ -93,24 +161,24 @@ func parsePrepGlosses(gloss string) []CaseVoiceGloss {
 	for i, entry := range entries {
 		matches := reCaseMarker.FindStringSubmatch(entry)
 		if matches == nil {
-			// The first entry not having a case marker is a fatal error
+			// Entries without case markers just get appended to current
+			// (a missing initial case marker is reported by lint_vocab)
 			if i == 0 {
-				log.Fatalf("preposition entry without initial case marker: %s",
-					gloss)
+				cg.Gloss = entry
+			} else {
+				cg.Gloss += "; " + entry
 			}
-			// Subsequent entries without case markers just get appended to current
-			cg.Gloss += "; " + entry
 			continue
 		}
 
-		if cg.Case != "" {
+		if cg.Case != "" || cg.Gloss != "" {
 			cglist = append(cglist, cg)
 		}
 		// Remove case marker from the gloss
 		gloss := strings.TrimSpace(strings.Replace(entry, matches[0], "", 1))
 		cg = CaseVoiceGloss{Case: matches[1], Marker: matches[0], Gloss: gloss}
 	}
-	if cg.Case != "" {
+	if cg.Case != "" || cg.Gloss != "" {
 		cglist = append(cglist, cg)
 	}
 	return cglist
@@ -182,59 +250,275 @@ func parsePluralGlosses(gloss string) []CaseVoiceGloss {
 	return cglist
 }
 
-// exportVocab exports vocab in Anki CSV format to wtr
-func exportVocab(wtr io.Writer, vocab []UnitVocab, opts Options) error {
-	cwtr := csv.NewWriter(wtr)
+// formatGloss returns gloss as HTML, with each semicolon-separated
+// entry escaped and on a separate line
+func formatGloss(gloss string) string {
+	entries := reSemicolon.Split(gloss, -1)
+	for i, entry := range entries {
+		entries[i] = html.EscapeString(entry)
+	}
+	return strings.Join(entries, "<br>")
+}
+
+// wordID returns the card id for w: its explicit id, or its headword.
+// Homonyms are numbered separately, in exportVocab.
+func wordID(w Word) string {
+	if w.Id != "" {
+		return w.Id
+	}
+	return reCommaStar.ReplaceAllString(w.Gr, "")
+}
+
+// imagePath returns the path of the image file img, relative to the
+// dataset file filename
+func imagePath(img, filename string) string {
+	if !filepath.IsAbs(img) && filename != dataset.Stdin {
+		return filepath.Join(filepath.Dir(filename), img)
+	}
+	return img
+}
+
+// copyImage copies the image file img of card id, relative to the
+// dataset file filename, into media, returning its media filename. If
+// media is nil (in dry-run mode) the image is only checked for.
+func copyImage(media *export.Media, id, img, filename string) (string, error) {
+	img = imagePath(img, filename)
+	if media == nil {
+		_, err := os.Stat(img)
+		return filepath.Base(img), err
+	}
+	return media.Copy(id, img)
+}
+
+// formatNotetype returns the name of the notetype for opts
+func formatNotetype(opts Options) string {
+	if opts.Cloze {
+		return export.NotetypeVocabCloze
+	}
+	if opts.Typed {
+		return export.NotetypeVocabGrEn + export.TypedSuffix
+	}
+	return export.NotetypeVocabGrEn
+}
+
+// sortWords returns a copy of words sorted in Greek dictionary order
+func sortWords(words []Word) []Word {
+	sorted := make([]Word, len(words))
+	copy(sorted, words)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return greek.Less(sorted[i].Gr, sorted[j].Gr)
+	})
+	return sorted
+}
+
+// exportVocab exports the vocab units read from dec in Anki CSV format
+// to wtr. Bad entries are skipped, and returned as a dataset.Errors
+// after the export completes.
+func exportVocab(wtr io.Writer, cwtr *export.Writer, dec unitDecoder, incr *incrGrouper, tmpl *export.Templates, fieldMap []fieldMapping, media *export.Media, simplexes simplexIndex, morpheus morpheusNotes, lsj lsjNotes, opts Options) error {
+	sep := export.Separators[opts.Separator]
+	deckName := deckNameGrEn
+	if incr != nil {
+		deckName = deckNameIncrGrEn
+	}
+	if opts.DeckName != "" {
+		deckName = opts.DeckName
+	}
 	count := 1
 	idmap := make(map[string]struct{})
+	homonyms := make(map[string]int) // homonym counts by base id
+	var errs dataset.Errors
+
+	columns, notetype := csvColumns, formatNotetype(opts)
+	if opts.Cloze {
+		columns = clozeColumns
+	} else if len(fieldMap) > 0 {
+		columns = append(append([]string{}, csvColumns...), extraColumns(fieldMap)...)
+	}
 
 	// Output file headers
 	fmt.Fprintln(wtr, csvCommentGrEn)
-	fmt.Fprintln(wtr, "#separator:Comma")
-	fmt.Fprintf(wtr, "#columns:%s\n", csvHeader)
-	fmt.Fprintf(wtr, "#notetype:%s\n", notetypeGrEn)
+	fmt.Fprintln(wtr, sep.Header())
+	fmt.Fprintln(wtr, sep.Columns(columns))
+	fmt.Fprintf(wtr, "#notetype:%s\n", notetype)
 	fmt.Fprintf(wtr, "#deck column:%d\n", deckColumnPos)
 	fmt.Fprintln(wtr, "#html:true")
 
 	// Output vocab entries
-	for _, u := range vocab {
+	for {
+		var u UnitVocab
+		err := dec.Decode(&u)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
 		if opts.Unit > 0 && u.Unit != opts.Unit {
+			// Count skipped homonyms, so numbering doesn't depend on --unit
+			for _, w := range u.Vocab {
+				if w.Homonym != "" {
+					homonyms[wordID(w)]++
+				}
+			}
 			continue
 		}
 
-		for _, w := range u.Vocab {
-			var id string
-			if w.Id != "" {
-				id = w.Id
-			} else {
-				id = reCommaStar.ReplaceAllString(w.Gr, "")
+		words := u.Vocab
+		if opts.Sort == "alpha" {
+			words = sortWords(words)
+		}
+
+		for _, w := range words {
+			if _, ok := dataset.PartsOfSpeech[w.Pos]; !ok {
+				errs = append(errs, &dataset.EntryError{
+					File: dec.Filename(), Line: w.Line,
+					Unit: u.Name, Entry: w.Gr,
+					Err: fmt.Errorf("bad POS %q%s", w.Pos,
+						dataset.FormatSuggestions(dataset.SuggestPOS(w.Pos))),
+				})
+				continue
+			}
+
+			id := wordID(w)
+
+			// Number homonyms in order, to distinguish homographs
+			homonym := 0
+			if w.Homonym != "" {
+				homonyms[id]++
+				homonym = homonyms[id]
+				id = fmt.Sprintf("%s (%d)", id, homonym)
 			}
 
 			// Make sure ids are unique
 			if _, exists := idmap[id]; exists {
-				log.Fatal("duplicate ids found: ", id)
+				errs = append(errs, &dataset.EntryError{
+					File: dec.Filename(), Line: w.Line,
+					Unit: u.Name, Entry: w.Gr,
+					Err: fmt.Errorf("duplicate id %q (run %s for a full report, or set homonym: on homographs)",
+						id, lintCommand),
+				})
+				continue
 			}
 			idmap[id] = struct{}{}
-			pos, ok := posMap[w.Pos]
-			if !ok {
-				log.Fatalf("bad POS %q found on word %q/%q",
-					w.Pos, w.Gr, w.En)
-			}
+			pos := dataset.PartsOfSpeech[w.Pos]
 
-			front := w.Gr
+			front := html.EscapeString(w.Gr)
+			if homonym > 0 {
+				front += fmt.Sprintf(" (%d)", homonym)
+			}
 			if w.GrExt != "" {
-				front += " " + w.GrExt
+				front += " " + html.EscapeString(w.GrExt)
 			}
-			tags := []string{"pos::" + pos}
+			if homonym > 0 {
+				front += " <i>[" + html.EscapeString(w.Homonym) + "]</i>"
+			}
+			tags := []string{"pos::" + pos, fmt.Sprintf("unit::%02d", u.Unit)}
+			gtags, err := grammarTags(w)
+			if err != nil {
+				errs = append(errs, &dataset.EntryError{
+					File: dec.Filename(), Line: w.Line,
+					Unit: u.Name, Entry: w.Gr, Err: err,
+				})
+				continue
+			}
+			tags = append(tags, gtags...)
+			smyth, smythTags := export.SmythRefs(w.Smyth)
+			tags = append(tags, smythTags...)
+			ref, refTags := export.MastronardeRefs(w.Ref)
+			tags = append(tags, refTags...)
+			w.Fields = grammarFields(w)
 			tagstr := strings.Join(tags, " ")
-			deck := strings.Join([]string{deckNameGrEn, u.Name}, "::")
+			deckslice := []string{deckName, u.Name}
+			if opts.Flat {
+				deckslice = []string{deckName}
+			} else if incr != nil {
+				deckslice = []string{deckName, incr.group(u), u.Name}
+			}
+			if opts.SubdeckBy == subdeckPOS {
+				deckslice = append(deckslice, posSubdeck(pos))
+			}
+			deck := strings.Join(deckslice, "::")
+
+			// In cloze mode, export only words with example phrases
+			if opts.Cloze {
+				text := formatCloze(w)
+				if text == "" {
+					continue
+				}
+				extra := html.EscapeString(w.Gr + ": " +
+					reSemicolon.ReplaceAllString(w.En, "; "))
+				err := cwtr.Write([]string{id, text, extra, tagstr, deck})
+				if err != nil {
+					return err
+				}
+				count++
+				if opts.Count > 0 && count > opts.Count {
+					break
+				}
+				continue
+			}
+
+			// Copy any image into the media directory
+			img := ""
+			if w.Img != "" {
+				filename, err := copyImage(media, id, w.Img, dec.Filename())
+				if err != nil {
+					errs = append(errs, &dataset.EntryError{
+						File: dec.Filename(), Line: w.Line,
+						Unit: u.Name, Entry: w.Gr, Err: err,
+					})
+					continue
+				}
+				img = `<img src="` + html.EscapeString(filename) + `">`
+			}
+
+			compound := simplexes.compoundNote(w)
+			analyses := morpheus.note(dec.Filename(), w)
+			definition := lsj.note(dec.Filename(), w)
+			link := dictionaryLink(opts.Links, w)
+
+			// writeCard writes a card for w, applying any templates
+			writeCard := func(id, front, back string) error {
+				if compound != "" {
+					back += "<br>" + compound
+				}
+				if analyses != "" {
+					back += "<br>" + analyses
+				}
+				if definition != "" {
+					back += "<br>" + definition
+				}
+				if link != "" {
+					back += "<br>" + link
+				}
+				if smyth != "" {
+					back += "<br>" + html.EscapeString(smyth)
+				}
+				if ref != "" {
+					back += "<br>" + html.EscapeString(ref)
+				}
+				if img != "" {
+					back += "<br>" + img
+				}
+				front, back, extras := applyFields(w, fieldMap, front, back)
+				data := cardData{Word: w, Unit: u.Name, Front: front, Back: back}
+				front, back, err := tmpl.Apply(data, front, back)
+				if err != nil {
+					return fmt.Errorf("%s: %w", id, err)
+				}
+				record := []string{id, front, back, tagstr, deck}
+				return cwtr.Write(append(record, extras...))
+			}
 
 			// For prepositions, split into per-case entries
 			var glosses []CaseVoiceGloss
 			if w.Pos == "prep" {
 				glosses = parsePrepGlosses(w.En)
 				if w.EnExt != "" {
-					fmt.Fprintf(os.Stderr, "Warning: en_ext is unsupported with prepositions - skipping for %q\n", front)
+					err := cwtr.Warnf("en_ext is unsupported with prepositions - skipping for %q", w.Gr)
+					if err != nil {
+						return err
+					}
 				}
 			} else if w.GrMP != "" {
 				// If a separate middle/passive form is defined, parse
@@ -250,40 +534,37 @@ func exportVocab(wtr io.Writer, vocab []UnitVocab, opts Options) error {
 					id2 := id
 					if cg.Case != "" {
 						id2 = id + "-" + cg.Case
-						front = w.Gr + " " + cg.Marker
+						front = html.EscapeString(w.Gr + " " + cg.Marker)
 						if w.GrExt != "" {
-							front += " " + w.GrExt
+							front += " " + html.EscapeString(w.GrExt)
 						}
 					} else if (cg.Voice == "mid" || cg.Voice == "pass") &&
 						w.GrMP != "" {
 						id2 = w.GrMP
-						front = w.GrMP
+						front = html.EscapeString(w.GrMP)
 					} else if w.GrPl != "" && cg.Plural {
 						id2 = reCommaStar.ReplaceAllString(w.GrPl, "")
-						front = w.GrPl
+						front = html.EscapeString(w.GrPl)
 					}
-					back := cg.Gloss
-					back = reSemicolon.ReplaceAllString(back, "<br>")
+					back := formatGloss(cg.Gloss)
 					//back = reSemicolonParenthesis.ReplaceAllString(back, "<br>(")
 					// Write entry
-					err := cwtr.Write([]string{
-						id2, front, back, tagstr, deck})
+					err := writeCard(id2, front, back)
 					if err != nil {
 						return err
 					}
 				}
 			} else {
-				back := w.En
-				back = reSemicolon.ReplaceAllString(back, "<br>")
+				back := formatGloss(w.En)
 				//back = reSemicolonParenthesis.ReplaceAllString(back, "<br>(")
 				if w.EnExt != "" {
-					back += "<br><i>" + w.EnExt + "</i>"
+					back += "<br><i>" + export.Markdown(w.EnExt) + "</i>"
 				}
 				if w.Cog != "" {
-					back += "<br>[" + w.Cog + "]"
+					back += "<br>[" + export.Markdown(w.Cog) + "]"
 				}
 				// Write entry
-				err := cwtr.Write([]string{id, front, back, tagstr, deck})
+				err := writeCard(id, front, back)
 				if err != nil {
 					return err
 				}
@@ -301,44 +582,190 @@ func exportVocab(wtr io.Writer, vocab []UnitVocab, opts Options) error {
 		return err
 	}
 
-	return nil
+	return errs.Err()
 }
 
 func RunCLI(wtr io.Writer, opts Options) error {
-	dataset := opts.Args.Filename
-	data, err := os.ReadFile(dataset)
+	if err := checkFormatOptions(opts); err != nil {
+		return err
+	}
+	if opts.Typed && opts.Cloze {
+		return errors.New("--typed cannot be used with --cloze")
+	}
+	tmpl, err := export.LoadTemplates(opts.FrontTemplate, opts.BackTemplate)
 	if err != nil {
 		return err
 	}
-
-	var vocab []UnitVocab
-	err = yaml.Unmarshal(data, &vocab)
+	if tmpl != nil && opts.Cloze {
+		return errors.New("templates cannot be used with --cloze")
+	}
+	if opts.Flat && (opts.Incremental != "" || opts.SubdeckBy != "") {
+		return errors.New("--flat cannot be used with --incr or --subdeck-by")
+	}
+	var fieldMap []fieldMapping
+	if opts.FieldMap != "" {
+		if opts.Cloze {
+			return errors.New("--field-map cannot be used with --cloze")
+		}
+		fieldMap, err = parseFieldMap(opts.FieldMap)
+		if err != nil {
+			return err
+		}
+	}
+	if (opts.DeckDescription != "" || opts.NewPerDay != 0) && opts.DecksFile == "" {
+		return errors.New("--deck-description and --new-per-day require --decks-file")
+	}
+	if opts.NewPerDay < 0 {
+		return fmt.Errorf("invalid --new-per-day %d", opts.NewPerDay)
+	}
+	deckOpts, err := export.NewDeckOptions(opts.DeckDescription, opts.NewPerDay)
 	if err != nil {
 		return err
 	}
+	if opts.DumpNotetype {
+		notetype, err := export.FindNotetype(formatNotetype(opts), export.DefaultStyle())
+		if err != nil {
+			return err
+		}
+		notetype.Write(wtr)
+		return nil
+	}
 
-	stats := make(map[string]int)
-	err = exportVocab(wtr, vocab, opts)
+	paths := dataset.DefaultPaths(opts.Args.Filenames, defaultFilename)
+	filenames, err := dataset.ExpandPaths(paths)
 	if err != nil {
 		return err
 	}
-	//stats["errors"] = errors
+	dec := dataset.OpenFiles(filenames)
+	defer dec.Close()
+	var units unitDecoder = dec
+	if opts.Overlay != "" {
+		units, err = loadOverlay(opts.Overlay, dec)
+		if err != nil {
+			return err
+		}
+	}
+	switch opts.Format {
+	case formatMemrise, formatPlain:
+		return runRows(wtr, units, opts)
+	case formatMochi:
+		return runMochi(wtr, units, opts)
+	}
+	var incr *incrGrouper
+	if opts.Incremental != "" {
+		incr, err = parseIncr(opts.Incremental)
+		if err != nil {
+			return err
+		}
+		// Halving the units requires reading them all up front
+		if incr.mode == incrHalf {
+			buf, err := bufferUnits(units)
+			if err != nil {
+				return err
+			}
+			incr.split(buf.units)
+			units = buf
+		}
+	}
+	// Linking compounds to their simplexes also requires all the units
+	var simplexes simplexIndex
+	if opts.Compounds {
+		buf, ok := units.(*unitBuffer)
+		if !ok {
+			buf, err = bufferUnits(units)
+			if err != nil {
+				return err
+			}
+			units = buf
+		}
+		simplexes = newSimplexIndex(buf.units)
+	}
 
-	if len(stats) > 0 {
-		jstats, err := json.MarshalIndent(stats, "", "  ")
+	// In dry-run mode, just report what would be exported
+	out := wtr
+	if opts.DryRun {
+		out = io.Discard
+	}
+	cwtr := export.NewWriter(out, export.Separators[opts.Separator], deckColumnPos)
+	cwtr.Strict = opts.Strict
+	cwtr.Progress = export.StderrProgress(opts.Quiet || opts.DryRun)
+	// Mnemosyne cards are collected from the CSV records, without headers
+	hdr := out
+	if opts.Format == formatMnemosyne {
+		cwtr.Collect = true
+		hdr = io.Discard
+	}
+	var media *export.Media
+	if !opts.DryRun {
+		media = export.NewMedia(opts.MediaDir, "export_anki_vocab")
+	}
+	var morpheus morpheusNotes
+	if opts.Morpheus {
+		morpheus, err = enrich.LoadSidecars[[]enrich.Analysis](filenames, enrich.SourceMorpheus)
+		if err != nil {
+			return err
+		}
+	}
+	var lsj lsjNotes
+	if opts.LSJ {
+		lsj, err = enrich.LoadSidecars[string](filenames, enrich.SourceLSJ)
+		if err != nil {
+			return err
+		}
+	}
+	err = exportVocab(hdr, cwtr, units, incr, tmpl, fieldMap, media, simplexes, morpheus, lsj, opts)
+	cwtr.Progress.Done()
+	if cwtr.Collect && !opts.DryRun {
+		items := export.MnemosyneItems(cwtr.Records, true)
+		if werr := export.WriteMnemosyne(wtr, items); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	if media != nil {
+		if merr := media.Finish(opts.PruneMedia); merr != nil && err == nil {
+			err = merr
+		}
+	}
+	if opts.DryRun {
+		cwtr.WriteSummary(wtr)
+	} else {
+		for _, warning := range cwtr.Warnings {
+			slog.Warn(warning)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if opts.DecksFile != "" && !opts.DryRun {
+		err = export.WriteDecksFile(opts.DecksFile, cwtr.Decks, deckOpts)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-		fmt.Fprintln(wtr, string(jstats))
 	}
 
 	return nil
 }
 
+// run runs a single export to the configured output
+func run(opts Options) error {
+	wtr, err := export.Create(opts.Outfile, opts.Compress, opts.BOM)
+	if err != nil {
+		return fmt.Errorf("opening outfile: %w", err)
+	}
+	err = RunCLI(wtr, opts)
+	if cerr := wtr.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
 func main() {
-	log.SetFlags(0)
 	// Parse default options are HelpFlag | PrintErrors | PassDoubleDash
 	var opts Options
+	opts.Version = func() {
+		fmt.Println(buildinfo.String("export_anki_vocab"))
+		os.Exit(0)
+	}
 	parser := flags.NewParser(&opts, flags.Default)
 	_, err := parser.Parse()
 	if err != nil {
@@ -351,16 +778,22 @@ func main() {
 		parser.WriteHelp(os.Stderr)
 		os.Exit(2)
 	}
+	logging.Setup(opts.Options, opts.Verbose)
 
-	wtr := os.Stdout
-	if opts.Outfile != "" {
-		wtr, err = os.Create(opts.Outfile)
-		if err != nil {
-			log.Fatal("opening outfile: ", err)
+	// Dry runs only write a summary, to stdout
+	if opts.DryRun {
+		opts.Outfile, opts.Compress, opts.BOM = "", false, false
+	}
+	if opts.Watch {
+		paths := dataset.DefaultPaths(opts.Args.Filenames, defaultFilename)
+		if opts.Overlay != "" {
+			paths = append(paths, opts.Overlay)
 		}
+		err = watch.Run(paths, func() error { return run(opts) })
+	} else {
+		err = run(opts)
 	}
-	err = RunCLI(wtr, opts)
 	if err != nil {
-		log.Fatal(err)
+		logging.Fatal(err)
 	}
 }
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gavincarr/mag/export"
+)
+
+// fieldMapping maps an extra word field (from a word's fields: map) to
+// an Anki CSV column. The Front and Back columns have the field appended;
+// any other column is added to the CSV.
+type fieldMapping struct {
+	Field  string
+	Column string
+}
+
+// parseFieldMap parses a --field-map spec of the form
+// "field=Column,field=Column"
+func parseFieldMap(spec string) ([]fieldMapping, error) {
+	var mappings []fieldMapping
+	columns := make(map[string]bool)
+	for _, s := range strings.Split(spec, ",") {
+		field, column, ok := strings.Cut(s, "=")
+		field, column = strings.TrimSpace(field), strings.TrimSpace(column)
+		if !ok || field == "" || column == "" {
+			return nil, fmt.Errorf("invalid --field-map entry %q (want field=Column)", s)
+		}
+		for _, c := range csvColumns {
+			if column == c && column != "Front" && column != "Back" {
+				return nil, fmt.Errorf("invalid --field-map column %q (reserved)", column)
+			}
+		}
+		if columns[column] && column != "Front" && column != "Back" {
+			return nil, fmt.Errorf("duplicate --field-map column %q", column)
+		}
+		columns[column] = true
+		mappings = append(mappings, fieldMapping{Field: field, Column: column})
+	}
+	return mappings, nil
+}
+
+// extraColumns returns the CSV columns added by mappings
+func extraColumns(mappings []fieldMapping) []string {
+	var columns []string
+	for _, m := range mappings {
+		if m.Column != "Front" && m.Column != "Back" {
+			columns = append(columns, m.Column)
+		}
+	}
+	return columns
+}
+
+// applyFields adds the extra fields of w to the card front and back as
+// directed by mappings, and returns the values of any extra columns
+func applyFields(w Word, mappings []fieldMapping, front, back string) (string, string, []string) {
+	var extras []string
+	for _, m := range mappings {
+		value := export.Markdown(w.Fields[m.Field])
+		switch m.Column {
+		case "Front":
+			if value != "" {
+				front += "<br>" + value
+			}
+		case "Back":
+			if value != "" {
+				back += "<br>" + value
+			}
+		default:
+			extras = append(extras, value)
+		}
+	}
+	return front, back, extras
+}
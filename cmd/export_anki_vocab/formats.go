@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Output formats, besides the default Anki CSV
+const (
+	formatAnki      = "anki"
+	formatMemrise   = "memrise"
+	formatMochi     = "mochi"
+	formatMnemosyne = "mnemosyne"
+	formatPlain     = "plain"
+)
+
+// checkFormatOptions returns an error if opts sets an option that isn't
+// supported by its --format. The Anki card options are only supported
+// by the anki format, unless listed for others.
+func checkFormatOptions(opts Options) error {
+	if opts.Format == "" || opts.Format == formatAnki {
+		return nil
+	}
+	formatOptions := []struct {
+		name    string
+		set     bool
+		formats []string // non-anki formats supporting the option
+	}{
+		{"--typed", opts.Typed, nil},
+		{"--cloze", opts.Cloze, nil},
+		{"--dump-notetype", opts.DumpNotetype, nil},
+		{"--front-template", opts.FrontTemplate != "", []string{formatMnemosyne}},
+		{"--back-template", opts.BackTemplate != "", []string{formatMnemosyne}},
+		{"--field-map", opts.FieldMap != "", nil},
+		{"--compounds", opts.Compounds, []string{formatMnemosyne}},
+		{"--morpheus", opts.Morpheus, []string{formatMnemosyne}},
+		{"--lsj", opts.LSJ, []string{formatMnemosyne}},
+		{"--links", opts.Links != "", []string{formatMnemosyne}},
+		{"--incr", opts.Incremental != "", []string{formatMnemosyne}},
+		{"--flat", opts.Flat, []string{formatMochi, formatMnemosyne}},
+		{"--subdeck-by", opts.SubdeckBy != "", []string{formatMochi, formatMnemosyne}},
+		{"--deckname", opts.DeckName != "", []string{formatMochi, formatMnemosyne}},
+		{"--decks-file", opts.DecksFile != "", nil},
+		{"--bom", opts.BOM, []string{formatMemrise, formatPlain}},
+		{"--compress", opts.Compress, []string{formatMemrise, formatMnemosyne, formatPlain}},
+	}
+	for _, o := range formatOptions {
+		if o.set && !slices.Contains(o.formats, opts.Format) {
+			return fmt.Errorf("%s cannot be used with --format %s", o.name, opts.Format)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/gavincarr/mag/greek"
+)
+
+// reDeclSep matches the separators in decl: fields (e.g. "1/2")
+var reDeclSep = regexp.MustCompile(`\pZ*[/,]\pZ*`)
+
+// articleGenders maps the nominative articles (without accents and
+// breathings) to noun genders
+var articleGenders = map[string]string{
+	"ο": "masculine", "η": "feminine", "το": "neuter",
+	"οι": "masculine", "αι": "feminine", "τα": "neuter",
+}
+
+// greekWords returns the Greek words in s, split on any non-letters
+func greekWords(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.Is(unicode.Mn, r)
+	})
+}
+
+// nounGenders returns the genders of the noun w, from the articles
+// following the headword in its gr and gr_ext fields (e.g. "λόγος,
+// -ου, ὁ"), in order. Nouns of common gender (e.g. "ὁ, ἡ") have more
+// than one.
+func nounGenders(w Word) []string {
+	if w.Pos != "n" {
+		return nil
+	}
+	words := greekWords(w.Gr + " " + w.GrExt)
+	var genders []string
+	seen := make(map[string]bool)
+	for _, word := range words[min(1, len(words)):] {
+		gender, ok := articleGenders[greek.StripDiacritics(word)]
+		if ok && !seen[gender] {
+			genders = append(genders, gender)
+			seen[gender] = true
+		}
+	}
+	return genders
+}
+
+// declensions returns the declensions of the noun or adjective w, from
+// its decl: field if set, or else inferred from its headword endings:
+// the genitive for nouns (e.g. "λόγος, -ου" is second declension), and
+// the feminine and neuter for adjectives (e.g. "ἀγαθός, -ή, -όν" is
+// first and second declension)
+func declensions(w Word) ([]string, error) {
+	if w.Decl != "" {
+		decls := reDeclSep.Split(w.Decl, -1)
+		for _, d := range decls {
+			if d != "1" && d != "2" && d != "3" {
+				return nil, fmt.Errorf("invalid decl %q (want 1, 2, or 3, or a list like 1/2)", w.Decl)
+			}
+		}
+		return decls, nil
+	}
+	words := greekWords(greek.StripDiacritics(w.Gr))
+	for i, word := range words {
+		// Drop noun articles (but not adjective endings like -η)
+		if _, ok := articleGenders[word]; ok && i > 0 && w.Pos == "n" {
+			words = words[:i]
+			break
+		}
+	}
+	if len(words) < 2 {
+		return nil, nil
+	}
+	nom, last := words[0], words[len(words)-1]
+	switch w.Pos {
+	case "n":
+		gen := words[1]
+		switch {
+		case strings.HasSuffix(gen, "ους"):
+			// s-stem neuters like "γένος, -ους"
+			return []string{"3"}, nil
+		case strings.HasSuffix(gen, "ης") || strings.HasSuffix(gen, "ας"):
+			return []string{"1"}, nil
+		case strings.HasSuffix(gen, "ου"):
+			if strings.HasSuffix(nom, "ης") || strings.HasSuffix(nom, "ας") {
+				return []string{"1"}, nil
+			}
+			return []string{"2"}, nil
+		case strings.HasSuffix(gen, "ω"):
+			return []string{"2"}, nil
+		case strings.HasSuffix(gen, "ος") || strings.HasSuffix(gen, "ως"):
+			return []string{"3"}, nil
+		}
+	case "adj":
+		switch {
+		case len(words) >= 3 && strings.HasSuffix(last, "ον"):
+			return []string{"1", "2"}, nil
+		case len(words) >= 3:
+			return []string{"1", "3"}, nil
+		case strings.HasSuffix(nom, "ος") && strings.HasSuffix(last, "ον"):
+			return []string{"2"}, nil
+		default:
+			return []string{"3"}, nil
+		}
+	}
+	return nil, nil
+}
+
+// verbClasses returns the classes of the verb w, from its vclass: field
+// if set, or else inferred from its headword
+func verbClasses(w Word) ([]string, error) {
+	if w.VClass != "" {
+		return greek.ParseVerbClasses(w.VClass)
+	}
+	if w.Pos != "v" {
+		return nil, nil
+	}
+	return greek.VerbClasses(w.Gr), nil
+}
+
+// grammarTags returns the grammatical tags for w: gender:: tags for
+// nouns, decl:: tags for nouns and adjectives, and vclass:: tags for
+// verbs
+func grammarTags(w Word) ([]string, error) {
+	var tags []string
+	for _, gender := range nounGenders(w) {
+		tags = append(tags, "gender::"+gender)
+	}
+	decls, err := declensions(w)
+	if err != nil {
+		return nil, err
+	}
+	for _, decl := range decls {
+		tags = append(tags, "decl::"+decl)
+	}
+	classes, err := verbClasses(w)
+	if err != nil {
+		return nil, err
+	}
+	for _, class := range classes {
+		tags = append(tags, "vclass::"+class)
+	}
+	return tags, nil
+}
+
+// grammarFields returns the fields of w with any derived grammatical
+// fields added (gender for nouns, decl for nouns and adjectives, and
+// vclass for verbs), for mapping with --field-map. Fields set explicitly in the dataset
+// are not overridden.
+func grammarFields(w Word) map[string]string {
+	derived := map[string]string{}
+	if genders := nounGenders(w); len(genders) > 0 {
+		derived["gender"] = strings.Join(genders, "/")
+	}
+	if decls, _ := declensions(w); len(decls) > 0 {
+		derived["decl"] = strings.Join(decls, "/")
+	}
+	if classes, _ := verbClasses(w); len(classes) > 0 {
+		derived["vclass"] = strings.Join(classes, "/")
+	}
+	if len(derived) == 0 {
+		return w.Fields
+	}
+	for k, v := range w.Fields {
+		derived[k] = v
+	}
+	return derived
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDeclensions(t *testing.T) {
+	tests := []struct {
+		gr, pos, decl string
+		want          []string
+	}{
+		{"λόγος, -ου, ὁ", "n", "", []string{"2"}},
+		{"ὁδός, -οῦ, ἡ", "n", "", []string{"2"}},
+		{"χώρα, -ας, ἡ", "n", "", []string{"1"}},
+		{"πολίτης, -ου, ὁ", "n", "", []string{"1"}},
+		{"νεώς, -ώ, ὁ", "n", "", []string{"2"}},
+		{"φύλαξ, -ακος, ὁ", "n", "", []string{"3"}},
+		{"βασιλεύς, -έως, ὁ", "n", "", []string{"3"}},
+		{"γένος, -ους, τό", "n", "", []string{"3"}},
+		{"τεῖχος, τείχους, τό", "n", "", []string{"3"}},
+		{"ἀγαθός, -ή, -όν", "adj", "", []string{"1", "2"}},
+		{"πᾶς, πᾶσα, πᾶν", "adj", "", []string{"1", "3"}},
+		{"ἄδικος, -ον", "adj", "", []string{"2"}},
+		{"ἀληθής, -ές", "adj", "", []string{"3"}},
+		{"ναῦς, νεώς, ἡ", "n", "3", []string{"3"}},
+		{"καί", "conj", "", nil},
+	}
+
+	for _, tc := range tests {
+		got, err := declensions(Word{Gr: tc.gr, Pos: tc.pos, Decl: tc.decl})
+		if err != nil {
+			t.Errorf("declensions(%q): %s", tc.gr, err)
+			continue
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("declensions(%q): got %v, want %v", tc.gr, got, tc.want)
+		}
+	}
+
+	if _, err := declensions(Word{Gr: "λόγος, -ου, ὁ", Pos: "n", Decl: "4"}); err == nil {
+		t.Errorf("declensions with decl 4: want error")
+	}
+}
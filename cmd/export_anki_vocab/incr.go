@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	deckNameIncrGrEn = "Mastronarde AtticGreek Vocab (Incr,GrEn)"
+	incrCore         = "core"
+	incrHalf         = "half"
+)
+
+// incrGrouper assigns units to incremental subdecks, grouping them
+// either by core/extended vocab, or by ranges of unit numbers
+type incrGrouper struct {
+	mode   string
+	starts []int // first unit number of each group, ascending
+	last   int   // last unit number, if known
+}
+
+// parseIncr parses an --incr grouping spec: "core" (main dataset vs
+// overlay words), "half" (first/second half of the units), or a
+// comma-separated list of the first unit numbers of each group
+func parseIncr(spec string) (*incrGrouper, error) {
+	switch spec {
+	case incrCore, incrHalf:
+		return &incrGrouper{mode: spec}, nil
+	}
+	g := &incrGrouper{}
+	for _, s := range strings.Split(spec, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid --incr grouping %q (want core, half, or a list of unit numbers)", spec)
+		}
+		g.starts = append(g.starts, n)
+	}
+	sort.Ints(g.starts)
+	if g.starts[0] != 1 {
+		g.starts = append([]int{1}, g.starts...)
+	}
+	return g, nil
+}
+
+// split sets the unit groups for a half grouping of units
+func (g *incrGrouper) split(units []UnitVocab) {
+	if len(units) == 0 {
+		return
+	}
+	first, last := units[0].Unit, units[0].Unit
+	for _, u := range units {
+		first = min(first, u.Unit)
+		last = max(last, u.Unit)
+	}
+	g.starts = []int{first}
+	if mid := first + (last-first+2)/2; mid <= last {
+		g.starts = append(g.starts, mid)
+	}
+	g.last = last
+}
+
+// group returns the subdeck name for unit u
+func (g *incrGrouper) group(u UnitVocab) string {
+	if g.mode == incrCore {
+		if u.Extended {
+			return "Extended"
+		}
+		return "Core"
+	}
+	i := sort.SearchInts(g.starts, u.Unit+1) - 1
+	if i < 0 {
+		return fmt.Sprintf("Units %d", u.Unit)
+	}
+	first := g.starts[i]
+	switch {
+	case i+1 < len(g.starts):
+		return fmt.Sprintf("Units %d-%d", first, g.starts[i+1]-1)
+	case g.last > first:
+		return fmt.Sprintf("Units %d-%d", first, g.last)
+	case g.last == first:
+		return fmt.Sprintf("Units %d", first)
+	}
+	return fmt.Sprintf("Units %d+", first)
+}
+
+// unitBuffer holds all the units read from a unitDecoder, for groupings
+// that need to see the whole dataset before exporting
+type unitBuffer struct {
+	units []UnitVocab
+	files []string // source filename of each unit
+	file  string
+}
+
+// bufferUnits reads all remaining units from dec
+func bufferUnits(dec unitDecoder) (*unitBuffer, error) {
+	b := &unitBuffer{}
+	for {
+		var u UnitVocab
+		err := dec.Decode(&u)
+		if errors.Is(err, io.EOF) {
+			return b, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		b.units = append(b.units, u)
+		b.files = append(b.files, dec.Filename())
+	}
+}
+
+// Decode decodes the next buffered unit into v, which must be a *UnitVocab
+func (b *unitBuffer) Decode(v interface{}) error {
+	if len(b.units) == 0 {
+		return io.EOF
+	}
+	*v.(*UnitVocab) = b.units[0]
+	b.file = b.files[0]
+	b.units, b.files = b.units[1:], b.files[1:]
+	return nil
+}
+
+// Filename returns the name of the dataset file the last unit came from
+func (b *unitBuffer) Filename() string {
+	return b.file
+}
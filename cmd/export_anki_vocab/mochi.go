@@ -0,0 +1,303 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gavincarr/mag/dataset"
+	"github.com/gavincarr/mag/export"
+)
+
+// mochiVersion is the version of the Mochi import format written
+const mochiVersion = 2
+
+// mochiData is the data.json file of a .mochi deck file
+type mochiData struct {
+	Version int          `json:"version"`
+	Decks   []*mochiDeck `json:"decks"`
+}
+
+// mochiDeck is a Mochi deck, nested by its parent id
+type mochiDeck struct {
+	ID       string      `json:"id"`
+	Name     string      `json:"name"`
+	ParentID string      `json:"parent-id,omitempty"`
+	Cards    []mochiCard `json:"cards,omitempty"`
+	path     string      // full deck name, with parent deck names
+}
+
+// mochiCard is a Mochi card, with Markdown content whose front and back
+// are separated by a --- line
+type mochiCard struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Content string   `json:"content"`
+	DeckID  string   `json:"deck-id"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+// mochiID returns a stable Mochi id for the deck or card key, so that
+// re-imports update the existing decks and cards
+func mochiID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	id := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(sum[:])
+	return strings.ToLower(id[:8])
+}
+
+// mochiTag returns the Anki tag as a Mochi tag, without the hierarchy
+// separators (e.g. "pos-noun" for "pos::noun")
+func mochiTag(tag string) string {
+	return strings.ReplaceAll(tag, "::", "-")
+}
+
+// mochiDecks builds the decks of a .mochi file, creating each deck the
+// first time a card is added to it or one of its subdecks
+type mochiDecks struct {
+	decks []*mochiDeck
+	paths map[string]*mochiDeck
+}
+
+// deck returns the deck with the path names, creating it and any
+// parent decks as needed
+func (m *mochiDecks) deck(names []string) *mochiDeck {
+	path := strings.Join(names, "::")
+	if d, ok := m.paths[path]; ok {
+		return d
+	}
+	d := &mochiDeck{ID: mochiID("deck:" + path), Name: names[len(names)-1], path: path}
+	if len(names) > 1 {
+		d.ParentID = m.deck(names[:len(names)-1]).ID
+	}
+	if m.paths == nil {
+		m.paths = make(map[string]*mochiDeck)
+	}
+	m.paths[path] = d
+	m.decks = append(m.decks, d)
+	return d
+}
+
+// mochiContent returns the Markdown content of the card for w: its
+// headword on the front, and its glosses, example phrase, cognates,
+// references, and image (as a Mochi attachment) on the back
+func mochiContent(w Word, homonym int, smyth, ref, attachment string) string {
+	front := w.Gr
+	if homonym > 0 {
+		front += fmt.Sprintf(" (%d)", homonym)
+	}
+	if w.GrExt != "" {
+		front += " " + w.GrExt
+	}
+	if homonym > 0 {
+		front += " *[" + w.Homonym + "]*"
+	}
+	back := []string{strings.Join(reSemicolon.Split(w.En, -1), "  \n")}
+	if w.EnExt != "" {
+		back = append(back, strings.TrimSpace(w.EnExt))
+	}
+	if w.Cog != "" {
+		back = append(back, `\[`+w.Cog+`\]`)
+	}
+	for _, note := range []string{smyth, ref} {
+		if note != "" {
+			back = append(back, note)
+		}
+	}
+	if attachment != "" {
+		back = append(back, "![](@media/"+attachment+")")
+	}
+	return "## " + front + "\n\n---\n\n" + strings.Join(back, "\n\n")
+}
+
+// exportMochi exports the vocab units read from dec as Mochi decks, a
+// parent deck with a subdeck per unit (unless opts.Flat is set), and
+// the attachments they reference, keyed by attachment name. Bad entries
+// are skipped, and returned as a dataset.Errors after the export
+// completes.
+func exportMochi(dec unitDecoder, opts Options) (*mochiData, map[string]string, error) {
+	deckName := deckNameGrEn
+	if opts.DeckName != "" {
+		deckName = opts.DeckName
+	}
+	count := 0
+	var decks mochiDecks
+	attachments := make(map[string]string) // attachment name => path
+	idmap := make(map[string]struct{})
+	homonyms := make(map[string]int) // homonym counts by base id
+	var errs dataset.Errors
+
+	data := &mochiData{Version: mochiVersion}
+	for {
+		var u UnitVocab
+		err := dec.Decode(&u)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if opts.Unit > 0 && u.Unit != opts.Unit {
+			// Count skipped homonyms, so numbering doesn't depend on --unit
+			for _, w := range u.Vocab {
+				if w.Homonym != "" {
+					homonyms[wordID(w)]++
+				}
+			}
+			continue
+		}
+
+		words := u.Vocab
+		if opts.Sort == "alpha" {
+			words = sortWords(words)
+		}
+
+		for _, w := range words {
+			if opts.Count > 0 && count >= opts.Count {
+				break
+			}
+			entryErr := func(err error) {
+				errs = append(errs, &dataset.EntryError{
+					File: dec.Filename(), Line: w.Line,
+					Unit: u.Name, Entry: w.Gr, Err: err,
+				})
+			}
+			pos, ok := dataset.PartsOfSpeech[w.Pos]
+			if !ok {
+				entryErr(fmt.Errorf("bad POS %q%s", w.Pos,
+					dataset.FormatSuggestions(dataset.SuggestPOS(w.Pos))))
+				continue
+			}
+
+			id := wordID(w)
+			homonym := 0
+			if w.Homonym != "" {
+				homonyms[id]++
+				homonym = homonyms[id]
+				id = fmt.Sprintf("%s (%d)", id, homonym)
+			}
+			if _, exists := idmap[id]; exists {
+				entryErr(fmt.Errorf("duplicate id %q (run %s for a full report, or set homonym: on homographs)",
+					id, lintCommand))
+				continue
+			}
+			idmap[id] = struct{}{}
+
+			tags := []string{"pos::" + pos, fmt.Sprintf("unit::%02d", u.Unit)}
+			gtags, err := grammarTags(w)
+			if err != nil {
+				entryErr(err)
+				continue
+			}
+			tags = append(tags, gtags...)
+			smyth, smythTags := export.SmythRefs(w.Smyth)
+			tags = append(tags, smythTags...)
+			ref, refTags := export.MastronardeRefs(w.Ref)
+			tags = append(tags, refTags...)
+			for i, tag := range tags {
+				tags[i] = mochiTag(tag)
+			}
+
+			// Attach any image, named by its card id to keep names unique
+			attachment := ""
+			if w.Img != "" {
+				img := imagePath(w.Img, dec.Filename())
+				if _, err := os.Stat(img); err != nil {
+					entryErr(err)
+					continue
+				}
+				attachment = mochiID("img:"+id) + strings.ToLower(filepath.Ext(img))
+				attachments[attachment] = img
+			}
+
+			deckslice := []string{deckName, u.Name}
+			if opts.Flat {
+				deckslice = []string{deckName}
+			}
+			if opts.SubdeckBy == subdeckPOS {
+				deckslice = append(deckslice, posSubdeck(pos))
+			}
+			deck := decks.deck(deckslice)
+			deck.Cards = append(deck.Cards, mochiCard{
+				ID:      mochiID("card:" + id),
+				Name:    id,
+				Content: mochiContent(w, homonym, smyth, ref, attachment),
+				DeckID:  deck.ID,
+				Tags:    tags,
+			})
+			count++
+		}
+	}
+	data.Decks = decks.decks
+	return data, attachments, errs.Err()
+}
+
+// writeMochi writes data and its attachments to wtr as a .mochi file:
+// a zip archive of data.json and the attachment files
+func writeMochi(wtr io.Writer, data *mochiData, attachments map[string]string) error {
+	zwtr := zip.NewWriter(wtr)
+	f, err := zwtr.Create("data.json")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetEscapeHTML(false)
+	err = enc.Encode(data)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(attachments))
+	for name := range attachments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f, err := zwtr.Create(name)
+		if err != nil {
+			return err
+		}
+		src, err := os.Open(attachments[name])
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, src)
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return zwtr.Close()
+}
+
+// runMochi exports units to wtr as a .mochi file, or in dry-run mode
+// reports the cards per deck that would be exported
+func runMochi(wtr io.Writer, units unitDecoder, opts Options) error {
+	data, attachments, err := exportMochi(units, opts)
+	if data == nil {
+		return err
+	}
+	if opts.DryRun {
+		total := 0
+		for _, d := range data.Decks {
+			total += len(d.Cards)
+		}
+		fmt.Fprintf(wtr, "%d card(s) in %d deck(s):\n", total, len(data.Decks))
+		for _, d := range data.Decks {
+			if len(d.Cards) > 0 {
+				fmt.Fprintf(wtr, "  %s: %d\n", d.path, len(d.Cards))
+			}
+		}
+		return err
+	}
+	if werr := writeMochi(wtr, data, attachments); werr != nil {
+		return werr
+	}
+	return err
+}
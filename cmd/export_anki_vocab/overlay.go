@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"io"
+
+	"github.com/gavincarr/mag/dataset"
+)
+
+// unitDecoder is a source of vocab units
+type unitDecoder interface {
+	Decode(v interface{}) error
+	Filename() string
+}
+
+// overlayDecoder merges the words of a personal overlay dataset over the
+// units read from a main dataset. Overlay words whose ids match a main
+// dataset word override its fields; the remaining overlay words are
+// returned as additional units after the main dataset is exhausted.
+type overlayDecoder struct {
+	dec      unitDecoder
+	filename string
+	units    []UnitVocab
+	byID     map[string]*Word
+	used     map[string]bool
+	inMain   bool // still reading the main dataset
+}
+
+// loadOverlay reads the overlay dataset filename, returning an
+// overlayDecoder applying it to the units from dec
+func loadOverlay(filename string, dec unitDecoder) (*overlayDecoder, error) {
+	data, err := dataset.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var units []UnitVocab
+	err = dataset.Unmarshal(data, &units)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &overlayDecoder{
+		dec:      dec,
+		filename: filename,
+		units:    units,
+		byID:     make(map[string]*Word),
+		used:     make(map[string]bool),
+		inMain:   true,
+	}
+	for i := range units {
+		for j := range units[i].Vocab {
+			w := &units[i].Vocab[j]
+			o.byID[wordID(*w)] = w
+		}
+	}
+	return o, nil
+}
+
+// mergeWord overrides the fields of w with any set in ow
+func mergeWord(w *Word, ow Word) {
+	fields := []struct {
+		dst *string
+		src string
+	}{
+		{&w.GrMP, ow.GrMP},
+		{&w.GrPl, ow.GrPl},
+		{&w.GrExt, ow.GrExt},
+		{&w.En, ow.En},
+		{&w.EnExt, ow.EnExt},
+		{&w.Cog, ow.Cog},
+		{&w.Pos, ow.Pos},
+		{&w.Decl, ow.Decl},
+		{&w.VClass, ow.VClass},
+		{&w.Smyth, ow.Smyth},
+		{&w.Ref, ow.Ref},
+		{&w.Homonym, ow.Homonym},
+		{&w.Img, ow.Img},
+	}
+	for _, f := range fields {
+		if f.src != "" {
+			*f.dst = f.src
+		}
+	}
+	if len(ow.Fields) > 0 {
+		merged := make(map[string]string, len(w.Fields)+len(ow.Fields))
+		for k, v := range w.Fields {
+			merged[k] = v
+		}
+		for k, v := range ow.Fields {
+			merged[k] = v
+		}
+		w.Fields = merged
+	}
+}
+
+// Decode decodes the next unit into v, which must be a *UnitVocab
+func (o *overlayDecoder) Decode(v interface{}) error {
+	u := v.(*UnitVocab)
+	if o.inMain {
+		err := o.dec.Decode(u)
+		if err == nil {
+			for i := range u.Vocab {
+				id := wordID(u.Vocab[i])
+				if ow, ok := o.byID[id]; ok {
+					mergeWord(&u.Vocab[i], *ow)
+					o.used[id] = true
+				}
+			}
+			return nil
+		}
+		if !errors.Is(err, io.EOF) {
+			return err
+		}
+		o.inMain = false
+	}
+
+	// Return overlay units with any words not used as overrides
+	for len(o.units) > 0 {
+		ou := o.units[0]
+		o.units = o.units[1:]
+		words := []Word{}
+		for _, w := range ou.Vocab {
+			if !o.used[wordID(w)] {
+				words = append(words, w)
+			}
+		}
+		if len(words) > 0 {
+			*u = ou
+			u.Vocab = words
+			u.Extended = true
+			return nil
+		}
+	}
+	return io.EOF
+}
+
+// Filename returns the name of the dataset file currently being read
+func (o *overlayDecoder) Filename() string {
+	if o.inMain {
+		return o.dec.Filename()
+	}
+	return o.filename
+}
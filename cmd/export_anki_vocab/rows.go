@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gavincarr/mag/dataset"
+	"github.com/gavincarr/mag/export"
+	"github.com/gavincarr/mag/greek"
+)
+
+// rowFunc returns the tab-separated row fields for w, with part of
+// speech pos, in a plain text --format
+type rowFunc func(w Word, pos string) []string
+
+// rowFormats maps the plain text --format choices to their rows
+var rowFormats = map[string]rowFunc{
+	formatMemrise: memriseRow,
+	formatPlain:   plainRow,
+}
+
+// rowHeadword returns the headword of w for a plain text row: its gr
+// and gr_ext fields, and any homonym hint
+func rowHeadword(w Word) string {
+	word := w.Gr
+	if w.GrExt != "" {
+		word += " " + w.GrExt
+	}
+	if w.Homonym != "" {
+		word += " [" + w.Homonym + "]"
+	}
+	return word
+}
+
+// rowGloss returns the glosses of w as plain text, separated by "; "
+func rowGloss(w Word) string {
+	return reSemicolon.ReplaceAllString(export.PlainText(w.En), "; ")
+}
+
+// memriseRow returns the Memrise bulk-add row for w: its word,
+// definition, part of speech, and pronunciation (a transliteration)
+func memriseRow(w Word, pos string) []string {
+	return []string{
+		rowHeadword(w),
+		rowGloss(w),
+		pos,
+		greek.Transliterate(reCommaStar.ReplaceAllString(w.Gr, "")),
+	}
+}
+
+// plainRow returns the plain row for w: its headword and gloss
+func plainRow(w Word, pos string) []string {
+	return []string{rowHeadword(w), rowGloss(w)}
+}
+
+// rowField returns s as a tab-separated row field, which can't contain
+// tabs or line breaks
+func rowField(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// exportRows exports the vocab units read from dec to wtr as plain text
+// rows of tab-separated fields returned by row, with no header row. It
+// returns the number of words exported. Bad entries are skipped, and
+// returned as a dataset.Errors after the export completes.
+func exportRows(wtr io.Writer, dec unitDecoder, row rowFunc, progress *export.Progress, opts Options) (int, error) {
+	count := 0
+	var errs dataset.Errors
+	for {
+		var u UnitVocab
+		err := dec.Decode(&u)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return count, err
+		}
+		if opts.Unit > 0 && u.Unit != opts.Unit {
+			continue
+		}
+
+		words := u.Vocab
+		if opts.Sort == "alpha" {
+			words = sortWords(words)
+		}
+
+		for _, w := range words {
+			if opts.Count > 0 && count >= opts.Count {
+				return count, errs.Err()
+			}
+			pos, ok := dataset.PartsOfSpeech[w.Pos]
+			if !ok {
+				errs = append(errs, &dataset.EntryError{
+					File: dec.Filename(), Line: w.Line,
+					Unit: u.Name, Entry: w.Gr,
+					Err: fmt.Errorf("bad POS %q%s", w.Pos,
+						dataset.FormatSuggestions(dataset.SuggestPOS(w.Pos))),
+				})
+				continue
+			}
+
+			fields := row(w, pos)
+			for i, field := range fields {
+				fields[i] = rowField(field)
+			}
+			_, err := fmt.Fprintln(wtr, strings.Join(fields, "\t"))
+			if err != nil {
+				return count, err
+			}
+			progress.Add(1)
+			count++
+		}
+	}
+	return count, errs.Err()
+}
+
+// runRows exports units to wtr as plain text rows in opts.Format, or in
+// dry-run mode reports the number of words that would be exported
+func runRows(wtr io.Writer, units unitDecoder, opts Options) error {
+	out := wtr
+	if opts.DryRun {
+		out = io.Discard
+	}
+	progress := export.StderrProgress(opts.Quiet || opts.DryRun)
+	count, err := exportRows(out, units, rowFormats[opts.Format], progress, opts)
+	progress.Done()
+	if opts.DryRun {
+		fmt.Fprintf(wtr, "%d word(s) for --format %s\n", count, opts.Format)
+	}
+	return err
+}
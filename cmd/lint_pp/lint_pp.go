@@ -3,189 +3,117 @@
 package main
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
-	"regexp"
+	"path/filepath"
 
 	flags "github.com/jessevdk/go-flags"
-	yaml "gopkg.in/yaml.v3"
-)
 
-var (
-	reEntry = regexp.MustCompile(`^\(?-?\p{Greek}+( ((or|and)( \(rare\))? )?\(?-?\p{Greek}+\)?)?(\pZ+\(stem \p{Greek}+-\))?\)?$`)
+	"github.com/gavincarr/mag/buildinfo"
+	"github.com/gavincarr/mag/dataset"
+	"github.com/gavincarr/mag/lint"
+	"github.com/gavincarr/mag/logging"
+	"github.com/gavincarr/mag/watch"
 )
 
-type Record struct {
-	Pr string
-	Fu string
-	Ao string
-	Pf string
-	Pm string
-	Ap string
-}
-
-type UnitPP struct {
-	Name string
-	Unit int
-	PP   []Record
-}
+const defaultFilename = "pp.yml"
 
 // Options
 type Options struct {
-	Verbose bool `short:"v" long:"verbose" description:"display verbose output"`
-	Unit    int  `short:"u" long:"unit" description:"lint only this unit number"`
-	Args    struct {
-		Filename string `description:"principal parts yml dataset to read" default:"pp.yml"`
+	Verbose bool   `short:"v" long:"verbose" description:"display verbose output"`
+	Version func() `long:"version" description:"print version and build information and exit"`
+	logging.Options
+	Unit        int    `short:"u" long:"unit" env:"MAG_UNIT" description:"lint only this unit number"`
+	Config      string `short:"C" long:"config" env:"MAG_LINT_CONFIG" description:"path to lint config file (default: search for .maglint.yml)"`
+	Output      string `long:"output" choice:"text" choice:"json" choice:"sarif" choice:"github" default:"text" env:"MAG_LINT_OUTPUT" description:"output format for findings"`
+	MaxWarnings int    `long:"max-warnings" default:"-1" env:"MAG_MAX_WARNINGS" description:"exit with an error if more than this many warnings are found (-1 for no limit)"`
+	MediaDir    string `long:"media-dir" env:"MAG_MEDIA_DIR" default:"media" description:"media directory whose export manifests to check for missing files (ignored if missing)"`
+	Fix         bool   `long:"fix" description:"fix mechanical issues (whitespace, NFC, final sigma, Latin homoglyphs) in place, leaving the rest of the dataset unchanged"`
+	Watch       bool   `short:"w" long:"watch" description:"watch the datasets and re-lint whenever they change"`
+	Args        struct {
+		Filenames []string `positional-arg-name:"filename" description:"principal parts yml datasets or directories to read (- for stdin; default: $MAG_PP or pp.yml)"`
 	} `positional-args:"yes"`
 }
 
-func checkWord(word, pptype, label string) error {
-	/*
-		fmt.Fprintf(os.Stderr, "+ %s:\n", word)
-		for _, r := range word {
-			fmt.Fprintf(os.Stderr, "  - %x %c\n", r, r)
-		}
-	*/
-	if !reEntry.MatchString(word) {
-		return errors.New(fmt.Sprintf("Bad %q entry found%s: %q",
-			pptype, label, word))
-	}
-	return nil
-}
-
-func LintRecord(wtr io.Writer, rec Record, label string) int {
-	errors := 0
-	if rec.Pr != "" {
-		err := checkWord(rec.Pr, "pr", label)
-		if err != nil {
-			fmt.Fprintln(wtr, err.Error())
-			errors++
-		}
-	}
-	if rec.Fu != "" {
-		err := checkWord(rec.Fu, "fu", label)
-		if err != nil {
-			fmt.Fprintln(wtr, err.Error())
-			errors++
-		}
-	}
-	if rec.Ao != "" {
-		err := checkWord(rec.Ao, "ao", label)
-		if err != nil {
-			fmt.Fprintln(wtr, err.Error())
-			errors++
-		}
-	}
-	if rec.Pf != "" {
-		err := checkWord(rec.Pf, "pf", label)
-		if err != nil {
-			fmt.Fprintln(wtr, err.Error())
-			errors++
-		}
-	}
-	if rec.Pm != "" {
-		err := checkWord(rec.Pm, "pm", label)
-		if err != nil {
-			fmt.Fprintln(wtr, err.Error())
-			errors++
-		}
-	}
-	if rec.Ap != "" {
-		err := checkWord(rec.Ap, "ap", label)
-		if err != nil {
-			fmt.Fprintln(wtr, err.Error())
-			errors++
-		}
+// loadPP reads the dataset filename, applying fixes first if
+// opts.Fix is set, and returns its units and inline suppressions
+func loadPP(wtr io.Writer, filename string, opts Options, stats map[string]int) ([]lint.PPUnit, []*lint.Suppression, error) {
+	if opts.Fix && filename == dataset.Stdin {
+		return nil, nil, errors.New("--fix cannot be used with stdin")
 	}
-	return errors
-}
-
-// LintPP runs a series of checks on pp, and outputs
-// any errors to stdout
-func LintPP(wtr io.Writer, opts Options, pp []UnitPP, stats *map[string]int) int {
-	errors := 0
-	if len(pp) == 0 {
-		fmt.Fprintln(wtr, "Empty pp list!")
-		errors++
-		return errors
+	data, err := dataset.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	for _, u := range pp {
-		if opts.Unit > 0 && u.Unit != opts.Unit {
-			continue
-		}
-
-		(*stats)["units"]++
-		var label string
-		if u.Name != "" {
-			label = fmt.Sprintf(" for unit %q", u.Name)
-		} else if u.Unit >= 3 {
-			label = fmt.Sprintf(" for unit %d", u.Unit)
-		}
-		if u.Name == "" {
-			fmt.Fprintf(wtr, "Empty unit 'name' field found%s\n", label)
-			errors++
-		}
-		if u.Unit == 0 {
-			fmt.Fprintf(wtr, "Empty unit 'unit' field found%s\n", label)
-			errors++
-		} else if u.Unit < 5 || u.Unit > 42 {
-			fmt.Fprintf(wtr, "Invalid unit 'unit' field found%s: %d\n",
-				label, u.Unit)
-			errors++
-		}
-		if len(u.PP) == 0 {
-			fmt.Fprintf(wtr, "Empty unit 'pp' list found%s\n", label)
-			errors++
-			continue
-		}
-		if label == "" {
-			continue
-		}
-
-		for _, rec := range u.PP {
-			(*stats)["records"]++
-			errors += LintRecord(wtr, rec, label)
+	if opts.Fix {
+		var fixes int
+		data, fixes, err = lint.FixFile(wtr, filename, data)
+		if err != nil {
+			return nil, nil, err
 		}
+		stats["fixes"] += fixes
 	}
 
-	return errors
+	return lint.ParsePP(data, filename, opts.Unit)
 }
 
 func RunCLI(wtr io.Writer, opts Options) error {
-	dataset := opts.Args.Filename
-	data, err := os.ReadFile(dataset)
+	paths := dataset.DefaultPaths(opts.Args.Filenames, defaultFilename)
+	filenames, err := dataset.ExpandPaths(paths)
 	if err != nil {
 		return err
 	}
-
-	var pp []UnitPP
-	err = yaml.Unmarshal(data, &pp)
+	config, err := lint.ResolveConfig(opts.Config, filepath.Dir(filenames[0]))
 	if err != nil {
 		return err
 	}
 
+	// Keep fix reports out of structured output
+	fixWtr := wtr
+	if opts.Output != "text" {
+		fixWtr = os.Stderr
+	}
 	stats := make(map[string]int)
-	errors := LintPP(wtr, opts, pp, &stats)
-	stats["errors"] = errors
+	if opts.Fix {
+		stats["fixes"] = 0
+	}
+	l := lint.New("lint_pp", config)
+	var pp []lint.PPUnit
+	for _, filename := range filenames {
+		units, sups, err := loadPP(fixWtr, filename, opts, stats)
+		if err != nil {
+			return err
+		}
+		pp = append(pp, units...)
+		l.AddSuppressions(sups)
+	}
 
-	jstats, err := json.MarshalIndent(stats, "", "  ")
+	lint.LintPP(l, pp, opts.Unit, &stats)
+	err = lint.LintManifests(l, opts.MediaDir)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	l.ReportUnusedSuppressions()
+	stats["errors"] = l.Errors()
+	stats["warnings"] = l.Warnings()
+	err = l.Write(wtr, opts.Output, stats)
+	if err != nil {
+		return err
 	}
-	fmt.Fprintln(wtr, string(jstats))
 
-	return nil
+	return l.Err(opts.MaxWarnings)
 }
 
 func main() {
-	log.SetFlags(0)
 	// Parse default options are HelpFlag | PrintErrors | PassDoubleDash
 	var opts Options
+	opts.Version = func() {
+		fmt.Println(buildinfo.String("lint_pp"))
+		os.Exit(0)
+	}
 	parser := flags.NewParser(&opts, flags.Default)
 	_, err := parser.Parse()
 	if err != nil {
@@ -198,9 +126,15 @@ func main() {
 		parser.WriteHelp(os.Stderr)
 		os.Exit(2)
 	}
+	logging.Setup(opts.Options, opts.Verbose)
 
-	err = RunCLI(os.Stdout, opts)
+	if opts.Watch {
+		paths := dataset.DefaultPaths(opts.Args.Filenames, defaultFilename)
+		err = watch.Run(paths, func() error { return RunCLI(os.Stdout, opts) })
+	} else {
+		err = RunCLI(os.Stdout, opts)
+	}
 	if err != nil {
-		log.Fatal(err)
+		logging.Fatal(err)
 	}
 }
@@ -3,149 +3,117 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
-	"regexp"
+	"path/filepath"
 
 	flags "github.com/jessevdk/go-flags"
-	yaml "gopkg.in/yaml.v3"
-)
 
-var (
-	rePos = regexp.MustCompile(`^(n|v|adj|adv|pron|prep|conj|particle|part)$`)
+	"github.com/gavincarr/mag/buildinfo"
+	"github.com/gavincarr/mag/dataset"
+	"github.com/gavincarr/mag/lint"
+	"github.com/gavincarr/mag/logging"
+	"github.com/gavincarr/mag/watch"
 )
 
-type Word struct {
-	Gr  string
-	En  string
-	Cog string
-	Pos string
-}
-
-type UnitVocab struct {
-	Name  string
-	Unit  int
-	Vocab []Word
-}
+const defaultFilename = "vocab.yml"
 
 // Options
 type Options struct {
-	Verbose bool `short:"v" long:"verbose" description:"display verbose output"`
-	Unit    int  `short:"u" long:"unit" description:"lint only this unit number"`
-	Args    struct {
-		Filename string `description:"vocab yml dataset to read" default:"vocab.yml"`
+	Verbose bool   `short:"v" long:"verbose" description:"display verbose output"`
+	Version func() `long:"version" description:"print version and build information and exit"`
+	logging.Options
+	Unit        int    `short:"u" long:"unit" env:"MAG_UNIT" description:"lint only this unit number"`
+	Config      string `short:"C" long:"config" env:"MAG_LINT_CONFIG" description:"path to lint config file (default: search for .maglint.yml)"`
+	Output      string `long:"output" choice:"text" choice:"json" choice:"sarif" choice:"github" default:"text" env:"MAG_LINT_OUTPUT" description:"output format for findings"`
+	MaxWarnings int    `long:"max-warnings" default:"-1" env:"MAG_MAX_WARNINGS" description:"exit with an error if more than this many warnings are found (-1 for no limit)"`
+	MediaDir    string `long:"media-dir" env:"MAG_MEDIA_DIR" default:"media" description:"media directory whose export manifests to check for missing files (ignored if missing)"`
+	Fix         bool   `long:"fix" description:"fix mechanical issues (whitespace, NFC, final sigma, Latin homoglyphs) in place, leaving the rest of the dataset unchanged"`
+	Watch       bool   `short:"w" long:"watch" description:"watch the datasets and re-lint whenever they change"`
+	Args        struct {
+		Filenames []string `positional-arg-name:"filename" description:"vocab yml datasets or directories to read (- for stdin; default: $MAG_VOCAB or vocab.yml)"`
 	} `positional-args:"yes"`
 }
 
-func LintWord(wtr io.Writer, w Word, label string, i int) int {
-	errors := 0
-	if w.Gr == "" {
-		fmt.Fprintf(wtr, "Empty 'gr' field found%s, word %d\n",
-			label, i)
-		errors++
+// loadVocab reads the dataset filename, applying fixes first if
+// opts.Fix is set, and returns its units and inline suppressions
+func loadVocab(wtr io.Writer, filename string, opts Options, stats map[string]int) ([]lint.VocabUnit, []*lint.Suppression, error) {
+	if opts.Fix && filename == dataset.Stdin {
+		return nil, nil, errors.New("--fix cannot be used with stdin")
 	}
-	if w.En == "" {
-		fmt.Fprintf(wtr, "Empty 'en' field found%s, word %d\n",
-			label, i)
-		errors++
-	}
-	if w.Pos == "" {
-		fmt.Fprintf(wtr, "Empty 'pos' field found%s, word %d\n",
-			label, i)
-		errors++
-	} else if !rePos.MatchString(w.Pos) {
-		fmt.Fprintf(wtr, "Invalid 'pos' value found%s, word %d: %q\n",
-			label, i, w.Pos)
-		errors++
-	}
-	return errors
-}
-
-// LintVocab runs a series of checks on vocab, and outputs
-// any errors to stdout
-func LintVocab(wtr io.Writer, opts Options, vocab []UnitVocab, stats *map[string]int) int {
-	errors := 0
-	if len(vocab) == 0 {
-		fmt.Fprintln(wtr, "Empty vocab list!")
-		errors++
-		return errors
+	data, err := dataset.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	for _, u := range vocab {
-		if opts.Unit > 0 && u.Unit != opts.Unit {
-			continue
-		}
-
-		(*stats)["units"]++
-		var label string
-		if u.Name != "" {
-			label = fmt.Sprintf(" for unit %q", u.Name)
-		} else if u.Unit >= 3 {
-			label = fmt.Sprintf(" for unit %d", u.Unit)
-		}
-		if u.Name == "" {
-			fmt.Fprintf(wtr, "Empty unit 'name' field found%s\n", label)
-			errors++
-		}
-		if u.Unit == 0 {
-			fmt.Fprintf(wtr, "Empty unit 'unit' field found%s\n", label)
-			errors++
-		} else if u.Unit < 3 || u.Unit > 42 {
-			fmt.Fprintf(wtr, "Invalid unit 'unit' field found%s: %d\n",
-				label, u.Unit)
-			errors++
-		}
-		if len(u.Vocab) == 0 {
-			fmt.Fprintf(wtr, "Empty unit 'vocab' list found%s\n", label)
-			errors++
-			continue
-		}
-		if label == "" {
-			continue
-		}
-
-		for i, w := range u.Vocab {
-			(*stats)["words"]++
-			errors += LintWord(wtr, w, label, i)
+	if opts.Fix {
+		var fixes int
+		data, fixes, err = lint.FixFile(wtr, filename, data)
+		if err != nil {
+			return nil, nil, err
 		}
+		stats["fixes"] += fixes
 	}
 
-	return errors
+	return lint.ParseVocab(data, filename, opts.Unit)
 }
 
 func RunCLI(wtr io.Writer, opts Options) error {
-	dataset := opts.Args.Filename
-	data, err := os.ReadFile(dataset)
+	paths := dataset.DefaultPaths(opts.Args.Filenames, defaultFilename)
+	filenames, err := dataset.ExpandPaths(paths)
 	if err != nil {
 		return err
 	}
-
-	var vocab []UnitVocab
-	err = yaml.Unmarshal(data, &vocab)
+	config, err := lint.ResolveConfig(opts.Config, filepath.Dir(filenames[0]))
 	if err != nil {
 		return err
 	}
 
+	// Keep fix reports out of structured output
+	fixWtr := wtr
+	if opts.Output != "text" {
+		fixWtr = os.Stderr
+	}
 	stats := make(map[string]int)
-	errors := LintVocab(wtr, opts, vocab, &stats)
-	stats["errors"] = errors
+	if opts.Fix {
+		stats["fixes"] = 0
+	}
+	l := lint.New("lint_vocab", config)
+	var vocab []lint.VocabUnit
+	for _, filename := range filenames {
+		units, sups, err := loadVocab(fixWtr, filename, opts, stats)
+		if err != nil {
+			return err
+		}
+		vocab = append(vocab, units...)
+		l.AddSuppressions(sups)
+	}
 
-	jstats, err := json.MarshalIndent(stats, "", "  ")
+	lint.LintVocab(l, vocab, opts.Unit, &stats)
+	err = lint.LintManifests(l, opts.MediaDir)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	l.ReportUnusedSuppressions()
+	stats["errors"] = l.Errors()
+	stats["warnings"] = l.Warnings()
+	err = l.Write(wtr, opts.Output, stats)
+	if err != nil {
+		return err
 	}
-	fmt.Fprintln(wtr, string(jstats))
 
-	return nil
+	return l.Err(opts.MaxWarnings)
 }
 
 func main() {
-	log.SetFlags(0)
 	// Parse default options are HelpFlag | PrintErrors | PassDoubleDash
 	var opts Options
+	opts.Version = func() {
+		fmt.Println(buildinfo.String("lint_vocab"))
+		os.Exit(0)
+	}
 	parser := flags.NewParser(&opts, flags.Default)
 	_, err := parser.Parse()
 	if err != nil {
@@ -158,9 +126,15 @@ func main() {
 		parser.WriteHelp(os.Stderr)
 		os.Exit(2)
 	}
+	logging.Setup(opts.Options, opts.Verbose)
 
-	err = RunCLI(os.Stdout, opts)
+	if opts.Watch {
+		paths := dataset.DefaultPaths(opts.Args.Filenames, defaultFilename)
+		err = watch.Run(paths, func() error { return RunCLI(os.Stdout, opts) })
+	} else {
+		err = RunCLI(os.Stdout, opts)
+	}
 	if err != nil {
-		log.Fatal(err)
+		logging.Fatal(err)
 	}
 }
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gavincarr/mag/greek"
+	"github.com/gavincarr/mag/pdf"
+)
+
+// Booklet text sizes, in points
+const (
+	bookletTitleSize   = 24
+	bookletHeadingSize = 16
+	bookletGreekSize   = 11
+	bookletTextSize    = 10
+	bookletHeadSize    = 8
+)
+
+// PrintBookletCommand prints a vocabulary booklet
+type PrintBookletCommand struct {
+	PrintOptions
+	Title string `long:"title" default:"Vocabulary" description:"booklet title, on the first page and the running heads"`
+	Sort  string `short:"s" long:"sort" choice:"alpha" env:"MAG_SORT" description:"sort words within each unit (alpha: Greek dictionary order)"`
+	Args  struct {
+		Filenames []string `positional-arg-name:"filename" required:"1" description:"vocab and pp yml datasets or directories to print"`
+	} `positional-args:"yes"`
+}
+
+// booklet lays out text flowing down the pages of a document, starting
+// new pages with running heads and page numbers as needed
+type booklet struct {
+	doc     *pdf.Document
+	font    *pdf.Font
+	page    *pdf.Page
+	title   string
+	section string  // section name, for the running heads
+	margin  float64 // page margin
+	y       float64 // baseline of the last line drawn
+}
+
+// top returns the y position of the top of the text area
+func (b *booklet) top() float64 {
+	return b.doc.Height - b.margin
+}
+
+// newPage starts a new page, with the running heads and page number
+func (b *booklet) newPage() {
+	b.page = b.doc.AddPage()
+	n := b.doc.Pages()
+	headY := b.doc.Height - b.margin/2
+	right := b.doc.Width - b.margin
+	if n > 1 {
+		b.page.Text(b.margin, headY, bookletHeadSize, 0.4, b.title)
+		b.page.Text(right-b.font.Width(b.section, bookletHeadSize), headY, bookletHeadSize, 0.4, b.section)
+		b.page.Line(b.margin, headY-3, right, headY-3, pdf.LineStyle{Width: 0.4, Gray: 0.6})
+	}
+	b.page.TextCentered(b.doc.Width/2, b.margin/2, bookletHeadSize, 0.4, strconv.Itoa(n))
+	b.y = b.top()
+}
+
+// need starts a new page unless height fits below the last line
+func (b *booklet) need(height float64) {
+	if b.page == nil || b.y-height < b.margin {
+		b.newPage()
+	}
+}
+
+// pageNumber returns the current page number
+func (b *booklet) pageNumber() int {
+	return b.doc.Pages()
+}
+
+// startSection starts the named section on a new page, with a heading
+func (b *booklet) startSection(name string) {
+	b.section = name
+	b.newPage()
+	b.heading(name, bookletHeadingSize)
+}
+
+// heading draws a heading at size, with space around it
+func (b *booklet) heading(text string, size float64) {
+	b.need(size * 3)
+	if b.y < b.top() {
+		b.y -= size * 0.8
+	}
+	b.y -= size * 1.2
+	b.page.Text(b.margin, b.y, size, 0, text)
+	b.y -= size * 0.6
+}
+
+// row draws a row of two wrapped columns: left at leftSize, and right
+// at rightSize from split across the text area
+func (b *booklet) row(left, right string, leftSize, rightSize, split float64) {
+	width := b.doc.Width - 2*b.margin
+	gap := 4 * pdf.MM
+	leftLines := b.font.Wrap(left, leftSize, width*split-gap)
+	rightLines := b.font.Wrap(right, rightSize, width*(1-split))
+	lineHeight := max(leftSize, rightSize) * 1.3
+	height := float64(max(len(leftLines), len(rightLines), 1)) * lineHeight
+	b.need(height)
+	for i := 0; i < max(len(leftLines), len(rightLines)); i++ {
+		y := b.y - float64(i+1)*lineHeight
+		if i < len(leftLines) {
+			b.page.Text(b.margin, y, leftSize, 0, leftLines[i])
+		}
+		if i < len(rightLines) {
+			b.page.Text(b.margin+width*split, y, rightSize, 0.15, rightLines[i])
+		}
+	}
+	b.y -= height
+}
+
+// bookletIndexEntry is an entry in the booklet index: a headword, and
+// the pages it appears on
+type bookletIndexEntry struct {
+	word  string
+	pages []int
+}
+
+// bookletIndex collects the booklet index entries by headword
+type bookletIndex map[string]*bookletIndexEntry
+
+// add records that the headword of gr (before any comma) is on page
+func (idx bookletIndex) add(gr string, page int) {
+	word := strings.TrimSpace(strings.SplitN(gr, ",", 2)[0])
+	if word == "" {
+		return
+	}
+	e := idx[word]
+	if e == nil {
+		e = &bookletIndexEntry{word: word}
+		idx[word] = e
+	}
+	if len(e.pages) == 0 || e.pages[len(e.pages)-1] != page {
+		e.pages = append(e.pages, page)
+	}
+}
+
+// sorted returns the index entries in Greek dictionary order
+func (idx bookletIndex) sorted() []*bookletIndexEntry {
+	entries := make([]*bookletIndexEntry, 0, len(idx))
+	for _, e := range idx {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return greek.Less(entries[i].word, entries[j].word)
+	})
+	return entries
+}
+
+func (c *PrintBookletCommand) Execute(args []string) error {
+	s, err := printData(c.Args.Filenames, c.Units)
+	if err != nil {
+		return err
+	}
+	doc, err := newPrintDocument(c.PrintOptions, c.Title)
+	if err != nil {
+		return err
+	}
+	b := &booklet{doc: doc, font: doc.Font(), title: c.Title, margin: 20 * pdf.MM}
+	checker := glyphChecker{font: b.font}
+	index := make(bookletIndex)
+
+	// Title, then a section per unit of vocab
+	b.newPage()
+	b.y -= bookletTitleSize * 1.5
+	b.page.Text(b.margin, b.y, bookletTitleSize, 0, c.Title)
+	b.y -= bookletTitleSize
+	first := true
+	for _, u := range s.units {
+		var words []serveWord
+		for _, w := range s.vocab {
+			if w.UnitName == u.Name && w.Unit == u.Unit {
+				words = append(words, w)
+			}
+		}
+		if len(words) == 0 {
+			continue
+		}
+		if c.Sort == "alpha" {
+			sort.SliceStable(words, func(i, j int) bool {
+				return greek.Less(words[i].Gr, words[j].Gr)
+			})
+		}
+		if first {
+			b.section = unitLabel(u.Name, u.Unit)
+			b.heading(b.section, bookletHeadingSize)
+			first = false
+		} else {
+			b.startSection(unitLabel(u.Name, u.Unit))
+		}
+		for _, w := range words {
+			gr := strings.TrimSpace(w.Gr + " " + w.GrExt)
+			if w.Homonym != "" {
+				gr += " [" + w.Homonym + "]"
+			}
+			en := reGlossSep.ReplaceAllString(w.En, "; ")
+			if w.Cog != "" {
+				en += " (cf. " + w.Cog + ")"
+			}
+			checker.check(gr, en)
+			b.row(gr, en, bookletGreekSize, bookletTextSize, 0.45)
+			index.add(w.Gr, b.pageNumber())
+		}
+	}
+
+	// Principal parts appendix, by unit
+	if len(s.pp) > 0 {
+		b.startSection("Appendix: Principal Parts")
+		for _, u := range s.units {
+			heading := false
+			for _, v := range s.pp {
+				if v.UnitName != u.Name || v.Unit != u.Unit {
+					continue
+				}
+				if !heading {
+					b.heading(unitLabel(u.Name, u.Unit), bookletTextSize+2)
+					heading = true
+				}
+				parts := verbParts(v)
+				rest := strings.Join(parts[1:], ", ")
+				checker.check(parts[0], rest)
+				b.row(parts[0], rest, bookletGreekSize, bookletGreekSize, 0.3)
+				index.add(v.Pr, b.pageNumber())
+			}
+		}
+	}
+
+	// Index of words and verbs, with their pages
+	b.startSection("Index")
+	for _, e := range index.sorted() {
+		pages := make([]string, len(e.pages))
+		for i, p := range e.pages {
+			pages[i] = strconv.Itoa(p)
+		}
+		b.row(e.word, strings.Join(pages, ", "), bookletTextSize, bookletTextSize, 0.6)
+	}
+
+	checker.warn()
+	return writePDF(doc, c.Outfile)
+}
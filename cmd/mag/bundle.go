@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gavincarr/mag/greek"
+)
+
+var reSlugChars = regexp.MustCompile(`[^\pL\pN]+`)
+
+// BundleCommand writes the datasets as a static JSON bundle
+type BundleCommand struct {
+	Outdir string `short:"o" long:"outdir" required:"yes" description:"directory to write the bundle to (created if missing)"`
+	Pretty bool   `long:"pretty" description:"indent the JSON files, for reading"`
+	Args   struct {
+		Filenames []string `positional-arg-name:"filename" required:"1" description:"vocab and pp yml datasets or directories to bundle"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	_, err := parser.AddCommand("bundle",
+		"Write datasets as a static JSON bundle",
+		"Write vocab.yml/pp.yml datasets to a directory of pre-chunked JSON files for hosting on a static site, as an alternative to mag serve: units.json (the units, as served by /units, with the paths of their files), vocab/UNIT.json and pp/UNIT.json (each unit's vocab words and verbs, as served by /vocab and /pp), and search.json (a search index of every word and verb, with its accentless lowercase Greek key)",
+		&BundleCommand{})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// bundleEntry is a search index entry in a bundle's search.json
+type bundleEntry struct {
+	Key  string `json:"key"`          // Greek text, without diacritics (see greek.StripKey)
+	Gr   string `json:"gr"`           // headword or present
+	En   string `json:"en,omitempty"` // English gloss (vocab only)
+	Kind string `json:"kind"`         // vocab or pp
+	File string `json:"file"`         // bundle file with the full entry
+}
+
+// unitSlug returns the bundle file name stem for the unit name/number:
+// its zero-padded number if set (e.g. "07"), or its slugified name
+func unitSlug(name string, number int) string {
+	if number > 0 {
+		return fmt.Sprintf("%02d", number)
+	}
+	slug := strings.Trim(reSlugChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if slug == "" {
+		slug = "unit"
+	}
+	return slug
+}
+
+// writeBundleFile writes v as JSON to the file name (a slash-separated
+// path) in the output directory
+func (c *BundleCommand) writeBundleFile(name string, v any) error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if c.Pretty {
+		enc.SetIndent("", "  ")
+	}
+	err := enc.Encode(v)
+	if err != nil {
+		return err
+	}
+	p := filepath.Join(c.Outdir, filepath.FromSlash(name))
+	err = os.MkdirAll(filepath.Dir(p), 0o755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, b.Bytes(), 0o644)
+}
+
+func (c *BundleCommand) Execute(args []string) error {
+	s, err := loadServer(c.Args.Filenames)
+	if err != nil {
+		return err
+	}
+
+	// Chunk the words and verbs by unit, in unit order
+	vocab := make(map[*serveUnit][]serveWord)
+	pp := make(map[*serveUnit][]serveVerb)
+	for _, w := range s.vocab {
+		u := s.unit(w.UnitName, w.Unit)
+		vocab[u] = append(vocab[u], w)
+	}
+	for _, v := range s.pp {
+		u := s.unit(v.UnitName, v.Unit)
+		pp[u] = append(pp[u], v)
+	}
+
+	index := []bundleEntry{}
+	slugs := make(map[string]bool)
+	for _, u := range s.units {
+		slug := unitSlug(u.Name, u.Unit)
+		if slugs[slug] {
+			return fmt.Errorf("unit %q: bundle file name %q is already used by another unit", u.Name, slug)
+		}
+		slugs[slug] = true
+		if words := vocab[u]; len(words) > 0 {
+			u.VocabFile = path.Join("vocab", slug+".json")
+			err = c.writeBundleFile(u.VocabFile, words)
+			if err != nil {
+				return err
+			}
+			for _, w := range words {
+				index = append(index, bundleEntry{
+					Key: greek.StripKey(w.Gr), Gr: w.Gr, En: w.En,
+					Kind: "vocab", File: u.VocabFile,
+				})
+			}
+		}
+		if verbs := pp[u]; len(verbs) > 0 {
+			u.PPFile = path.Join("pp", slug+".json")
+			err = c.writeBundleFile(u.PPFile, verbs)
+			if err != nil {
+				return err
+			}
+			for _, v := range verbs {
+				index = append(index, bundleEntry{
+					Key: greek.StripKey(v.greek()), Gr: v.Pr,
+					Kind: "pp", File: u.PPFile,
+				})
+			}
+		}
+	}
+	err = c.writeBundleFile("units.json", s.units)
+	if err != nil {
+		return err
+	}
+	err = c.writeBundleFile("search.json", index)
+	if err != nil {
+		return err
+	}
+	slog.Info("wrote bundle", "dir", c.Outdir, "units", len(s.units),
+		"vocab", len(s.vocab), "pp", len(s.pp))
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/gavincarr/mag/greek"
+	"github.com/gavincarr/mag/lint"
+)
+
+var reHeadword = regexp.MustCompile(`,.*$`)
+
+// CompoundsCommand reports the compound verbs in vocab datasets whose
+// simplex verbs are also in the datasets
+type CompoundsCommand struct {
+	Args struct {
+		Filenames []string `positional-arg-name:"filename" description:"vocab yml datasets or directories to check (- for stdin)"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	_, err := parser.AddCommand("compounds",
+		"Report compound verbs",
+		"Report the compound verbs in vocab datasets (recognised by their preverb prefixes, e.g. ἀπο-, κατα-, συν-) whose simplex verbs are also in the datasets, with the simplex entry they link to",
+		&CompoundsCommand{})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// verbEntry is a vocab verb and its location
+type verbEntry struct {
+	headword string
+	unit     string
+	file     string
+	line     int
+}
+
+func (c *CompoundsCommand) Execute(args []string) error {
+	if len(c.Args.Filenames) == 0 {
+		return fmt.Errorf("no datasets specified")
+	}
+	files, err := pathFiles(c.Args.Filenames)
+	if err != nil {
+		return err
+	}
+
+	var verbs []verbEntry
+	simplexes := make(map[string]verbEntry)
+	for _, f := range files {
+		units, _, err := lint.ParseVocab(f.data, f.name, 0)
+		if err != nil {
+			return err
+		}
+		for _, u := range units {
+			for _, w := range u.Vocab {
+				if w.Pos != "v" {
+					continue
+				}
+				v := verbEntry{
+					headword: reHeadword.ReplaceAllString(w.Gr, ""),
+					unit:     lint.UnitLabel(u.Name, u.Unit),
+					file:     f.name, line: w.Line,
+				}
+				verbs = append(verbs, v)
+				key := greek.StripKey(v.headword)
+				if _, exists := simplexes[key]; !exists {
+					simplexes[key] = v
+				}
+			}
+		}
+	}
+
+	for _, v := range verbs {
+		prefix, key, ok := greek.SplitCompound(v.headword, func(key string) bool {
+			_, exists := simplexes[key]
+			return exists
+		})
+		if !ok {
+			continue
+		}
+		s := simplexes[key]
+		fmt.Printf("%s:%d: %s (unit %q) = %s + %s (unit %q, %s:%d)\n",
+			v.file, v.line, v.headword, v.unit, prefix, s.headword, s.unit,
+			s.file, s.line)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/gavincarr/mag/dataset"
+	"github.com/gavincarr/mag/greek"
+	"github.com/gavincarr/mag/lint"
+	"github.com/gavincarr/mag/morph"
+)
+
+// ConjugateCommand prints the core indicative conjugations of verbs
+type ConjugateCommand struct {
+	Datasets []string `short:"d" long:"dataset" description:"pp yml datasets or directories to look up verbs in (default: $MAG_PP or pp.yml)"`
+	Args     struct {
+		Verbs []string `positional-arg-name:"verb" required:"1" description:"verbs to conjugate: either a present to look up (e.g. λύω), or principal parts (e.g. \"λύω, λύσω, ἔλυσα\", or all six, ignoring the last three)"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	_, err := parser.AddCommand("conjugate",
+		"Print verb conjugations",
+		"Print the present, future, and aorist indicative conjugations of verbs, generated from their principal parts, given either directly or looked up by present in the pp datasets",
+		&ConjugateCommand{})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// recordVerb returns the principal parts of the pp record r to
+// conjugate (with its vclass: classes, if valid)
+func recordVerb(r lint.Record) morph.Verb {
+	v := morph.Verb{Pr: r.Pr, Fu: r.Fu, Ao: r.Ao}
+	if r.VClass != "" {
+		if classes, err := greek.ParseVerbClasses(r.VClass); err == nil {
+			v.Classes = classes
+		}
+	}
+	return v
+}
+
+// ppVerbs returns the verbs of the pp datasets at paths, keyed by the
+// keys (see greek.StripKey) of their presents
+func ppVerbs(paths []string) (map[string][]morph.Verb, error) {
+	files, err := pathFiles(dataset.DefaultPaths(paths, "pp.yml"))
+	if err != nil {
+		return nil, err
+	}
+	verbs := make(map[string][]morph.Verb)
+	for _, f := range files {
+		units, _, err := lint.ParsePP(f.data, f.name, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range units {
+			for _, r := range u.PP {
+				words := strings.Fields(r.Pr)
+				if len(words) == 0 {
+					continue
+				}
+				key := greek.StripKey(words[0])
+				verbs[key] = append(verbs[key], recordVerb(r))
+			}
+		}
+	}
+	return verbs, nil
+}
+
+func (c *ConjugateCommand) Execute(args []string) error {
+	var verbs map[string][]morph.Verb
+	failed, printed := 0, 0
+	for _, arg := range c.Args.Verbs {
+		var vs []morph.Verb
+		if strings.Contains(arg, ",") {
+			v, err := morph.ParseVerb(arg)
+			if err != nil {
+				slog.Error("could not parse verb", "verb", arg, "error", err)
+				failed++
+				continue
+			}
+			vs = append(vs, v)
+		} else {
+			if verbs == nil {
+				var err error
+				verbs, err = ppVerbs(c.Datasets)
+				if err != nil {
+					return err
+				}
+			}
+			vs = verbs[greek.StripKey(arg)]
+			if len(vs) == 0 {
+				slog.Warn("verb not found", "verb", arg)
+				failed++
+				continue
+			}
+		}
+
+		for _, v := range vs {
+			paradigms, err := morph.Conjugate(v)
+			for _, p := range paradigms {
+				if printed > 0 {
+					fmt.Println()
+				}
+				writeParadigm(os.Stdout, v.Pr, p)
+				printed++
+			}
+			if err != nil {
+				slog.Error("could not conjugate verb", "verb", v.Pr, "error", err)
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d verb(s) could not be fully conjugated", failed)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gavincarr/mag/dataset"
+	"github.com/gavincarr/mag/greek"
+	"github.com/gavincarr/mag/lint"
+	"github.com/gavincarr/mag/morph"
+)
+
+// DeclineCommand prints the declension tables of nouns
+type DeclineCommand struct {
+	Datasets []string `short:"d" long:"dataset" description:"vocab yml datasets or directories to look up nouns in (default: $MAG_VOCAB or vocab.yml)"`
+	Args     struct {
+		Words []string `positional-arg-name:"word" required:"1" description:"nouns to decline: either a headword to look up (e.g. λόγος), or a full entry (e.g. \"λόγος, -ου, ὁ\")"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	_, err := parser.AddCommand("decline",
+		"Print noun declensions",
+		"Print the declension tables of nouns, generated from their nominative, genitive, and article, given either as a full entry or looked up by headword in the vocab datasets",
+		&DeclineCommand{})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// vocabNouns returns the noun entries of the vocab datasets at paths,
+// keyed by their headword keys (see greek.StripKey)
+func vocabNouns(paths []string) (map[string][]string, error) {
+	files, err := pathFiles(dataset.DefaultPaths(paths, "vocab.yml"))
+	if err != nil {
+		return nil, err
+	}
+	nouns := make(map[string][]string)
+	for _, f := range files {
+		units, _, err := lint.ParseVocab(f.data, f.name, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range units {
+			for _, w := range u.Vocab {
+				if w.Pos != "" && w.Pos != "n" {
+					continue
+				}
+				entry := w.Entry()
+				n, err := morph.ParseNoun(entry)
+				if err != nil {
+					continue
+				}
+				key := greek.StripKey(n.Nom)
+				nouns[key] = append(nouns[key], entry)
+			}
+		}
+	}
+	return nouns, nil
+}
+
+// splitCell splits the paradigm cell into its row and column keys, e.g.
+// "nom sg" into "nom" and "sg", or "3pl" into "3" and "pl"
+func splitCell(cell string) (string, string) {
+	if row, col, ok := strings.Cut(cell, " "); ok {
+		return row, col
+	}
+	i := strings.IndexFunc(cell, func(r rune) bool { return r < '0' || r > '9' })
+	if i <= 0 {
+		return cell, ""
+	}
+	return cell[:i], cell[i:]
+}
+
+// writeParadigm writes p as a text table to wtr, with rows for each
+// distinct cell prefix (e.g. "nom" or "3") and columns for each suffix
+// ("sg"). Forms whose accent is uncertain are marked with a "?", and
+// explained in a note after the table.
+func writeParadigm(wtr io.Writer, title string, p morph.Paradigm) {
+	var rows, cols []string
+	seen := make(map[string]bool)
+	uncertain := false
+	for _, f := range p.Forms {
+		uncertain = uncertain || f.Uncertain
+		row, col := splitCell(f.Cell)
+		if !seen[row] {
+			rows = append(rows, row)
+			seen[row] = true
+		}
+		if !seen[" "+col] {
+			cols = append(cols, col)
+			seen[" "+col] = true
+		}
+	}
+
+	fmt.Fprintf(wtr, "%s: %s\n", title, p.Name)
+	tw := tabwriter.NewWriter(wtr, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "\t%s\n", strings.Join(cols, "\t"))
+	for _, row := range rows {
+		forms := make([]string, len(cols))
+		for i, col := range cols {
+			for _, f := range p.Forms {
+				if r, c := splitCell(f.Cell); r == row && c == col {
+					forms[i] = f.Form
+					if f.Uncertain {
+						forms[i] += "?"
+					}
+				}
+			}
+		}
+		fmt.Fprintf(tw, "  %s\t%s\n", row, strings.Join(forms, "\t"))
+	}
+	tw.Flush()
+	if uncertain {
+		fmt.Fprintln(wtr, "  ? may be mis-accented: the length of the accented α, ι, or υ is not shown by the nominative")
+	}
+}
+
+func (c *DeclineCommand) Execute(args []string) error {
+	var nouns map[string][]string
+	failed := 0
+	for i, word := range c.Args.Words {
+		entries := []string{word}
+		if !strings.Contains(word, ",") {
+			if nouns == nil {
+				var err error
+				nouns, err = vocabNouns(c.Datasets)
+				if err != nil {
+					return err
+				}
+			}
+			entries = nouns[greek.StripKey(word)]
+			if len(entries) == 0 {
+				slog.Warn("noun not found", "word", word)
+				failed++
+				continue
+			}
+		}
+
+		for j, entry := range entries {
+			n, err := morph.ParseNoun(entry)
+			if err == nil {
+				var p morph.Paradigm
+				p, err = morph.Decline(n)
+				if err == nil {
+					if i > 0 || j > 0 {
+						fmt.Println()
+					}
+					writeParadigm(os.Stdout, entry, p)
+					continue
+				}
+			}
+			slog.Error("could not decline noun", "word", entry, "error", err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d noun(s) could not be declined", failed)
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/gavincarr/mag/dataset"
+	"github.com/gavincarr/mag/enrich"
+	"github.com/gavincarr/mag/httpclient"
+	"github.com/gavincarr/mag/lint"
+)
+
+// EnrichCommand looks up dataset headwords in external services,
+// storing the results in sidecar files alongside the datasets
+type EnrichCommand struct {
+	httpclient.Config
+	Morpheus      bool          `long:"morpheus" description:"look up each headword's lemmas and inflections with the Morpheus morphological analyser, in <dataset>.morpheus.yml sidecars"`
+	MorpheusURL   string        `long:"morpheus-url" env:"MAG_MORPHEUS_URL" default:"https://morph.perseids.org/analysis/word" description:"Morpheus analysis service endpoint"`
+	LSJ           bool          `long:"lsj" description:"look up a short LSJ definition of each headword (from the Perseus Digital Library), in <dataset>.lsj.yml sidecars"`
+	LSJURL        string        `long:"lsj-url" env:"MAG_LSJ_URL" default:"https://www.perseus.tufts.edu/hopper/xmlchunk" description:"Perseus LSJ entry endpoint"`
+	Wiktionary    bool          `long:"wiktionary" description:"look up the Ancient Greek etymology of each headword on Wiktionary, in <dataset>.wiktionary.yml sidecars"`
+	WiktionaryURL string        `long:"wiktionary-url" env:"MAG_WIKTIONARY_URL" default:"https://en.wiktionary.org/w/api.php" description:"Wiktionary MediaWiki API endpoint"`
+	Cog           bool          `long:"cog" description:"add the English terms from the Wiktionary etymologies to the 'cog' fields of vocab words that don't already list them, rewriting the datasets"`
+	Review        bool          `long:"review" description:"print the 'cog' additions --cog would make, without rewriting the datasets (implies --cog)"`
+	Refresh       bool          `long:"refresh" description:"look up all headwords again, not just those missing from the sidecars, bypassing the cache"`
+	CacheDir      string        `long:"cache-dir" env:"MAG_CACHE_DIR" description:"directory caching service responses across runs and datasets (default: mag/enrich in the user cache directory, e.g. ~/.cache/mag/enrich)"`
+	CacheTTL      time.Duration `long:"cache-ttl" env:"MAG_CACHE_TTL" default:"720h" description:"maximum age of cached responses (0 for no limit)"`
+	NoCache       bool          `long:"no-cache" description:"don't use or update the response cache"`
+	Offline       bool          `long:"offline" env:"MAG_OFFLINE" description:"don't access the network, using only cached responses (even expired ones), and report the lookups that would need a fetch"`
+	Args          struct {
+		Filenames []string `positional-arg-name:"filename" required:"1" description:"vocab or pp yml datasets or directories to enrich"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	_, err := parser.AddCommand("enrich",
+		"Enrich datasets from external services",
+		"Look up the headwords of vocab.yml/pp.yml datasets in external services (--morpheus: the Morpheus morphological analyser; --lsj: LSJ short definitions from Perseus; --wiktionary: Wiktionary etymologies), storing the results in sidecar files alongside the datasets, for lint cross-checks and exports",
+		&EnrichCommand{})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// datasetHeadwords returns the lookup headwords (see enrich.Headword) of
+// the entries of the vocab or pp dataset f, in order and without
+// duplicates, and its schema ("vocab" or "pp", or "" if it is neither)
+func datasetHeadwords(f lintFile) ([]string, string, error) {
+	var doc yaml.Node
+	err := yaml.Unmarshal(f.data, &doc)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", f.name, err)
+	}
+	var entries []string
+	schema := dataset.DetectSchema(&doc)
+	switch schema {
+	case "vocab":
+		units, _, err := lint.ParseVocab(f.data, f.name, 0)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, u := range units {
+			for _, w := range u.Vocab {
+				entries = append(entries, w.Gr)
+			}
+		}
+	case "pp":
+		units, _, err := lint.ParsePP(f.data, f.name, 0)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, u := range units {
+			for _, r := range u.PP {
+				entries = append(entries, r.Pr)
+			}
+		}
+	default:
+		return nil, "", nil
+	}
+
+	var headwords []string
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		hw := enrich.Headword(entry)
+		if hw == "" || seen[hw] {
+			continue
+		}
+		seen[hw] = true
+		headwords = append(headwords, hw)
+	}
+	return headwords, schema, nil
+}
+
+// lookupCounts counts the unsuccessful lookups of an enrich run
+type lookupCounts struct {
+	failed   int // lookups that failed
+	uncached int // lookups needing a fetch, in offline mode
+}
+
+// err returns an error reporting any unsuccessful lookups counted
+func (n lookupCounts) err() error {
+	var errs []error
+	if n.failed > 0 {
+		errs = append(errs, fmt.Errorf("%d lookup(s) failed (re-run to retry them)", n.failed))
+	}
+	if n.uncached > 0 {
+		errs = append(errs, fmt.Errorf("%d lookup(s) not cached (re-run without --offline to fetch them)", n.uncached))
+	}
+	return errors.Join(errs...)
+}
+
+// enrichSidecar looks up the headwords of the dataset filename missing
+// from its sidecar for source (or all of them, if refresh is set) with
+// lookup, and updates the sidecar, adding any unsuccessful lookups to
+// counts
+func enrichSidecar[T any](filename, source string, headwords []string, refresh bool, lookup func(headword string) (T, error), counts *lookupCounts) error {
+	path := enrich.SidecarPath(filename, source)
+	sidecar, err := enrich.LoadSidecar[T](path, source)
+	if err != nil {
+		return err
+	}
+	failed, uncached, updated := 0, 0, 0
+	for _, hw := range headwords {
+		if _, exists := sidecar.Words[hw]; exists && !refresh {
+			continue
+		}
+		result, err := lookup(hw)
+		if errors.Is(err, enrich.ErrOffline) {
+			slog.Warn("lookup needs a fetch (offline)", "source", source, "word", hw)
+			uncached++
+			continue
+		}
+		if err != nil {
+			slog.Warn("lookup failed", "source", source, "word", hw, "error", err)
+			failed++
+			continue
+		}
+		// Unrecognised words are recorded too, so they aren't looked up again
+		sidecar.Words[hw] = result
+		updated++
+	}
+	if updated > 0 {
+		err = sidecar.Write(path, filename)
+		if err != nil {
+			return err
+		}
+	}
+	slog.Info("enriched dataset", "file", filename, "source", source,
+		"looked_up", updated, "failed", failed, "uncached", uncached, "sidecar", path)
+	counts.failed += failed
+	counts.uncached += uncached
+	return nil
+}
+
+// addCognates adds the English terms from the Wiktionary etymologies in
+// the sidecar of the vocab dataset f to its words' 'cog' fields, or with
+// review just prints the additions
+func addCognates(f lintFile, review bool) error {
+	path := enrich.SidecarPath(f.name, enrich.SourceWiktionary)
+	sidecar, err := enrich.LoadSidecar[enrich.Etymology](path, enrich.SourceWiktionary)
+	if err != nil {
+		return err
+	}
+	out, additions, err := dataset.AddCognates(f.data, func(gr string) []string {
+		return sidecar.Words[enrich.Headword(gr)].Cognates
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.name, err)
+	}
+	if review {
+		for _, a := range additions {
+			fmt.Printf("%s:%d: %s: cog + %s\n", f.name, a.Line, a.Gr, strings.Join(a.Added, ", "))
+		}
+		return nil
+	}
+	if len(additions) == 0 {
+		return nil
+	}
+	info, err := os.Stat(f.name)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(f.name, out, info.Mode())
+	if err != nil {
+		return err
+	}
+	slog.Info("added cognates", "file", f.name, "words", len(additions))
+	return nil
+}
+
+func (c *EnrichCommand) Execute(args []string) error {
+	if c.Review {
+		c.Cog = true
+	}
+	if !c.Morpheus && !c.LSJ && !c.Wiktionary && !c.Cog {
+		return fmt.Errorf("no enrichment sources selected (use --morpheus, --lsj, and/or --wiktionary)")
+	}
+	files, err := pathFiles(c.Args.Filenames)
+	if err != nil {
+		return err
+	}
+
+	m := enrich.NewMorpheus()
+	m.URL = c.MorpheusURL
+	m.Client = httpclient.New(c.Config)
+	analyze := func(hw string) ([]enrich.Analysis, error) {
+		analyses, err := m.Analyze(hw)
+		if err == nil && analyses == nil {
+			analyses = []enrich.Analysis{}
+		}
+		return analyses, err
+	}
+	lsj := enrich.NewLSJ()
+	lsj.URL = c.LSJURL
+	lsj.Client = httpclient.New(c.Config)
+	wiktionary := enrich.NewWiktionary()
+	wiktionary.URL = c.WiktionaryURL
+	wiktionary.Client = httpclient.New(c.Config)
+	if c.Offline && c.NoCache {
+		return fmt.Errorf("--offline requires the response cache (drop --no-cache)")
+	}
+	if !c.NoCache {
+		dir := c.CacheDir
+		if dir == "" {
+			dir, err = enrich.DefaultCacheDir()
+			if err != nil {
+				return err
+			}
+		}
+		cache := enrich.NewCache(dir, c.CacheTTL)
+		cache.Refresh = c.Refresh
+		cache.Offline = c.Offline
+		for _, client := range []*http.Client{m.Client, lsj.Client, wiktionary.Client} {
+			cache.Use(client)
+		}
+		slog.Debug("using response cache", "dir", dir, "ttl", c.CacheTTL)
+	}
+	var counts lookupCounts
+	for _, f := range files {
+		if f.name == dataset.Stdin {
+			return fmt.Errorf("cannot enrich stdin (sidecars are written alongside dataset files)")
+		}
+		headwords, schema, err := datasetHeadwords(f)
+		if err != nil {
+			return err
+		}
+		if schema == "" {
+			slog.Debug("skipping non-vocab/pp file", "file", f.name)
+			continue
+		}
+		if c.Morpheus {
+			err = enrichSidecar(f.name, enrich.SourceMorpheus, headwords, c.Refresh, analyze, &counts)
+			if err != nil {
+				return err
+			}
+		}
+		if c.LSJ {
+			err = enrichSidecar(f.name, enrich.SourceLSJ, headwords, c.Refresh, lsj.Define, &counts)
+			if err != nil {
+				return err
+			}
+		}
+		if c.Wiktionary {
+			err = enrichSidecar(f.name, enrich.SourceWiktionary, headwords, c.Refresh, wiktionary.Etymology, &counts)
+			if err != nil {
+				return err
+			}
+		}
+		if c.Cog && schema == "vocab" {
+			err = addCognates(f, c.Review)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return counts.err()
+}
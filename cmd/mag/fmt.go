@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/gavincarr/mag/dataset"
+)
+
+// FmtCommand rewrites datasets in canonical form
+type FmtCommand struct {
+	Check  bool `short:"c" long:"check" description:"list files that are not canonically formatted, without rewriting them"`
+	Stdout bool `long:"stdout" description:"write formatted output to stdout instead of rewriting files"`
+	Args   struct {
+		Filenames []string `positional-arg-name:"filename" description:"yml datasets to format (- for stdin, written to stdout)" required:"1"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	_, err := parser.AddCommand("fmt",
+		"Rewrite datasets in canonical form",
+		"Rewrite mag yml datasets (vocab, pp, paradigms, sentences, exercises) with canonical key ordering, quoting, indentation, and NFC normalisation",
+		&FmtCommand{})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (c *FmtCommand) Execute(args []string) error {
+	unformatted := 0
+	for _, filename := range c.Args.Filenames {
+		data, err := dataset.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		out, err := dataset.Format(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+
+		switch {
+		case c.Stdout, filename == dataset.Stdin && !c.Check:
+			os.Stdout.Write(out)
+		case bytes.Equal(data, out):
+			slog.Debug("already formatted", "file", filename)
+		case c.Check:
+			fmt.Println(filename)
+			unformatted++
+		default:
+			info, err := os.Stat(filename)
+			if err != nil {
+				return err
+			}
+			err = os.WriteFile(filename, out, info.Mode())
+			if err != nil {
+				return err
+			}
+			slog.Info("reformatted", "file", filename)
+		}
+	}
+
+	if unformatted > 0 {
+		return fmt.Errorf("%d file(s) not canonically formatted", unformatted)
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gavincarr/mag/pdf"
+)
+
+// Practice test question types, by --types name
+const (
+	questionVocab   = "vocab"
+	questionPP      = "pp"
+	questionReverse = "reverse"
+)
+
+var questionTypes = []string{questionVocab, questionPP, questionReverse}
+
+// GenCommand groups the commands generating practice material
+type GenCommand struct{}
+
+// GenTestCommand generates randomized practice tests
+type GenTestCommand struct {
+	PrintOptions
+	Count   int    `short:"n" long:"count" default:"20" description:"number of questions"`
+	Types   string `short:"t" long:"types" default:"vocab,pp,reverse" description:"question types to mix, as a comma-separated list of vocab (translate Greek words), pp (give the principal parts of verbs), and reverse (give the Greek for English glosses)"`
+	Seed    int64  `long:"seed" description:"random seed, to reproduce a test (default: the current time, which is logged)"`
+	Format  string `short:"f" long:"format" choice:"pdf" choice:"markdown" description:"output format (default: markdown if --outfile ends in .md, else pdf)"`
+	Answers string `long:"answers" description:"answer key file to write (default: --outfile with -answers before its extension)"`
+	Title   string `long:"title" description:"test title (default: from --units)"`
+	Args    struct {
+		Filenames []string `positional-arg-name:"filename" required:"1" description:"vocab and pp yml datasets or directories"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	cmd, err := parser.AddCommand("gen",
+		"Generate practice material",
+		"Generate practice material from vocab.yml/pp.yml datasets, for use without Anki",
+		&GenCommand{})
+	if err != nil {
+		panic(err)
+	}
+	_, err = cmd.AddCommand("test",
+		"Generate randomized practice tests",
+		"Generate a practice test of randomly chosen questions mixing vocab translation, principal parts recall, and reverse translation, as a PDF or Markdown file, with a separate answer key file. The same --seed and datasets give the same test.",
+		&GenTestCommand{})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// testQuestion is a practice test question, with its answer
+type testQuestion struct {
+	prompt string
+	answer string
+}
+
+// testQuestions returns the candidate questions of type kind for the
+// words and verbs in s
+func testQuestions(s *server, kind string) []testQuestion {
+	var questions []testQuestion
+	switch kind {
+	case questionVocab, questionReverse:
+		for _, w := range s.vocab {
+			gr := strings.TrimSpace(w.Gr + " " + w.GrExt)
+			en := reGlossSep.ReplaceAllString(w.En, "; ")
+			if kind == questionVocab {
+				if w.Homonym != "" {
+					gr += " [" + w.Homonym + "]"
+				}
+				questions = append(questions, testQuestion{"Translate into English: " + gr, en})
+			} else {
+				questions = append(questions, testQuestion{"Give the Greek for: " + en, gr})
+			}
+		}
+	case questionPP:
+		for _, v := range s.pp {
+			parts := verbParts(v)
+			questions = append(questions, testQuestion{
+				"Give the principal parts of: " + parts[0],
+				strings.Join(parts[1:], ", "),
+			})
+		}
+	}
+	return questions
+}
+
+// buildTest returns count questions of the types kinds for the words and
+// verbs in s, chosen and ordered by r. The types are mixed evenly, as far
+// as there are questions of each.
+func buildTest(s *server, kinds []string, count int, r *rand.Rand) []testQuestion {
+	var pools [][]testQuestion
+	for _, kind := range kinds {
+		questions := testQuestions(s, kind)
+		if len(questions) == 0 {
+			slog.Warn("no questions of type, skipping", "type", kind)
+			continue
+		}
+		r.Shuffle(len(questions), func(i, j int) {
+			questions[i], questions[j] = questions[j], questions[i]
+		})
+		pools = append(pools, questions)
+	}
+
+	// Take questions from each pool in turn, until enough or all are taken
+	var test []testQuestion
+	for len(test) < count && len(pools) > 0 {
+		for i := 0; i < len(pools) && len(test) < count; i++ {
+			test = append(test, pools[i][0])
+			pools[i] = pools[i][1:]
+		}
+		pools = slices.DeleteFunc(pools, func(pool []testQuestion) bool { return len(pool) == 0 })
+	}
+	r.Shuffle(len(test), func(i, j int) {
+		test[i], test[j] = test[j], test[i]
+	})
+	return test
+}
+
+// testMarkdown returns the test as a Markdown document, or its answer
+// key if key is set
+func testMarkdown(title string, test []testQuestion, key bool) []byte {
+	var b bytes.Buffer
+	if key {
+		title += ": Answers"
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	if !key {
+		b.WriteString("Name: ____________________\n\n")
+	}
+	for i, q := range test {
+		if key {
+			fmt.Fprintf(&b, "%d. %s  \n   **%s**\n\n", i+1, q.prompt, q.answer)
+		} else {
+			fmt.Fprintf(&b, "%d. %s  \n   ______________________________\n\n", i+1, q.prompt)
+		}
+	}
+	return b.Bytes()
+}
+
+// testPDF draws the test in doc, or its answer key if key is set
+func testPDF(doc *pdf.Document, title string, test []testQuestion, key bool) {
+	if key {
+		title += ": Answers"
+	}
+	b := &booklet{doc: doc, font: doc.Font(), title: title, margin: 20 * pdf.MM}
+	b.newPage()
+	b.y -= bookletTitleSize * 1.5
+	b.page.Text(b.margin, b.y, bookletTitleSize, 0, title)
+	if !key {
+		b.y -= bookletTitleSize
+		b.page.Text(b.margin, b.y, bookletTextSize, 0, "Name: ____________________")
+	}
+	b.y -= bookletTextSize * 2
+
+	width := doc.Width - 2*b.margin
+	lineHeight := bookletGreekSize * 1.3
+	for i, q := range test {
+		prompt := b.font.Wrap(fmt.Sprintf("%d. %s", i+1, q.prompt), bookletGreekSize, width)
+		answer := b.font.Wrap(q.answer, bookletGreekSize, width-8*pdf.MM)
+		if !key {
+			answer = []string{""}
+		}
+		b.need(float64(len(prompt)+len(answer)+1) * lineHeight)
+		for _, line := range prompt {
+			b.y -= lineHeight
+			b.page.Text(b.margin, b.y, bookletGreekSize, 0, line)
+		}
+		for _, line := range answer {
+			b.y -= lineHeight * 1.2
+			if key {
+				b.page.Text(b.margin+8*pdf.MM, b.y, bookletGreekSize, 0.35, line)
+			}
+		}
+		if !key {
+			b.page.Line(b.margin+8*pdf.MM, b.y-2, b.margin+width, b.y-2, pdf.LineStyle{Width: 0.6, Gray: 0.3})
+		}
+		b.y -= lineHeight
+	}
+}
+
+func (c *GenTestCommand) Execute(args []string) error {
+	if c.Count < 1 {
+		return fmt.Errorf("invalid --count %d", c.Count)
+	}
+	var kinds []string
+	for _, kind := range strings.Split(c.Types, ",") {
+		kind = strings.TrimSpace(kind)
+		if !slices.Contains(questionTypes, kind) {
+			return fmt.Errorf("invalid --types %q (want a list of %s)", kind, strings.Join(questionTypes, ", "))
+		}
+		if !slices.Contains(kinds, kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+	format := c.Format
+	if format == "" {
+		format = worksheetPDF
+		if strings.EqualFold(filepath.Ext(c.Outfile), ".md") {
+			format = worksheetMD
+		}
+	}
+	answers := c.Answers
+	if answers == "" {
+		answers = answersFilename(c.Outfile)
+	}
+	if answers == c.Outfile {
+		return fmt.Errorf("--answers must differ from --outfile")
+	}
+
+	s, err := printData(c.Args.Filenames, c.Units)
+	if err != nil {
+		return err
+	}
+	seed := c.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+		slog.Info("generating test", "seed", seed)
+	}
+	test := buildTest(s, kinds, c.Count, rand.New(rand.NewSource(seed)))
+	if len(test) == 0 {
+		return fmt.Errorf("no questions of --types %s in the datasets", c.Types)
+	}
+	if len(test) < c.Count {
+		slog.Warn("not enough words and verbs for --count questions", "count", c.Count, "questions", len(test))
+	}
+	title := c.Title
+	if title == "" {
+		title = unitsTitle("Practice Test", c.Units)
+	}
+
+	if format == worksheetMD {
+		err = os.WriteFile(c.Outfile, testMarkdown(title, test, false), 0o644)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(answers, testMarkdown(title, test, true), 0o644)
+	}
+	for _, key := range []bool{false, true} {
+		doc, err := newPrintDocument(c.PrintOptions, title)
+		if err != nil {
+			return err
+		}
+		if !key {
+			checker := glyphChecker{font: doc.Font()}
+			for _, q := range test {
+				checker.check(q.prompt, q.answer)
+			}
+			checker.warn()
+		}
+		testPDF(doc, title, test, key)
+		filename := c.Outfile
+		if key {
+			filename = answers
+		}
+		err = writePDF(doc, filename)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
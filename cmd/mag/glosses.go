@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/gavincarr/mag/dataset"
+	"github.com/gavincarr/mag/lint"
+)
+
+var (
+	reGlossMarker = regexp.MustCompile(`\([^)]*\)`)
+	reGlossPunct  = regexp.MustCompile(`[^\pL\pN;]+`)
+	reGlossSemi   = regexp.MustCompile(` *; *`)
+	reGlossLead   = regexp.MustCompile(`(^|;)(to|a|an|the) `)
+)
+
+// minGlossLength is the minimum normalised gloss length compared for
+// near-duplicates, as short glosses are too close to compare usefully
+const minGlossLength = 6
+
+// GlossesCommand reports distinct vocab words with identical or nearly
+// identical glosses
+type GlossesCommand struct {
+	MaxDistance int `short:"d" long:"max-distance" default:"2" description:"maximum edit distance between normalised glosses to report as near-duplicates (0 for identical glosses only)"`
+	Args        struct {
+		Filenames []string `positional-arg-name:"filename" description:"vocab yml datasets or directories to check (- for stdin)"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	_, err := parser.AddCommand("glosses",
+		"Report duplicate glosses",
+		"Report distinct vocab words whose English glosses are identical or nearly identical (ignoring markers, punctuation, and leading to/a/the), as they make ambiguous English-to-Greek cards",
+		&GlossesCommand{})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// glossWord is a vocab word with its normalised gloss
+type glossWord struct {
+	lint.Word
+	unit  string
+	file  string
+	gloss string
+}
+
+// normaliseGloss returns gloss lowercased, without parenthesised markers,
+// punctuation, or leading to/a/the in each segment
+func normaliseGloss(gloss string) string {
+	gloss = strings.ToLower(reGlossMarker.ReplaceAllString(gloss, " "))
+	gloss = strings.TrimSpace(reGlossPunct.ReplaceAllString(gloss, " "))
+	gloss = reGlossSemi.ReplaceAllString(gloss, ";")
+	return reGlossLead.ReplaceAllString(gloss, "$1")
+}
+
+func (c *GlossesCommand) Execute(args []string) error {
+	if len(c.Args.Filenames) == 0 {
+		return fmt.Errorf("no datasets specified")
+	}
+	files, err := pathFiles(c.Args.Filenames)
+	if err != nil {
+		return err
+	}
+
+	var words []glossWord
+	for _, f := range files {
+		units, _, err := lint.ParseVocab(f.data, f.name, 0)
+		if err != nil {
+			return err
+		}
+		for _, u := range units {
+			for _, w := range u.Vocab {
+				gloss := normaliseGloss(w.En)
+				if gloss == "" {
+					continue
+				}
+				words = append(words, glossWord{
+					Word: w, unit: lint.UnitLabel(u.Name, u.Unit),
+					file: f.name, gloss: gloss,
+				})
+			}
+		}
+	}
+
+	pairs := 0
+	for i, a := range words {
+		for _, b := range words[i+1:] {
+			if a.Gr == b.Gr {
+				continue
+			}
+			d := 0
+			if a.gloss != b.gloss {
+				if c.MaxDistance == 0 || min(len(a.gloss), len(b.gloss)) < minGlossLength {
+					continue
+				}
+				d = dataset.Levenshtein(a.gloss, b.gloss)
+				if d > c.MaxDistance {
+					continue
+				}
+			}
+			kind := "identical"
+			if d > 0 {
+				kind = fmt.Sprintf("near-identical (distance %d)", d)
+			}
+			fmt.Printf("%s:%d: %s glosses: %q (unit %q: %s) vs. %q (unit %q, %s:%d: %s)\n",
+				b.file, b.Line, kind, b.Gr, b.unit, b.En,
+				a.Gr, a.unit, a.file, a.Line, a.En)
+			pairs++
+		}
+	}
+	if pairs > 0 {
+		slog.Warn(fmt.Sprintf("%d pair(s) of words with duplicate glosses found", pairs))
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/gavincarr/mag/dataset"
+	"github.com/gavincarr/mag/enrich"
+	"github.com/gavincarr/mag/lint"
+)
+
+// LintCommand lints vocab and pp datasets, detecting the dataset type
+// of each from its content
+type LintCommand struct {
+	Staged      bool   `long:"staged" description:"lint only the yml datasets staged in git, as staged in the index (the lint config and sidecar files are still read from the working tree)"`
+	Config      string `short:"C" long:"config" env:"MAG_LINT_CONFIG" description:"path to lint config file (default: search for .maglint.yml)"`
+	Output      string `long:"output" choice:"text" choice:"json" choice:"sarif" choice:"github" default:"text" env:"MAG_LINT_OUTPUT" description:"output format for findings"`
+	MediaDir    string `long:"media-dir" env:"MAG_MEDIA_DIR" default:"media" description:"media directory whose export manifests to check for missing files (ignored if missing)"`
+	MaxWarnings int    `long:"max-warnings" default:"-1" env:"MAG_MAX_WARNINGS" description:"exit with an error if more than this many warnings are found (-1 for no limit)"`
+	Args        struct {
+		Filenames []string `positional-arg-name:"filename" description:"yml datasets or directories to lint (- for stdin)"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	_, err := parser.AddCommand("lint",
+		"Lint datasets",
+		"Lint vocab.yml/pp.yml/sentences.yml/exercises.yml datasets (checking sentence words against the vocab linted with them), reporting findings tersely. With --staged, lint the yml datasets staged in git (suitable for a pre-commit hook), as staged in the index; the .maglint.yml config and the enrichment sidecar files are still read from the working tree, so stage or stash changes to them too.",
+		&LintCommand{})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// lintFile is a dataset file to be linted
+type lintFile struct {
+	name string
+	data []byte
+}
+
+// gitOutput runs git with args and returns its stdout
+func gitOutput(args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err,
+			bytes.TrimSpace(stderr.Bytes()))
+	}
+	return out, nil
+}
+
+// stagedFiles returns the yml files staged in git, with their content
+// read from the index rather than the working tree
+func stagedFiles() ([]lintFile, error) {
+	out, err := gitOutput("diff", "--cached", "--name-only", "-z",
+		"--diff-filter=ACMR", "--", "*.yml", "*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	var files []lintFile
+	for _, name := range bytes.Split(out, []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		data, err := gitOutput("show", ":"+string(name))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, lintFile{name: string(name), data: data})
+	}
+	return files, nil
+}
+
+// pathFiles returns the yml datasets in paths, with their content
+func pathFiles(paths []string) ([]lintFile, error) {
+	filenames, err := dataset.ExpandPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]lintFile, len(filenames))
+	for i, filename := range filenames {
+		data, err := dataset.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		files[i] = lintFile{name: filename, data: data}
+	}
+	return files, nil
+}
+
+func (c *LintCommand) Execute(args []string) error {
+	var files []lintFile
+	var err error
+	if c.Staged {
+		if len(c.Args.Filenames) > 0 {
+			return fmt.Errorf("--staged cannot be used with filenames")
+		}
+		files, err = stagedFiles()
+	} else {
+		if len(c.Args.Filenames) == 0 {
+			return fmt.Errorf("no datasets specified")
+		}
+		files, err = pathFiles(c.Args.Filenames)
+	}
+	if err != nil {
+		return err
+	}
+
+	// With --staged, only the datasets come from the index: the config
+	// and sidecars are read from the working tree
+	configDir := "."
+	if len(files) > 0 {
+		configDir = filepath.Dir(files[0].name)
+	}
+	config, err := lint.ResolveConfig(c.Config, configDir)
+	if err != nil {
+		return err
+	}
+
+	l := lint.New("mag lint", config)
+	var vocab []lint.VocabUnit
+	var pp []lint.PPUnit
+	var sentences []lint.SentenceUnit
+	var exercises []lint.ExerciseUnit
+	for _, f := range files {
+		var doc yaml.Node
+		err = yaml.Unmarshal(f.data, &doc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.name, err)
+		}
+		var sups []*lint.Suppression
+		switch dataset.DetectSchema(&doc) {
+		case "vocab":
+			var units []lint.VocabUnit
+			units, sups, err = lint.ParseVocab(f.data, f.name, 0)
+			vocab = append(vocab, units...)
+		case "pp":
+			var units []lint.PPUnit
+			units, sups, err = lint.ParsePP(f.data, f.name, 0)
+			pp = append(pp, units...)
+		case "sentences":
+			var units []lint.SentenceUnit
+			units, sups, err = lint.ParseSentences(f.data, f.name, 0)
+			sentences = append(sentences, units...)
+		case "exercises":
+			var units []lint.ExerciseUnit
+			units, sups, err = lint.ParseExercises(f.data, f.name, 0)
+			exercises = append(exercises, units...)
+		default:
+			// Not a dataset (e.g. .maglint.yml, CI config)
+			slog.Debug("skipping non-dataset file", "file", f.name)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		l.AddSuppressions(sups)
+	}
+
+	stats := make(map[string]int)
+	if len(vocab) > 0 {
+		lint.LintVocab(l, vocab, 0, &stats)
+		var vocabFiles []string
+		for _, u := range vocab {
+			vocabFiles = append(vocabFiles, u.File)
+		}
+		analyses, err := enrich.LoadSidecars[[]enrich.Analysis](vocabFiles, enrich.SourceMorpheus)
+		if err != nil {
+			return err
+		}
+		lint.LintMorpheus(l, vocab, analyses)
+	}
+	if len(pp) > 0 {
+		lint.LintPP(l, pp, 0, &stats)
+	}
+	if len(sentences) > 0 {
+		// Check sentence words against the vocab being linted, if any
+		var vocabIDs map[string]bool
+		if len(vocab) > 0 {
+			vocabIDs = lint.VocabIDs(vocab)
+		}
+		lint.LintSentences(l, sentences, 0, vocabIDs, &stats)
+	}
+	if len(exercises) > 0 {
+		lint.LintExercises(l, exercises, 0, &stats)
+	}
+	err = lint.LintManifests(l, c.MediaDir)
+	if err != nil {
+		return err
+	}
+	l.ReportUnusedSuppressions()
+	stats["errors"] = l.Errors()
+	stats["warnings"] = l.Warnings()
+
+	// Text output is terse: findings only, without the stats summary
+	if c.Output == "text" {
+		l.WriteText(os.Stdout)
+	} else {
+		err = l.Write(os.Stdout, c.Output, stats)
+		if err != nil {
+			return err
+		}
+	}
+
+	return l.Err(c.MaxWarnings)
+}
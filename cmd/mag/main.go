@@ -0,0 +1,59 @@
+// mag utility providing subcommands for maintaining the mag datasets
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	flags "github.com/jessevdk/go-flags"
+
+	"github.com/gavincarr/mag/buildinfo"
+	"github.com/gavincarr/mag/logging"
+)
+
+// Options
+type Options struct {
+	Verbose bool   `short:"v" long:"verbose" description:"display verbose output"`
+	Version func() `long:"version" description:"print version and build information and exit"`
+	logging.Options
+}
+
+var (
+	opts Options
+	// Commands are registered on parser in their init() functions.
+	// PrintErrors is omitted so that command errors aren't printed twice.
+	parser = flags.NewParser(&opts, flags.HelpFlag|flags.PassDoubleDash)
+)
+
+func main() {
+	opts.Version = func() {
+		fmt.Println(buildinfo.String("mag"))
+		os.Exit(0)
+	}
+	// Configure logging once options are parsed, before running the command
+	parser.CommandHandler = func(cmd flags.Commander, args []string) error {
+		logging.Setup(opts.Options, opts.Verbose)
+		if cmd == nil {
+			return nil
+		}
+		return cmd.Execute(args)
+	}
+	_, err := parser.Parse()
+	if err != nil {
+		var ferr *flags.Error
+		if errors.As(err, &ferr) {
+			if ferr.Type == flags.ErrHelp {
+				fmt.Fprintln(os.Stdout, err)
+				os.Exit(0)
+			}
+
+			fmt.Fprintf(os.Stderr, "Error: %s\n\n", err.Error())
+			parser.WriteHelp(os.Stderr)
+			os.Exit(2)
+		}
+
+		logging.Fatal(err)
+	}
+}
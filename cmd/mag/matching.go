@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gavincarr/mag/pdf"
+)
+
+// matchingBlank is the space for students to write the letter of a gloss
+const matchingBlank = "____"
+
+// PrintMatchingCommand prints matching exercises
+type PrintMatchingCommand struct {
+	PrintOptions
+	Size    int    `short:"n" long:"size" default:"10" description:"words per exercise: units with more words are split into several exercises (at most 26)"`
+	Format  string `short:"f" long:"format" choice:"pdf" choice:"markdown" description:"output format (default: markdown if --outfile ends in .md, else pdf)"`
+	Answers string `long:"answers" description:"answer key file to write (default: --outfile with -answers before its extension)"`
+	Seed    int64  `long:"seed" description:"random seed for shuffling the glosses, to reproduce the exercises (default: the current time)"`
+	Title   string `long:"title" description:"title (default: from --units)"`
+	Args    struct {
+		Filenames []string `positional-arg-name:"filename" required:"1" description:"vocab yml datasets or directories"`
+	} `positional-args:"yes"`
+}
+
+// matchingExercise is a matching exercise: a column of Greek words, and
+// a shuffled column of their glosses
+type matchingExercise struct {
+	title   string
+	words   []string
+	glosses []string
+	answers []int // index in glosses of the gloss of each word
+}
+
+// matchingLetter returns the label of gloss i: A to Z
+func matchingLetter(i int) string {
+	return string(rune('A' + i))
+}
+
+// matchingExercises returns the matching exercises for the vocab words
+// in s, in exercises of at most size words per unit, with the glosses
+// shuffled by r. Words with the same gloss share its letter.
+func matchingExercises(s *server, size int, r *rand.Rand) []*matchingExercise {
+	var exercises []*matchingExercise
+	for _, u := range s.units {
+		var words []serveWord
+		for _, w := range s.vocab {
+			if w.UnitName == u.Name && w.Unit == u.Unit {
+				words = append(words, w)
+			}
+		}
+		parts := (len(words) + size - 1) / size
+		for i := 0; i < parts; i++ {
+			// Spread the words evenly, rather than leaving a short last part
+			chunk := words[i*len(words)/parts : (i+1)*len(words)/parts]
+			e := &matchingExercise{title: unitLabel(u.Name, u.Unit)}
+			if parts > 1 {
+				e.title += fmt.Sprintf(" (%d of %d)", i+1, parts)
+			}
+			var glosses []string
+			for _, w := range chunk {
+				gr := strings.TrimSpace(w.Gr + " " + w.GrExt)
+				if w.Homonym != "" {
+					gr += " [" + w.Homonym + "]"
+				}
+				e.words = append(e.words, gr)
+				en := reGlossSep.ReplaceAllString(w.En, "; ")
+				if !slices.Contains(glosses, en) {
+					glosses = append(glosses, en)
+				}
+			}
+			order := r.Perm(len(glosses))
+			e.glosses = make([]string, len(glosses))
+			for j, k := range order {
+				e.glosses[j] = glosses[k]
+			}
+			for _, w := range chunk {
+				en := reGlossSep.ReplaceAllString(w.En, "; ")
+				for j, g := range e.glosses {
+					if g == en {
+						e.answers = append(e.answers, j)
+						break
+					}
+				}
+			}
+			exercises = append(exercises, e)
+		}
+	}
+	return exercises
+}
+
+// matchingMarkdown returns the exercises as a Markdown document, with
+// the answers filled in (in bold) if key is set
+func matchingMarkdown(title string, exercises []*matchingExercise, key bool) []byte {
+	var b bytes.Buffer
+	if key {
+		title += ": Answers"
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	if !key {
+		b.WriteString("Name: ____________________\n\n" +
+			"Write the letter of each word's meaning in the blank beside it.\n\n")
+	}
+	for _, e := range exercises {
+		fmt.Fprintf(&b, "## %s\n\n", e.title)
+		b.WriteString("| # | Greek | | | English |\n|---|---|---|---|---|\n")
+		for i := 0; i < max(len(e.words), len(e.glosses)); i++ {
+			num, word, answer, letter, gloss := "", "", "", "", ""
+			if i < len(e.words) {
+				num, word, answer = fmt.Sprintf("%d", i+1), markdownCell(e.words[i]), matchingBlank
+				if key {
+					answer = "**" + matchingLetter(e.answers[i]) + "**"
+				}
+			}
+			if i < len(e.glosses) {
+				letter, gloss = matchingLetter(i), markdownCell(e.glosses[i])
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", num, word, answer, letter, gloss)
+		}
+		b.WriteString("\n")
+	}
+	return b.Bytes()
+}
+
+// matchingPDF draws the exercises in doc, with the answers filled in if
+// key is set
+func matchingPDF(doc *pdf.Document, title string, exercises []*matchingExercise, key bool) {
+	if key {
+		title += ": Answers"
+	}
+	b := &booklet{doc: doc, font: doc.Font(), title: title, margin: 20 * pdf.MM}
+	b.newPage()
+	b.y -= bookletTitleSize * 1.5
+	b.page.Text(b.margin, b.y, bookletTitleSize, 0, title)
+	if !key {
+		b.y -= bookletTitleSize
+		b.page.Text(b.margin, b.y, bookletTextSize, 0, "Name: ____________________")
+		b.y -= bookletTextSize * 2
+		b.page.Text(b.margin, b.y, bookletTextSize, 0.3,
+			"Write the letter of each word's meaning in the blank beside it.")
+	}
+	b.y -= bookletTextSize
+
+	lineHeight := bookletGreekSize * 1.3
+	for _, e := range exercises {
+		// Keep each exercise on one page, if it fits
+		rows := max(len(e.words), len(e.glosses))
+		b.need(bookletHeadingSize*2.6 + float64(rows)*lineHeight*1.5)
+		b.section = e.title
+		b.heading(e.title, bookletHeadingSize-2)
+		for i := 0; i < rows; i++ {
+			left, right := "", ""
+			if i < len(e.words) {
+				answer := matchingBlank
+				if key {
+					answer = matchingLetter(e.answers[i])
+				}
+				left = fmt.Sprintf("%d. %s  %s", i+1, answer, e.words[i])
+			}
+			if i < len(e.glosses) {
+				right = matchingLetter(i) + ". " + e.glosses[i]
+			}
+			b.row(left, right, bookletGreekSize, bookletTextSize, 0.45)
+			b.y -= lineHeight / 3
+		}
+	}
+}
+
+func (c *PrintMatchingCommand) Execute(args []string) error {
+	if c.Size < 2 || c.Size > 26 {
+		return fmt.Errorf("invalid --size %d (want 2 to 26 words per exercise)", c.Size)
+	}
+	format := c.Format
+	if format == "" {
+		format = worksheetPDF
+		if strings.EqualFold(filepath.Ext(c.Outfile), ".md") {
+			format = worksheetMD
+		}
+	}
+	answers := c.Answers
+	if answers == "" {
+		answers = answersFilename(c.Outfile)
+	}
+	if answers == c.Outfile {
+		return fmt.Errorf("--answers must differ from --outfile")
+	}
+
+	s, err := printData(c.Args.Filenames, c.Units)
+	if err != nil {
+		return err
+	}
+	if len(s.vocab) == 0 {
+		return fmt.Errorf("no vocab words to match (give vocab datasets)")
+	}
+	seed := c.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	exercises := matchingExercises(s, c.Size, rand.New(rand.NewSource(seed)))
+	title := c.Title
+	if title == "" {
+		title = unitsTitle("Vocabulary: Matching", c.Units)
+	}
+
+	if format == worksheetMD {
+		err = os.WriteFile(c.Outfile, matchingMarkdown(title, exercises, false), 0o644)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(answers, matchingMarkdown(title, exercises, true), 0o644)
+	}
+	for _, key := range []bool{false, true} {
+		doc, err := newPrintDocument(c.PrintOptions, title)
+		if err != nil {
+			return err
+		}
+		if !key {
+			checker := glyphChecker{font: doc.Font()}
+			for _, e := range exercises {
+				checker.check(e.words...)
+				checker.check(e.glosses...)
+			}
+			checker.warn()
+		}
+		matchingPDF(doc, title, exercises, key)
+		filename := c.Outfile
+		if key {
+			filename = answers
+		}
+		err = writePDF(doc, filename)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/gavincarr/mag/dataset"
+)
+
+// MigrateCommand upgrades datasets to the current schema version
+type MigrateCommand struct {
+	Check  bool `short:"c" long:"check" description:"list files that need migrating, without rewriting them"`
+	Stdout bool `long:"stdout" description:"write migrated output to stdout instead of rewriting files"`
+	Args   struct {
+		Filenames []string `positional-arg-name:"filename" description:"yml datasets to migrate (- for stdin, written to stdout)" required:"1"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	_, err := parser.AddCommand("migrate",
+		"Upgrade datasets to the current schema version",
+		fmt.Sprintf("Upgrade mag yml datasets (vocab, pp, paradigms, sentences, exercises) from older schema versions to the current version (%d). Both forms of version 1 dataset (a bare list of units, or a mapping with the units under a top-level \"units\" key alongside a \"version\" field) are current, so there are no migrations yet: this checks that datasets are not newer than the current version.",
+			dataset.CurrentVersion),
+		&MigrateCommand{})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (c *MigrateCommand) Execute(args []string) error {
+	outdated := 0
+	for _, filename := range c.Args.Filenames {
+		data, err := dataset.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		var doc yaml.Node
+		err = yaml.Unmarshal(data, &doc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+
+		version, err := dataset.Migrate(&doc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		// Datasets read from stdin are always written to stdout
+		stdout := c.Stdout || filename == dataset.Stdin && !c.Check
+		if version == dataset.CurrentVersion && !stdout {
+			slog.Debug("already at current version", "file", filename,
+				"version", version)
+			continue
+		}
+		if c.Check {
+			fmt.Printf("%s: version %d\n", filename, version)
+			outdated++
+			continue
+		}
+
+		out, err := dataset.Encode(&doc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		if stdout {
+			os.Stdout.Write(out)
+			continue
+		}
+		info, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(filename, out, info.Mode())
+		if err != nil {
+			return err
+		}
+		slog.Info("migrated", "file", filename, "from", version,
+			"to", dataset.CurrentVersion)
+	}
+
+	if outdated > 0 {
+		return fmt.Errorf("%d file(s) need migrating", outdated)
+	}
+	return nil
+}
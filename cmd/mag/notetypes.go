@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+
+	"github.com/gavincarr/mag/export"
+)
+
+// NotetypesCommand prints the definitions of the Anki notetypes used by
+// the exporters
+type NotetypesCommand struct {
+	Format    string `short:"f" long:"format" choice:"json" choice:"text" default:"json" description:"output format"`
+	Fonts     string `long:"fonts" env:"MAG_FONTS" description:"CSS font-family list for card text (default: a polytonic Greek font stack)"`
+	FontSize  int    `long:"font-size" env:"MAG_FONT_SIZE" description:"base card font size in px (default: 20)"`
+	FrontSize int    `long:"front-size" env:"MAG_FRONT_SIZE" description:"card front font size in px (default: 32)"`
+	Args      struct {
+		Names []string `positional-arg-name:"name" description:"notetypes to print (default: all)"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	_, err := parser.AddCommand("notetypes",
+		"Print Anki notetype definitions",
+		"Print the definitions (fields, card templates, and CSS) of the Anki notetypes the exporters create notes for, so they can be created in Anki before importing",
+		&NotetypesCommand{})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (c *NotetypesCommand) Execute(args []string) error {
+	style := export.DefaultStyle()
+	if c.Fonts != "" {
+		style.Fonts = c.Fonts
+	}
+	if c.FontSize > 0 {
+		style.FontSize = c.FontSize
+	}
+	if c.FrontSize > 0 {
+		style.FrontSize = c.FrontSize
+	}
+
+	notetypes := export.Notetypes(style)
+	if len(c.Args.Names) > 0 {
+		notetypes = nil
+		for _, name := range c.Args.Names {
+			n, err := export.FindNotetype(name, style)
+			if err != nil {
+				return err
+			}
+			notetypes = append(notetypes, n)
+		}
+	}
+
+	if c.Format == "text" {
+		for i, n := range notetypes {
+			if i > 0 {
+				os.Stdout.WriteString("\n")
+			}
+			n.Write(os.Stdout)
+		}
+		return nil
+	}
+	return export.WriteNotetypesJSON(os.Stdout, notetypes)
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gavincarr/mag/dataset"
+	"github.com/gavincarr/mag/pdf"
+)
+
+var (
+	reUnitRange = regexp.MustCompile(`^(\d+)(?:-(\d+))?$`)
+	reGrid      = regexp.MustCompile(`^(\d+)x(\d+)$`)
+	reGlossSep  = regexp.MustCompile(`\s*;\s*`)
+)
+
+// PrintCommand groups the commands printing the datasets as PDFs
+type PrintCommand struct{}
+
+// PrintOptions are the options shared by the print commands
+type PrintOptions struct {
+	Outfile string `short:"o" long:"outfile" required:"yes" description:"file to write"`
+	Paper   string `long:"paper" choice:"a4" choice:"letter" default:"a4" env:"MAG_PAPER" description:"paper size"`
+	Font    string `long:"font" env:"MAG_FONT" description:"TrueType or OpenType font file covering polytonic Greek (default: DejaVu Sans, Noto Sans, or Arial Unicode, if installed)"`
+	Units   string `short:"u" long:"units" env:"MAG_UNITS" description:"print only this unit number, or range of unit numbers (e.g. 3 or 1-5)"`
+}
+
+func init() {
+	cmd, err := parser.AddCommand("print",
+		"Print datasets as PDFs",
+		"Print vocab.yml/pp.yml datasets as PDFs, for use without Anki. Greek text needs a font with polytonic glyphs, given by --font if none of the defaults is installed.",
+		&PrintCommand{})
+	if err != nil {
+		panic(err)
+	}
+	_, err = cmd.AddCommand("cards",
+		"Print double-sided flashcards",
+		"Print flashcards for cutting out: pages of card fronts (Greek headwords and presents), each followed by a page of the matching backs (English glosses and principal parts) mirrored for double-sided printing",
+		&PrintCardsCommand{})
+	if err != nil {
+		panic(err)
+	}
+	_, err = cmd.AddCommand("booklet",
+		"Print a vocabulary booklet",
+		"Print a vocabulary booklet: a section per unit listing its words and glosses, an appendix of the principal parts of the verbs in the pp datasets, and an alphabetical index of the words and verbs, with running heads and page numbers",
+		&PrintBookletCommand{})
+	if err != nil {
+		panic(err)
+	}
+	_, err = cmd.AddCommand("worksheet",
+		"Print fill-in-the-blank worksheets",
+		"Print a worksheet where students supply the English glosses of vocab words, or the missing principal parts of verbs, as a PDF or Markdown file, with a separate answer key file",
+		&PrintWorksheetCommand{})
+	if err != nil {
+		panic(err)
+	}
+	_, err = cmd.AddCommand("matching",
+		"Print matching exercises",
+		"Print matching exercises for each unit: a column of Greek vocab words, and a shuffled column of their lettered glosses to match them with, as a PDF or Markdown file, with a separate answer key file",
+		&PrintMatchingCommand{})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// unitRange is a range of unit numbers, where 0 means unbounded
+type unitRange struct {
+	from, to int
+}
+
+// parseUnitRange parses a --units unit number or range (e.g. "3" or
+// "1-5")
+func parseUnitRange(s string) (unitRange, error) {
+	if s == "" {
+		return unitRange{}, nil
+	}
+	m := reUnitRange.FindStringSubmatch(s)
+	if m == nil {
+		return unitRange{}, fmt.Errorf("invalid --units %q (want a unit number, or a range like 1-5)", s)
+	}
+	from, _ := strconv.Atoi(m[1])
+	to := from
+	if m[2] != "" {
+		to, _ = strconv.Atoi(m[2])
+	}
+	if to < from {
+		return unitRange{}, fmt.Errorf("invalid --units %q (range is backwards)", s)
+	}
+	return unitRange{from, to}, nil
+}
+
+// contains reports whether the unit number is in r
+func (r unitRange) contains(unit int) bool {
+	return (r.from == 0 || unit >= r.from) && (r.to == 0 || unit <= r.to)
+}
+
+// unitLabel returns the label for the unit name/number on printed pages
+func unitLabel(name string, number int) string {
+	if name != "" {
+		return name
+	}
+	return fmt.Sprintf("Unit %d", number)
+}
+
+// printData loads the datasets at paths for printing, with only the
+// words and verbs in the --units range unitsOpt
+func printData(paths []string, unitsOpt string) (*server, error) {
+	units, err := parseUnitRange(unitsOpt)
+	if err != nil {
+		return nil, err
+	}
+	s, err := loadServer(paths)
+	if err != nil {
+		return nil, err
+	}
+	var vocab []serveWord
+	for _, w := range s.vocab {
+		if units.contains(w.Unit) {
+			vocab = append(vocab, w)
+		}
+	}
+	var pp []serveVerb
+	for _, v := range s.pp {
+		if units.contains(v.Unit) {
+			pp = append(pp, v)
+		}
+	}
+	if len(vocab) == 0 && len(pp) == 0 {
+		return nil, fmt.Errorf("no words or verbs in --units %s", unitsOpt)
+	}
+	s.vocab, s.pp = vocab, pp
+	return s, nil
+}
+
+// newPrintDocument returns a new document with the paper size and font
+// of opts
+func newPrintDocument(opts PrintOptions, title string) (*pdf.Document, error) {
+	filename := opts.Font
+	if filename == "" {
+		filename = pdf.FindFont()
+		if filename == "" {
+			return nil, fmt.Errorf("no default font installed: use --font to give a TrueType or OpenType font covering polytonic Greek")
+		}
+		slog.Debug("using default font", "font", filename)
+	}
+	font, err := pdf.LoadFont(filename)
+	if err != nil {
+		return nil, err
+	}
+	size := pdf.PageSizes[opts.Paper]
+	doc := pdf.New(size[0], size[1], font)
+	doc.Title = title
+	return doc, nil
+}
+
+// glyphChecker collects the characters of printed text that the font
+// has no glyphs for, to warn about once
+type glyphChecker struct {
+	font    *pdf.Font
+	missing []rune
+	seen    map[rune]bool
+}
+
+// check records the missing characters in texts
+func (c *glyphChecker) check(texts ...string) {
+	if c.seen == nil {
+		c.seen = make(map[rune]bool)
+	}
+	for _, s := range texts {
+		for _, r := range c.font.Missing(s) {
+			if !c.seen[r] {
+				c.seen[r] = true
+				c.missing = append(c.missing, r)
+			}
+		}
+	}
+}
+
+// warn logs a warning if any characters were missing
+func (c *glyphChecker) warn() {
+	if len(c.missing) > 0 {
+		slog.Warn("font has no glyphs for some characters, which will print as blanks or boxes (try another --font)",
+			"font", c.font.Name, "chars", string(c.missing))
+	}
+}
+
+// writePDF writes doc to filename
+func writePDF(doc *pdf.Document, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	_, err = doc.WriteTo(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	slog.Info("wrote PDF", "file", filename, "pages", doc.Pages())
+	return nil
+}
+
+// PrintCardsCommand prints double-sided flashcards
+type PrintCardsCommand struct {
+	PrintOptions
+	Grid     string  `long:"grid" default:"2x4" env:"MAG_CARD_GRID" description:"cards per page, as COLUMNSxROWS"`
+	Flip     string  `long:"flip" choice:"long" choice:"short" default:"long" description:"the page edge your printer flips double-sided pages on, which determines how the backs are mirrored (long: the usual duplex setting; short: for tumble/short-edge binding)"`
+	FontSize float64 `long:"font-size" default:"22" description:"maximum font size of the card text, in points, reduced as needed to fit long text"`
+	Args     struct {
+		Filenames []string `positional-arg-name:"filename" required:"1" description:"vocab and pp yml datasets or directories to print"`
+	} `positional-args:"yes"`
+}
+
+// cardFace is the text of one side of a printed card: its main text,
+// any smaller subsidiary text below it, and a label in the corner
+type cardFace struct {
+	Main, Sub, Label string
+}
+
+// printCard is a printed flashcard
+type printCard struct {
+	Front, Back cardFace
+}
+
+// vocabCard returns the printed card for the vocab word w
+func vocabCard(w serveWord) printCard {
+	label := unitLabel(w.UnitName, w.Unit)
+	sub := w.GrExt
+	if w.Homonym != "" {
+		sub = strings.TrimSpace(sub + " [" + w.Homonym + "]")
+	}
+	return printCard{
+		Front: cardFace{Main: w.Gr, Sub: sub, Label: label},
+		Back: cardFace{
+			Main:  reGlossSep.ReplaceAllString(w.En, "; "),
+			Sub:   dataset.PartsOfSpeech[w.Pos],
+			Label: label,
+		},
+	}
+}
+
+// verbParts returns the principal parts of v, with dashes for those it
+// lacks
+func verbParts(v serveVerb) []string {
+	parts := []string{v.Pr, v.Fu, v.Ao, v.Pf, v.Pm, v.Ap}
+	for i, p := range parts {
+		if p == "" {
+			parts[i] = "—"
+		}
+	}
+	return parts
+}
+
+// ppCard returns the printed card for the principal parts verb v
+func ppCard(v serveVerb) printCard {
+	label := unitLabel(v.UnitName, v.Unit)
+	return printCard{
+		Front: cardFace{Main: v.Pr, Sub: "principal parts", Label: label},
+		Back:  cardFace{Main: strings.Join(verbParts(v), ", "), Sub: v.VClass, Label: label},
+	}
+}
+
+// cardLayout is the position of the cards on a page
+type cardLayout struct {
+	cols, rows        int
+	margin            float64
+	cellW, cellH      float64
+	pageW, pageH      float64
+	maxSize, minSize  float64
+	padding, labelPad float64
+}
+
+// cell returns the bottom left corner of the card at col and row, from
+// the top left of the page
+func (l cardLayout) cell(col, row int) (float64, float64) {
+	return l.margin + float64(col)*l.cellW, l.pageH - l.margin - float64(row+1)*l.cellH
+}
+
+// drawGuides draws dashed cutting guides around the cards on p
+func (l cardLayout) drawGuides(p *pdf.Page) {
+	style := pdf.LineStyle{Width: 0.4, Gray: 0.6, Dash: 3}
+	top, bottom := l.pageH-l.margin, l.margin
+	for col := 0; col <= l.cols; col++ {
+		x := l.margin + float64(col)*l.cellW
+		p.Line(x, bottom, x, top, style)
+	}
+	for row := 0; row <= l.rows; row++ {
+		y := l.margin + float64(row)*l.cellH
+		p.Line(l.margin, y, l.pageW-l.margin, y, style)
+	}
+}
+
+// drawFace draws the card face in the cell with bottom left corner
+// (x, y), shrinking the main text until it fits
+func (l cardLayout) drawFace(p *pdf.Page, font *pdf.Font, x, y float64, face cardFace) {
+	const labelSize = 7
+	if face.Label != "" {
+		p.Text(x+l.labelPad, y+l.cellH-l.labelPad-font.Ascent(labelSize), labelSize, 0.5, face.Label)
+	}
+
+	width := l.cellW - 2*l.padding
+	subSize := 0.0
+	var main, sub []string
+	size := l.maxSize
+	for {
+		main = font.Wrap(face.Main, size, width)
+		subSize = max(size*0.5, l.minSize)
+		sub = font.Wrap(face.Sub, subSize, width)
+		height := float64(len(main))*size*1.2 + float64(len(sub))*subSize*1.2
+		if height <= l.cellH-2*l.padding || size <= l.minSize {
+			break
+		}
+		size = max(size*0.9, l.minSize)
+	}
+
+	// Centre the text block vertically in the cell
+	height := float64(len(main))*size*1.2 + float64(len(sub))*subSize*1.2
+	line := y + (l.cellH+height)/2
+	cx := x + l.cellW/2
+	for _, s := range main {
+		line -= size * 1.2
+		p.TextCentered(cx, line+font.Descent(size), size, 0, s)
+	}
+	for _, s := range sub {
+		line -= subSize * 1.2
+		p.TextCentered(cx, line+font.Descent(subSize), subSize, 0.35, s)
+	}
+}
+
+func (c *PrintCardsCommand) Execute(args []string) error {
+	m := reGrid.FindStringSubmatch(c.Grid)
+	if m == nil {
+		return fmt.Errorf("invalid --grid %q (want COLUMNSxROWS, e.g. 2x4)", c.Grid)
+	}
+	cols, _ := strconv.Atoi(m[1])
+	rows, _ := strconv.Atoi(m[2])
+	if cols < 1 || rows < 1 || cols > 10 || rows > 20 {
+		return fmt.Errorf("invalid --grid %q (want 1-10 columns and 1-20 rows)", c.Grid)
+	}
+	if c.FontSize < 6 {
+		return fmt.Errorf("invalid --font-size %g (minimum 6)", c.FontSize)
+	}
+
+	s, err := printData(c.Args.Filenames, c.Units)
+	if err != nil {
+		return err
+	}
+	doc, err := newPrintDocument(c.PrintOptions, "mag flashcards")
+	if err != nil {
+		return err
+	}
+	font := doc.Font()
+
+	var cards []printCard
+	for _, w := range s.vocab {
+		cards = append(cards, vocabCard(w))
+	}
+	for _, v := range s.pp {
+		cards = append(cards, ppCard(v))
+	}
+	checker := glyphChecker{font: font}
+	for _, card := range cards {
+		checker.check(card.Front.Main, card.Front.Sub, card.Back.Main, card.Back.Sub)
+	}
+	checker.warn()
+
+	margin := 10 * pdf.MM
+	l := cardLayout{
+		cols: cols, rows: rows, margin: margin,
+		pageW: doc.Width, pageH: doc.Height,
+		cellW:   (doc.Width - 2*margin) / float64(cols),
+		cellH:   (doc.Height - 2*margin) / float64(rows),
+		maxSize: c.FontSize, minSize: 6,
+		padding: 4 * pdf.MM, labelPad: 2 * pdf.MM,
+	}
+
+	// Each page of fronts is followed by its backs, mirrored so each back
+	// prints behind its front: across the page for long-edge flipping,
+	// or down the page for short-edge flipping
+	perPage := cols * rows
+	for start := 0; start < len(cards); start += perPage {
+		page := cards[start:min(start+perPage, len(cards))]
+		fronts, backs := doc.AddPage(), doc.AddPage()
+		l.drawGuides(fronts)
+		l.drawGuides(backs)
+		for i, card := range page {
+			col, row := i%cols, i/cols
+			x, y := l.cell(col, row)
+			l.drawFace(fronts, font, x, y, card.Front)
+			if c.Flip == "short" {
+				row = rows - 1 - row
+			} else {
+				col = cols - 1 - col
+			}
+			x, y = l.cell(col, row)
+			l.drawFace(backs, font, x, y, card.Back)
+		}
+	}
+	slog.Info("printing flashcards", "cards", len(cards), "vocab", len(s.vocab), "pp", len(s.pp))
+	return writePDF(doc, c.Outfile)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// ProgressCommand summarizes the quiz history by unit
+type ProgressCommand struct {
+	Units string `short:"u" long:"units" env:"MAG_UNITS" description:"summarize only this unit number, or range of unit numbers (e.g. 3 or 1-5)"`
+	State string `long:"state" env:"MAG_QUIZ_STATE" description:"quiz history file (default: mag/quiz.json in $XDG_STATE_HOME or ~/.local/state)"`
+	Args  struct {
+		Filenames []string `positional-arg-name:"filename" required:"1" description:"vocab and pp yml datasets or directories"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	_, err := parser.AddCommand("progress",
+		"Summarize quiz progress",
+		fmt.Sprintf("Summarize the mag quiz history of the cards in vocab.yml/pp.yml datasets by unit: the number of cards (a vocab word in each direction, or a principal part), how many have been quizzed, mastered (answered correctly %d times in a row), and are due for review, and the percentage mastered", quizMasteredStreak),
+		&ProgressCommand{})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// unitProgress is the quiz progress of the cards of a unit
+type unitProgress struct {
+	cards, seen, mastered, due int
+}
+
+// add adds the card c (nil if never quizzed) to the progress p, as of now
+func (p *unitProgress) add(c *quizCard, now time.Time) {
+	p.cards++
+	if c == nil {
+		return
+	}
+	p.seen++
+	if c.mastered() {
+		p.mastered++
+	}
+	if !c.due().After(now) {
+		p.due++
+	}
+}
+
+// row returns p as a tab-separated table row, labelled label
+func (p *unitProgress) row(label string) string {
+	mastery := 0
+	if p.cards > 0 {
+		mastery = 100 * p.mastered / p.cards
+	}
+	return fmt.Sprintf("%s\t%d\t%d\t%d\t%d\t%d%%", label, p.cards, p.seen, p.mastered, p.due, mastery)
+}
+
+func (c *ProgressCommand) Execute(args []string) error {
+	stateFile, err := quizStateFile(c.State)
+	if err != nil {
+		return err
+	}
+	state, err := loadQuizState(stateFile)
+	if err != nil {
+		return err
+	}
+	s, err := printData(c.Args.Filenames, c.Units)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Unit\tCards\tQuizzed\tMastered\tDue\tMastery")
+	var total unitProgress
+	for _, u := range s.units {
+		var p unitProgress
+		for _, w := range s.vocab {
+			if w.UnitName == u.Name && w.Unit == u.Unit {
+				p.add(state.Cards[vocabCardID(w, quizGrEn)], now)
+				p.add(state.Cards[vocabCardID(w, quizEnGr)], now)
+			}
+		}
+		for _, v := range s.pp {
+			if v.UnitName != u.Name || v.Unit != u.Unit {
+				continue
+			}
+			for i, part := range verbParts(v)[1:] {
+				if part != "—" {
+					p.add(state.Cards[ppCardID(v, i+1)], now)
+				}
+			}
+		}
+		if p.cards == 0 {
+			continue
+		}
+		fmt.Fprintln(tw, p.row(unitLabel(u.Name, u.Unit)))
+		total.cards += p.cards
+		total.seen += p.seen
+		total.mastered += p.mastered
+		total.due += p.due
+	}
+	fmt.Fprintln(tw, total.row("Total"))
+	return tw.Flush()
+}
@@ -0,0 +1,525 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gavincarr/mag/dataset"
+	"github.com/gavincarr/mag/greek"
+)
+
+// Quiz modes and vocab directions
+const (
+	quizVocab = "vocab"
+	quizPP    = "pp"
+	quizGrEn  = "gr-en"
+	quizEnGr  = "en-gr"
+	quizMixed = "mixed"
+)
+
+// Differences in typed Greek answers that --ignore can accept
+const (
+	ignoreAccents    = "accents"
+	ignoreBreathings = "breathings"
+	ignoreSigma      = "sigma"
+)
+
+var (
+	reParenthetical = regexp.MustCompile(`\s*\([^)]*\)\s*`)
+	// reStemNote matches the "(stem X-)" annotations in principal parts
+	reStemNote = regexp.MustCompile(`\s*\(stem \p{Greek}+-\)`)
+)
+
+// QuizCommand quizzes the vocab and principal parts in the terminal
+type QuizCommand struct {
+	Units     string  `short:"u" long:"units" env:"MAG_UNITS" description:"quiz only this unit number, or range of unit numbers (e.g. 3 or 1-5)"`
+	Mode      string  `short:"m" long:"mode" choice:"vocab" choice:"pp" default:"vocab" env:"MAG_QUIZ_MODE" description:"what to quiz (vocab: vocab words, in --direction; pp: principal parts of verbs)"`
+	Direction string  `short:"d" long:"direction" choice:"gr-en" choice:"en-gr" choice:"mixed" default:"en-gr" env:"MAG_QUIZ_DIRECTION" description:"vocab direction (gr-en: type the English for Greek words; en-gr: type the Greek for English glosses; mixed: either, at random)"`
+	Limit     int     `short:"n" long:"limit" description:"ask only this many questions (default: all)"`
+	Shuffle   bool    `short:"s" long:"shuffle" description:"ask the questions in random order, instead of dataset order"`
+	NewFirst  bool    `long:"new-first" description:"ask the questions never quizzed before first"`
+	DueFirst  bool    `long:"due-first" description:"ask the questions quizzed before first, most overdue for review first"`
+	State     string  `long:"state" env:"MAG_QUIZ_STATE" description:"file recording the quiz history, for --new-first and --due-first (default: mag/quiz.json in $XDG_STATE_HOME or ~/.local/state)"`
+	Ignore    string  `long:"ignore" env:"MAG_QUIZ_IGNORE" description:"differences to accept in typed Greek answers, as a comma-separated list of accents, breathings, and sigma (final sigma mistakes)"`
+	Seed      int64   `long:"seed" description:"random seed for --shuffle and --direction mixed (default: the current time)"`
+	Close     float64 `long:"close" default:"0.75" description:"similarity (0 to 1, by edit distance) at or above which a wrong answer is a near miss, with a hint where it went wrong"`
+	Partial   bool    `long:"partial-credit" description:"count near misses as partially correct in the score, in proportion to their similarity"`
+	Args      struct {
+		Filenames []string `positional-arg-name:"filename" required:"1" description:"vocab and pp yml datasets or directories"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	_, err := parser.AddCommand("quiz",
+		"Quiz vocab and principal parts",
+		"Quiz vocab.yml/pp.yml datasets in the terminal: type the English for Greek words, the Greek for English glosses, or the principal parts of verbs, enter ? for a hint (the part of speech, first letter, case marker, or another principal part, in turn), and a blank line if you don't know. Answers are recorded in a quiz history file, scheduling each card for review at growing intervals while it is answered correctly, for ordering later sessions with --new-first or --due-first (see also mag progress). Typed Greek is graded letter by letter, reporting exactly which accents, breathings, iota subscripts, diaereses, or sigma forms differ, with --ignore accepting some of them.",
+		&QuizCommand{})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// quizItem is a quiz question, with its accepted answers
+type quizItem struct {
+	id     string // card id, for the quiz history
+	prompt string
+	answer string   // expected answer, as shown
+	accept []string // accepted answers
+	greek  bool     // answers are Greek, graded with greek.CheckAnswer
+	hints  []string // hints to reveal in turn, on request
+}
+
+// quizHintKey is the answer requesting the next hint
+const quizHintKey = "?"
+
+// firstLetterHint returns a hint giving the first letter of s, without
+// any diacritics
+func firstLetterHint(s string) string {
+	for _, r := range greek.StripDiacritics(s) {
+		return "starts with " + string(r)
+	}
+	return ""
+}
+
+// wordHints returns the hints for the vocab word w: its part of speech,
+// the first letter of answer, and (for Greek answers) its case marker or
+// other dictionary form endings
+func wordHints(w serveWord, answer string, isGreek bool) []string {
+	var hints []string
+	if pos := dataset.PartsOfSpeech[w.Pos]; pos != "" {
+		hints = append(hints, pos)
+	}
+	hints = append(hints, firstLetterHint(answer))
+	if isGreek {
+		forms := ""
+		if _, rest, ok := strings.Cut(w.Gr, ","); ok {
+			forms = strings.TrimSpace(rest)
+		}
+		forms = strings.TrimSpace(forms + " " + w.GrExt)
+		if forms != "" {
+			label := "forms: "
+			if w.Pos == "n" {
+				label = "genitive and article: "
+			}
+			hints = append(hints, label+forms)
+		}
+	}
+	return hints
+}
+
+// vocabCardID returns the card id of the vocab word w in direction: the
+// direction, and the word's explicit id or its headword (with any
+// homonym number). Card ids don't depend on units, glosses, or dataset
+// order, so quiz histories survive most dataset updates.
+func vocabCardID(w serveWord, direction string) string {
+	id := w.Id
+	if id == "" {
+		id = strings.TrimSpace(strings.SplitN(w.Gr, ",", 2)[0])
+	}
+	if w.Homonym != "" {
+		id += " [" + w.Homonym + "]"
+	}
+	return direction + ":" + id
+}
+
+// ppCardID returns the card id of principal part i (an index into
+// ppPartNames) of the verb v
+func ppCardID(v serveVerb, i int) string {
+	return quizPP + ":" + v.Pr + ":" + ppPartNames[i]
+}
+
+// vocabItem returns the question for the vocab word w in direction
+func vocabItem(w serveWord, direction string) quizItem {
+	gr := strings.TrimSpace(w.Gr + " " + w.GrExt)
+	id := vocabCardID(w, direction)
+	if direction == quizGrEn {
+		if w.Homonym != "" {
+			gr += " [" + w.Homonym + "]"
+		}
+		glosses := reGlossSep.Split(strings.TrimSpace(w.En), -1)
+		return quizItem{
+			id:     id,
+			prompt: "English for: " + gr,
+			answer: strings.Join(glosses, "; "),
+			accept: glosses,
+			hints:  wordHints(w, glosses[0], false),
+		}
+	}
+	headword := strings.TrimSpace(strings.SplitN(w.Gr, ",", 2)[0])
+	return quizItem{
+		id:     id,
+		prompt: "Greek for: " + reGlossSep.ReplaceAllString(w.En, "; "),
+		answer: gr,
+		accept: []string{headword, gr},
+		greek:  true,
+		hints:  wordHints(w, headword, true),
+	}
+}
+
+// ppAccepted returns the accepted answers for the principal part entry
+// part: the entry without its "(stem X-)" annotation, and each of its
+// "or" alternates (e.g. ἤνεγκα and ἤνεγκον for "ἤνεγκα or ἤνεγκον"),
+// without parentheses or "(rare)" notes
+func ppAccepted(part string) []string {
+	part = strings.TrimSpace(reStemNote.ReplaceAllString(part, ""))
+	accept := []string{part}
+	alts := strings.Split(part, " or ")
+	if len(alts) == 1 {
+		return accept
+	}
+	for _, alt := range alts {
+		alt = strings.ReplaceAll(alt, "(rare)", "")
+		alt = strings.Trim(strings.TrimSpace(alt), "()")
+		if alt != "" {
+			accept = append(accept, alt)
+		}
+	}
+	return accept
+}
+
+// quizItems returns the questions for mode for the words and verbs in s,
+// choosing the vocab directions for --direction mixed with r
+func quizItems(s *server, mode, direction string, r *rand.Rand) []quizItem {
+	var items []quizItem
+	switch mode {
+	case quizVocab:
+		for _, w := range s.vocab {
+			dir := direction
+			if dir == quizMixed {
+				dir = []string{quizGrEn, quizEnGr}[r.Intn(2)]
+			}
+			items = append(items, vocabItem(w, dir))
+		}
+	case quizPP:
+		for _, v := range s.pp {
+			parts := verbParts(v)
+			for i, part := range parts[1:] {
+				if part == "—" {
+					continue
+				}
+				// Hint with the first other part the verb has, after the present
+				hints := []string{firstLetterHint(part)}
+				for j, other := range parts[1:] {
+					if j != i && other != "—" {
+						hints = append(hints, ppPartLabels[j+1]+": "+other)
+						break
+					}
+				}
+				items = append(items, quizItem{
+					id:     ppCardID(v, i+1),
+					prompt: ppPartLabels[i+1] + " of: " + strings.TrimSpace(reStemNote.ReplaceAllString(parts[0], "")),
+					answer: part,
+					accept: ppAccepted(part),
+					greek:  true,
+					hints:  hints,
+				})
+			}
+		}
+	}
+	return items
+}
+
+// normalizeGloss returns the English gloss s lowercased, without any
+// parenthetical notes, and with its whitespace collapsed
+func normalizeGloss(s string) string {
+	s = reParenthetical.ReplaceAllString(s, " ")
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// similarityKey returns the answer s normalised for similarity scoring:
+// Greek without diacritics, case, or sigma forms, and English glosses as
+// by normalizeGloss
+func similarityKey(s string, isGreek bool) string {
+	if !isGreek {
+		return normalizeGloss(s)
+	}
+	s = strings.ToLower(greek.StripDiacritics(strings.Join(strings.Fields(s), " ")))
+	return strings.ReplaceAll(s, "ς", "σ")
+}
+
+// similarity returns the similarity of the typed answer to want, from 0
+// (nothing in common) to 1 (the same, apart from what similarityKey
+// ignores), by their edit distance, with a hint where they differ
+func similarity(typed, want string, isGreek bool) (float64, string) {
+	t, w := []rune(similarityKey(typed, isGreek)), []rune(similarityKey(want, isGreek))
+	longest := max(len(t), len(w))
+	if longest == 0 {
+		return 1, ""
+	}
+	sim := 1 - float64(dataset.Levenshtein(string(t), string(w)))/float64(longest)
+
+	// Find where they differ, between their common prefix and suffix
+	prefix := 0
+	for prefix < min(len(t), len(w)) && t[prefix] == w[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < min(len(t), len(w))-prefix && t[len(t)-1-suffix] == w[len(w)-1-suffix] {
+		suffix++
+	}
+	hint := "check the spelling"
+	switch {
+	case prefix >= len(w)/2:
+		hint = "check the ending"
+	case len(w)-suffix <= (len(w)+1)/2:
+		hint = "check the beginning"
+	}
+	return sim, hint
+}
+
+// quiz runs a quiz, reading answers from in and writing to out
+type quiz struct {
+	in      *bufio.Scanner
+	out     io.Writer
+	tol     greek.Tolerance
+	close   float64 // similarity of near misses
+	partial bool    // give partial credit for near misses
+	state   *quizState
+}
+
+// quizResult is the grade of an answer
+type quizResult struct {
+	correct  bool
+	close    bool    // a near miss
+	credit   float64 // 1 if correct, else any partial credit
+	feedback string
+}
+
+// grade grades the typed answer to item
+func (q *quiz) grade(item quizItem, typed string) quizResult {
+	if strings.TrimSpace(typed) == "" {
+		return quizResult{feedback: "answer: " + item.answer}
+	}
+	correct, feedback := q.check(item, typed)
+	if correct {
+		return quizResult{correct: true, credit: 1, feedback: feedback}
+	}
+	if feedback != "" {
+		return quizResult{feedback: feedback}
+	}
+
+	// Near misses get a hint where they went wrong, and any partial credit
+	best, hint := 0.0, ""
+	for _, a := range item.accept {
+		sim, h := similarity(typed, a, item.greek)
+		if sim > best {
+			best, hint = sim, h
+		}
+	}
+	if best < q.close {
+		return quizResult{feedback: "answer: " + item.answer}
+	}
+	result := quizResult{close: true, feedback: "close — " + hint}
+	if q.partial {
+		result.credit = best
+		result.feedback += fmt.Sprintf(" (%.1f credit)", best)
+	}
+	result.feedback += "\n  answer: " + item.answer
+	return result
+}
+
+// check reports whether the typed answer to item is correct, with any
+// feedback on the diacritics of typed Greek
+func (q *quiz) check(item quizItem, typed string) (bool, string) {
+	if !item.greek {
+		for _, a := range item.accept {
+			if normalizeGloss(typed) == normalizeGloss(a) {
+				return true, ""
+			}
+		}
+		return false, ""
+	}
+
+	// Grade against the accepted answer with the same letters, if any,
+	// to report its diacritic differences
+	var diffs []greek.AnswerDiff
+	for _, a := range item.accept {
+		ok, d := greek.CheckAnswer(typed, a, q.tol)
+		if ok {
+			if len(d) == 0 {
+				return true, ""
+			}
+			return true, "accepted, but check: " + describeDiffs(d, false)
+		}
+		if d != nil && diffs == nil {
+			diffs = d
+		}
+	}
+	if diffs != nil {
+		return false, "answer: " + item.answer + "\n  diacritics differ: " + describeDiffs(diffs, true)
+	}
+	return false, ""
+}
+
+// describeDiffs returns the answer differences diffs as a list, noting
+// the ones --ignore accepted if markIgnored is set
+func describeDiffs(diffs []greek.AnswerDiff, markIgnored bool) string {
+	descs := make([]string, len(diffs))
+	for i, d := range diffs {
+		descs[i] = d.String()
+		if markIgnored && d.Tolerated {
+			descs[i] += " (ignored)"
+		}
+	}
+	return strings.Join(descs, "; ")
+}
+
+// quizStats are the statistics of a quiz session
+type quizStats struct {
+	asked, right, close int
+	credit              float64 // right answers, plus any partial credit
+	hints, hinted       int     // hints used, and questions using them
+}
+
+// run asks the items in turn until they are done or the input ends, and
+// returns the session statistics
+func (q *quiz) run(items []quizItem) quizStats {
+	var stats quizStats
+	fmt.Fprintf(q.out, "Enter %s for a hint, or a blank line if you don't know.\n", quizHintKey)
+	for i, item := range items {
+		fmt.Fprintf(q.out, "\n[%d/%d] %s\n> ", i+1, len(items), item.prompt)
+		hints := 0
+		var typed string
+		for {
+			if !q.in.Scan() {
+				fmt.Fprintln(q.out)
+				return stats
+			}
+			typed = q.in.Text()
+			if strings.TrimSpace(typed) != quizHintKey {
+				break
+			}
+			if hints == len(item.hints) {
+				fmt.Fprint(q.out, "no more hints\n> ")
+				continue
+			}
+			fmt.Fprintf(q.out, "hint: %s\n> ", item.hints[hints])
+			hints++
+		}
+		if hints > 0 {
+			stats.hints += hints
+			stats.hinted++
+		}
+		result := q.grade(item, typed)
+		q.state.record(item.id, result.correct, hints > 0, time.Now())
+		stats.asked++
+		stats.credit += result.credit
+		mark := "✗"
+		if result.correct {
+			mark = "✓"
+			stats.right++
+		}
+		if result.close {
+			stats.close++
+		}
+		if result.feedback != "" {
+			mark += " " + result.feedback
+		}
+		fmt.Fprintln(q.out, mark)
+	}
+	return stats
+}
+
+// summary returns the score lines for the session statistics
+func (s quizStats) summary(partial bool) string {
+	var line string
+	if partial {
+		line = fmt.Sprintf("Score: %.1f/%d (%d%%), with partial credit for %d close",
+			s.credit, s.asked, int(100*s.credit/float64(s.asked)), s.close)
+	} else {
+		line = fmt.Sprintf("Score: %d/%d (%d%%)", s.right, s.asked, 100*s.right/s.asked)
+		if s.close > 0 {
+			line += fmt.Sprintf(", %d close", s.close)
+		}
+	}
+	if s.hints > 0 {
+		line += fmt.Sprintf("\nHints: %d used, on %d of %d questions", s.hints, s.hinted, s.asked)
+	}
+	return line
+}
+
+// parseTolerance returns the tolerance for the --ignore list s
+func parseTolerance(s string) (greek.Tolerance, error) {
+	var tol greek.Tolerance
+	if s == "" {
+		return tol, nil
+	}
+	names := []string{ignoreAccents, ignoreBreathings, ignoreSigma}
+	for _, name := range strings.Split(s, ",") {
+		switch strings.TrimSpace(name) {
+		case ignoreAccents:
+			tol.Accents = true
+		case ignoreBreathings:
+			tol.Breathings = true
+		case ignoreSigma:
+			tol.FinalSigma = true
+		default:
+			return tol, fmt.Errorf("invalid --ignore %q (want a list of %s)", name, strings.Join(names, ", "))
+		}
+	}
+	return tol, nil
+}
+
+func (c *QuizCommand) Execute(args []string) error {
+	if c.Close <= 0 || c.Close > 1 {
+		return fmt.Errorf("invalid --close %g (want a similarity above 0, up to 1)", c.Close)
+	}
+	tol, err := parseTolerance(c.Ignore)
+	if err != nil {
+		return err
+	}
+	if c.NewFirst && c.DueFirst {
+		return fmt.Errorf("--new-first and --due-first are mutually exclusive")
+	}
+	stateFile, err := quizStateFile(c.State)
+	if err != nil {
+		return err
+	}
+	state, err := loadQuizState(stateFile)
+	if err != nil {
+		return err
+	}
+	s, err := printData(c.Args.Filenames, c.Units)
+	if err != nil {
+		return err
+	}
+
+	seed := c.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	r := rand.New(rand.NewSource(seed))
+	items := quizItems(s, c.Mode, c.Direction, r)
+	if len(items) == 0 {
+		return fmt.Errorf("nothing to quiz for --mode %s (give vocab datasets for vocab, or pp datasets for pp)", c.Mode)
+	}
+	if c.Shuffle {
+		r.Shuffle(len(items), func(i, j int) {
+			items[i], items[j] = items[j], items[i]
+		})
+	}
+	switch {
+	case c.NewFirst:
+		state.newFirst(items)
+	case c.DueFirst:
+		state.dueFirst(items)
+	}
+	if c.Limit > 0 && c.Limit < len(items) {
+		items = items[:c.Limit]
+	}
+
+	q := &quiz{in: bufio.NewScanner(os.Stdin), out: os.Stdout, tol: tol,
+		close: c.Close, partial: c.Partial, state: state}
+	stats := q.run(items)
+	if stats.asked == 0 {
+		return nil
+	}
+	fmt.Fprintf(q.out, "\n%s\n", stats.summary(c.Partial))
+	return state.save(stateFile)
+}
@@ -0,0 +1,182 @@
+// mag quiz: drills vocab and principal parts from the mag serve JSON API
+"use strict";
+
+const $ = (selector) => document.querySelector(selector);
+
+// The drill state: the cards left, the current card, and the results
+let deck = [];
+let current = null;
+let missed = new Set();
+let total = 0;
+
+// getJSON fetches url, returning its JSON, or throwing its error message
+async function getJSON(url) {
+  const resp = await fetch(url);
+  const data = await resp.json();
+  if (!resp.ok) {
+    throw new Error(data.error || resp.statusText);
+  }
+  return data;
+}
+
+function showError(err) {
+  $("#error").textContent = String(err.message || err);
+  $("#error").hidden = false;
+}
+
+function addOption(select, value, label) {
+  const option = document.createElement("option");
+  option.value = value;
+  option.textContent = label;
+  select.append(option);
+}
+
+function shuffle(a) {
+  for (let i = a.length - 1; i > 0; i--) {
+    const j = Math.floor(Math.random() * (i + 1));
+    [a[i], a[j]] = [a[j], a[i]];
+  }
+  return a;
+}
+
+// vocabCard returns the card for vocab word w in mode gr-en or en-gr
+function vocabCard(w, mode) {
+  const gr = w.gr_ext ? `${w.gr}, ${w.gr_ext}` : w.gr;
+  const note = w.cog ? `cf. ${w.cog}` : "";
+  if (mode === "en-gr") {
+    return { prompt: w.en, promptLang: "en", answer: gr, answerLang: "grc", note };
+  }
+  return { prompt: gr, promptLang: "grc", answer: w.en, answerLang: "en", note };
+}
+
+// ppCard returns the card for principal parts verb v
+function ppCard(v) {
+  const parts = [v.pr, v.fu, v.ao, v.pf, v.pm, v.ap].map((p) => p || "—");
+  return {
+    prompt: v.pr, promptLang: "grc",
+    answer: parts.join(", "), answerLang: "grc",
+    note: v.vclass ? `class: ${v.vclass}` : "",
+  };
+}
+
+async function start(event) {
+  event.preventDefault();
+  $("#error").hidden = true;
+  const mode = $("#mode").value;
+  const params = new URLSearchParams();
+  if ($("#unit").value) {
+    params.set("unit", $("#unit").value);
+  }
+  try {
+    if (mode === "pp") {
+      const verbs = await getJSON(`/pp?${params}`);
+      deck = verbs.map(ppCard);
+    } else {
+      if ($("#pos").value) {
+        params.set("pos", $("#pos").value);
+      }
+      const words = await getJSON(`/vocab?${params}`);
+      deck = words.map((w) => vocabCard(w, mode));
+    }
+  } catch (err) {
+    showError(err);
+    return;
+  }
+  if (deck.length === 0) {
+    showError(new Error("No cards match those filters"));
+    return;
+  }
+  shuffle(deck);
+  missed = new Set();
+  total = deck.length;
+  $("#done").hidden = true;
+  $("#card").hidden = false;
+  next();
+}
+
+// next shows the next card, or the summary when the deck is done
+function next() {
+  current = deck.shift();
+  if (!current) {
+    $("#card").hidden = true;
+    $("#done").hidden = false;
+    const right = total - missed.size;
+    $("#summary").textContent = `Done! ${right} of ${total} right first time.`;
+    return;
+  }
+  $("#progress").textContent = `${deck.length + 1} left · ${missed.size} missed`;
+  $("#prompt").textContent = current.prompt;
+  $("#prompt").lang = current.promptLang;
+  $("#answer").textContent = current.answer;
+  $("#answer").lang = current.answerLang;
+  $("#note").textContent = current.note;
+  $("#answer").hidden = true;
+  $("#note").hidden = true;
+  $("#reveal").hidden = false;
+  $("#again").hidden = true;
+  $("#good").hidden = true;
+}
+
+function reveal() {
+  if (!current || !$("#answer").hidden) {
+    return;
+  }
+  $("#answer").hidden = false;
+  $("#note").hidden = !current.note;
+  $("#reveal").hidden = true;
+  $("#again").hidden = false;
+  $("#good").hidden = false;
+}
+
+// grade records the answer to the current card, repeating missed cards
+// at the end of the deck
+function grade(good) {
+  if (!current || $("#answer").hidden) {
+    return;
+  }
+  if (!good) {
+    missed.add(current);
+    deck.push(current);
+  }
+  next();
+}
+
+async function init() {
+  $("#setup").addEventListener("submit", start);
+  $("#reveal").addEventListener("click", reveal);
+  $("#again").addEventListener("click", () => grade(false));
+  $("#good").addEventListener("click", () => grade(true));
+  $("#restart").addEventListener("click", () => $("#setup").requestSubmit());
+  $("#mode").addEventListener("change", () => {
+    $("#pos").disabled = $("#mode").value === "pp";
+  });
+  document.addEventListener("keydown", (event) => {
+    if ($("#card").hidden || event.target.closest("select")) {
+      return;
+    }
+    if (event.key === " " || event.key === "Enter") {
+      event.preventDefault();
+      reveal();
+    } else if (event.key === "1") {
+      grade(false);
+    } else if (event.key === "2") {
+      grade(true);
+    }
+  });
+
+  try {
+    for (const u of await getJSON("/units")) {
+      const name = u.name || `Unit ${u.unit}`;
+      addOption($("#unit"), u.unit || u.name, `${name} (${u.vocab} words, ${u.pp} verbs)`);
+    }
+    const vocab = await getJSON("/vocab");
+    const pos = [...new Set(vocab.map((w) => w.pos).filter(Boolean))].sort();
+    for (const p of pos) {
+      addOption($("#pos"), p, p);
+    }
+  } catch (err) {
+    showError(err);
+  }
+}
+
+init();
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestPPGrade(t *testing.T) {
+	q := &quiz{close: 0.7}
+	tests := []struct {
+		part, typed string
+		correct     bool
+	}{
+		{"ἤνεγκα or ἤνεγκον", "ἤνεγκα", true},
+		{"ἤνεγκα or ἤνεγκον", "ἤνεγκον", true},
+		{"ἤνεγκα or ἤνεγκον", "ἤνεγκα or ἤνεγκον", true},
+		{"ἤνεγκα or ἤνεγκον", "ἤνεγκε", false},
+		{"εἶμι (stem ἰ-)", "εἶμι", true},
+		{"εἶμι (stem ἰ-)", "εἶμι (stem ἰ-)", false},
+		{"(ἔφθην or ἔφθασα)", "ἔφθασα", true},
+		{"ἔβην or ἔβησα (rare)", "ἔβησα", true},
+	}
+
+	for _, tc := range tests {
+		item := quizItem{answer: tc.part, accept: ppAccepted(tc.part), greek: true}
+		result := q.grade(item, tc.typed)
+		if result.correct != tc.correct {
+			t.Errorf("%q for %q: got correct %v, want %v (%s)",
+				tc.typed, tc.part, result.correct, tc.correct, result.feedback)
+		}
+	}
+}
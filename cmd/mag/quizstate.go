@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// quizStateVersion is the version of the quiz state file format
+const quizStateVersion = 1
+
+// Card scheduling, after SM-2: a card answered correctly streak times in
+// a row is next due ease^(streak-1) days after it was last seen, and a
+// wrong answer makes it due again at once
+const (
+	quizDefaultEase    = 2.5
+	quizMinEase        = 1.3
+	quizMasteredStreak = 3 // streak at which a card counts as mastered
+)
+
+// quizCard is the quiz history of a card
+type quizCard struct {
+	LastSeen time.Time `json:"last_seen"`
+	Correct  bool      `json:"correct"` // whether the last answer was correct
+	Ease     float64   `json:"ease,omitempty"`
+	Streak   int       `json:"streak"`  // correct answers in a row
+	Reviews  int       `json:"reviews"` // answers in all
+}
+
+// ease returns the ease of c, defaulting for cards recorded before ease
+// was tracked
+func (c *quizCard) ease() float64 {
+	if c.Ease == 0 {
+		return quizDefaultEase
+	}
+	return c.Ease
+}
+
+// due returns when c is next due for review
+func (c *quizCard) due() time.Time {
+	if c.Streak == 0 {
+		return c.LastSeen
+	}
+	days := math.Pow(c.ease(), float64(c.Streak-1))
+	return c.LastSeen.Add(time.Duration(days * float64(24*time.Hour)))
+}
+
+// mastered reports whether c counts as mastered
+func (c *quizCard) mastered() bool {
+	return c.Streak >= quizMasteredStreak
+}
+
+// quizState is the quiz history of the cards, by card id, as stored in
+// the quiz state file
+type quizState struct {
+	Version int                  `json:"version"`
+	Cards   map[string]*quizCard `json:"cards"`
+}
+
+// defaultQuizStateFile returns the default quiz state file: mag/quiz.json
+// in $XDG_STATE_HOME, or ~/.local/state
+func defaultQuizStateFile() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "mag", "quiz.json"), nil
+}
+
+// quizStateFile returns the --state file, or the default
+func quizStateFile(state string) (string, error) {
+	if state != "" {
+		return state, nil
+	}
+	return defaultQuizStateFile()
+}
+
+// loadQuizState loads the quiz state file filename, or returns an empty
+// state if it doesn't exist yet
+func loadQuizState(filename string) (*quizState, error) {
+	st := &quizState{Version: quizStateVersion, Cards: make(map[string]*quizCard)}
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, st)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if st.Version > quizStateVersion {
+		return nil, fmt.Errorf("%s: unsupported quiz state version %d (upgrade mag)", filename, st.Version)
+	}
+	if st.Cards == nil {
+		st.Cards = make(map[string]*quizCard)
+	}
+	return st, nil
+}
+
+// save writes the state to filename, atomically
+func (st *quizState) save(filename string) error {
+	err := os.MkdirAll(filepath.Dir(filename), 0o755)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(append(data, '\n'))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// record records an answer to card id at t, correct or not, and whether
+// hints were used. Correct answers make the card easier, unless hinted,
+// and wrong answers harder.
+func (st *quizState) record(id string, correct, hinted bool, t time.Time) {
+	c := st.Cards[id]
+	if c == nil {
+		c = &quizCard{}
+		st.Cards[id] = c
+	}
+	ease := c.ease()
+	switch {
+	case !correct:
+		ease -= 0.2
+		c.Streak = 0
+	case hinted:
+		ease -= 0.15
+		c.Streak++
+	default:
+		ease += 0.1
+		c.Streak++
+	}
+	c.Ease = math.Max(quizMinEase, math.Round(ease*100)/100)
+	c.LastSeen, c.Correct = t.UTC(), correct
+	c.Reviews++
+}
+
+// newFirst sorts items with the cards never quizzed first, keeping their
+// order otherwise
+func (st *quizState) newFirst(items []quizItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return st.Cards[items[i].id] == nil && st.Cards[items[j].id] != nil
+	})
+}
+
+// dueFirst sorts items with the cards quizzed before first, in the order
+// they are due for review, then the cards never quizzed
+func (st *quizState) dueFirst(items []quizItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := st.Cards[items[i].id], st.Cards[items[j].id]
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return a.due().Before(b.due())
+	})
+}
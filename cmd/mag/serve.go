@@ -0,0 +1,334 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/gavincarr/mag/dataset"
+	"github.com/gavincarr/mag/greek"
+	"github.com/gavincarr/mag/lint"
+)
+
+//go:embed quiz
+var quizFS embed.FS
+
+// ServeCommand serves the datasets as read-only JSON over HTTP, with a
+// browser quiz app
+type ServeCommand struct {
+	Addr string `short:"a" long:"addr" env:"MAG_SERVE_ADDR" default:"localhost:8080" description:"address to listen on"`
+	Args struct {
+		Filenames []string `positional-arg-name:"filename" required:"1" description:"vocab and pp yml datasets or directories to serve"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	_, err := parser.AddCommand("serve",
+		"Serve datasets as JSON",
+		"Serve vocab.yml/pp.yml datasets over HTTP as read-only JSON: /units (units, with their word and verb counts), /vocab?unit=N&pos=P (vocab words, optionally by unit number or name and pos), /pp?unit=N (principal parts verbs, optionally by unit), /pp/VERB (a verb's principal parts, by its present, ignoring accents), and /search?q=TEXT (vocab words and verbs matching Greek text, ignoring accents, or English). The root URL serves a browser quiz app drilling the vocab and principal parts, with unit and pos filters.",
+		&ServeCommand{})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// serveUnit is a dataset unit, as served by /units
+type serveUnit struct {
+	Name  string `json:"name,omitempty"`
+	Unit  int    `json:"unit,omitempty"`
+	Vocab int    `json:"vocab"` // number of vocab words
+	PP    int    `json:"pp"`    // number of principal parts verbs
+
+	// Bundle files of the unit's words and verbs (see mag bundle)
+	VocabFile string `json:"vocab_file,omitempty"`
+	PPFile    string `json:"pp_file,omitempty"`
+}
+
+// serveWord is a vocab word, as served by /vocab and /search
+type serveWord struct {
+	Gr       string `json:"gr"`
+	GrMP     string `json:"gr_mp,omitempty"`
+	GrPl     string `json:"gr_pl,omitempty"`
+	GrExt    string `json:"gr_ext,omitempty"`
+	Id       string `json:"id,omitempty"`
+	Homonym  string `json:"homonym,omitempty"`
+	En       string `json:"en"`
+	Cog      string `json:"cog,omitempty"`
+	Pos      string `json:"pos,omitempty"`
+	VClass   string `json:"vclass,omitempty"`
+	Img      string `json:"img,omitempty"`
+	UnitName string `json:"unit_name,omitempty"`
+	Unit     int    `json:"unit,omitempty"`
+}
+
+// serveVerb is a principal parts verb, as served by /pp and /search
+type serveVerb struct {
+	Pr       string            `json:"pr"`
+	Fu       string            `json:"fu,omitempty"`
+	Ao       string            `json:"ao,omitempty"`
+	Pf       string            `json:"pf,omitempty"`
+	Pm       string            `json:"pm,omitempty"`
+	Ap       string            `json:"ap,omitempty"`
+	VClass   string            `json:"vclass,omitempty"`
+	Stems    map[string]string `json:"stems,omitempty"`
+	UnitName string            `json:"unit_name,omitempty"`
+	Unit     int               `json:"unit,omitempty"`
+}
+
+// greek returns the Greek text of v, for searching
+func (v serveVerb) greek() string {
+	return strings.Join(strings.Fields(strings.Join([]string{v.Pr, v.Fu, v.Ao, v.Pf, v.Pm, v.Ap}, " ")), " ")
+}
+
+// server holds the datasets served
+type server struct {
+	units []*serveUnit
+	vocab []serveWord
+	pp    []serveVerb
+}
+
+// unit returns the served unit with name and number, adding it if new
+func (s *server) unit(name string, number int) *serveUnit {
+	for _, u := range s.units {
+		if u.Name == name && u.Unit == number {
+			return u
+		}
+	}
+	u := &serveUnit{Name: name, Unit: number}
+	s.units = append(s.units, u)
+	return u
+}
+
+// load adds the vocab or pp dataset f to s, skipping other datasets
+func (s *server) load(f lintFile) error {
+	var doc yaml.Node
+	err := yaml.Unmarshal(f.data, &doc)
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.name, err)
+	}
+	switch dataset.DetectSchema(&doc) {
+	case "vocab":
+		units, _, err := lint.ParseVocab(f.data, f.name, 0)
+		if err != nil {
+			return err
+		}
+		for _, u := range units {
+			s.unit(u.Name, u.Unit).Vocab += len(u.Vocab)
+			for _, w := range u.Vocab {
+				s.vocab = append(s.vocab, serveWord{
+					Gr: w.Gr, GrMP: w.GrMP, GrPl: w.GrPl, GrExt: w.GrExt,
+					Id: w.Id, Homonym: w.Homonym, En: w.En, Cog: w.Cog,
+					Pos: w.Pos, VClass: w.VClass, Img: w.Img,
+					UnitName: u.Name, Unit: u.Unit,
+				})
+			}
+		}
+	case "pp":
+		units, _, err := lint.ParsePP(f.data, f.name, 0)
+		if err != nil {
+			return err
+		}
+		for _, u := range units {
+			s.unit(u.Name, u.Unit).PP += len(u.PP)
+			for _, r := range u.PP {
+				s.pp = append(s.pp, serveVerb{
+					Pr: r.Pr, Fu: r.Fu, Ao: r.Ao, Pf: r.Pf, Pm: r.Pm, Ap: r.Ap,
+					VClass: r.VClass, Stems: r.Stems,
+					UnitName: u.Name, Unit: u.Unit,
+				})
+			}
+		}
+	default:
+		slog.Debug("skipping non-vocab/pp file", "file", f.name)
+	}
+	return nil
+}
+
+// writeJSON writes v to w as JSON with the HTTP status
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(v)
+	if err != nil {
+		slog.Warn("writing response", "error", err)
+	}
+}
+
+// writeError writes an error message to w as JSON with the HTTP status
+func writeError(w http.ResponseWriter, status int, format string, args ...any) {
+	writeJSON(w, status, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+// matchUnit reports whether a word from the unit name/number matches
+// the unit query parameter q (a unit number or name, or "" for all)
+func matchUnit(q, name string, number int) bool {
+	if q == "" {
+		return true
+	}
+	if n, err := strconv.Atoi(q); err == nil {
+		return n == number
+	}
+	return strings.EqualFold(q, name)
+}
+
+func (s *server) handleUnits(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.units)
+}
+
+func (s *server) handleVocab(w http.ResponseWriter, r *http.Request) {
+	unit, pos := r.URL.Query().Get("unit"), r.URL.Query().Get("pos")
+	words := []serveWord{}
+	for _, word := range s.vocab {
+		if matchUnit(unit, word.UnitName, word.Unit) && (pos == "" || word.Pos == pos) {
+			words = append(words, word)
+		}
+	}
+	writeJSON(w, http.StatusOK, words)
+}
+
+func (s *server) handlePPList(w http.ResponseWriter, r *http.Request) {
+	unit := r.URL.Query().Get("unit")
+	verbs := []serveVerb{}
+	for _, v := range s.pp {
+		if matchUnit(unit, v.UnitName, v.Unit) {
+			verbs = append(verbs, v)
+		}
+	}
+	writeJSON(w, http.StatusOK, verbs)
+}
+
+func (s *server) handlePP(w http.ResponseWriter, r *http.Request) {
+	key := greek.StripKey(strings.TrimPrefix(r.URL.Path, "/pp/"))
+	if key == "" {
+		s.handlePPList(w, r)
+		return
+	}
+	verbs := []serveVerb{}
+	for _, v := range s.pp {
+		words := strings.Fields(v.Pr)
+		if len(words) > 0 && greek.StripKey(words[0]) == key {
+			verbs = append(verbs, v)
+		}
+	}
+	if len(verbs) == 0 {
+		writeError(w, http.StatusNotFound, "verb %q not found", strings.TrimPrefix(r.URL.Path, "/pp/"))
+		return
+	}
+	writeJSON(w, http.StatusOK, verbs)
+}
+
+func (s *server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		writeError(w, http.StatusBadRequest, "no search text given (use /search?q=TEXT)")
+		return
+	}
+	// Greek text is matched ignoring diacritics, English ignoring case
+	isGreek := strings.IndexFunc(q, greek.IsGreekLetter) >= 0
+	matches := func(gr, en string) bool {
+		if isGreek {
+			return strings.Contains(greek.StripKey(gr), greek.StripKey(q))
+		}
+		return strings.Contains(strings.ToLower(en), strings.ToLower(q))
+	}
+	result := struct {
+		Vocab []serveWord `json:"vocab"`
+		PP    []serveVerb `json:"pp"`
+	}{Vocab: []serveWord{}, PP: []serveVerb{}}
+	for _, word := range s.vocab {
+		if matches(strings.Join([]string{word.Gr, word.GrMP, word.GrPl, word.GrExt}, " "), word.En+" "+word.Cog) {
+			result.Vocab = append(result.Vocab, word)
+		}
+	}
+	for _, v := range s.pp {
+		if matches(v.greek(), "") {
+			result.PP = append(result.PP, v)
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// readOnly restricts h to GET and HEAD requests, logging them
+func readOnly(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slog.Debug("request", "method", r.Method, "url", r.URL.String(), "remote", r.RemoteAddr)
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// handler returns the HTTP handler for the endpoints of s
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/units", readOnly(s.handleUnits))
+	mux.HandleFunc("/vocab", readOnly(s.handleVocab))
+	mux.HandleFunc("/pp", readOnly(s.handlePPList))
+	mux.HandleFunc("/pp/", readOnly(s.handlePP))
+	mux.HandleFunc("/search", readOnly(s.handleSearch))
+
+	// Everything else is the quiz app, or an unknown endpoint
+	quiz, err := fs.Sub(quizFS, "quiz")
+	if err != nil {
+		panic(err)
+	}
+	files := http.FileServer(http.FS(quiz))
+	mux.HandleFunc("/", readOnly(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		if _, err := fs.Stat(quiz, name); name != "" && err != nil {
+			writeError(w, http.StatusNotFound, "no such endpoint %q (try /units, /vocab, /pp, /pp/VERB, or /search?q=TEXT)", r.URL.Path)
+			return
+		}
+		files.ServeHTTP(w, r)
+	}))
+	return mux
+}
+
+// loadServer returns a server for the vocab and pp datasets at paths
+func loadServer(paths []string) (*server, error) {
+	files, err := pathFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+	s := &server{}
+	for _, f := range files {
+		err = s.load(f)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(s.vocab) == 0 && len(s.pp) == 0 {
+		return nil, fmt.Errorf("no vocab or pp datasets found")
+	}
+	return s, nil
+}
+
+func (c *ServeCommand) Execute(args []string) error {
+	s, err := loadServer(c.Args.Filenames)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Addr:              c.Addr,
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	slog.Info("serving datasets", "quiz", "http://"+c.Addr+"/", "units", len(s.units),
+		"vocab", len(s.vocab), "pp", len(s.pp))
+	return srv.ListenAndServe()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/gavincarr/mag/dataset"
+)
+
+// ValidateCommand checks datasets against their JSON Schemas
+type ValidateCommand struct {
+	Schema     string `short:"s" long:"schema" choice:"vocab" choice:"pp" choice:"paradigms" choice:"sentences" choice:"exercises" description:"schema to validate against (default: detect from content)"`
+	DumpSchema bool   `long:"dump-schema" description:"print the selected JSON Schema and exit"`
+	Args       struct {
+		Filenames []string `positional-arg-name:"filename" description:"yml datasets to validate (- for stdin)"`
+	} `positional-args:"yes"`
+}
+
+func init() {
+	_, err := parser.AddCommand("validate",
+		"Validate datasets against their schemas",
+		"Validate mag yml datasets (vocab, pp, paradigms, sentences, exercises) against their JSON Schemas, reporting unknown keys, wrong types, and missing required fields",
+		&ValidateCommand{})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (c *ValidateCommand) Execute(args []string) error {
+	if c.DumpSchema {
+		if c.Schema == "" {
+			return fmt.Errorf("--dump-schema requires --schema")
+		}
+		data, err := dataset.SchemaJSON(c.Schema)
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write(data)
+		return nil
+	}
+	if len(c.Args.Filenames) == 0 {
+		return fmt.Errorf("no datasets specified")
+	}
+
+	errors := 0
+	for _, filename := range c.Args.Filenames {
+		data, err := dataset.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		var doc yaml.Node
+		err = yaml.Unmarshal(data, &doc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+
+		name := c.Schema
+		if name == "" {
+			name = dataset.DetectSchema(&doc)
+			if name == "" {
+				return fmt.Errorf("%s: cannot detect dataset type (use --schema)",
+					filename)
+			}
+		}
+		schema, err := dataset.LoadSchema(name)
+		if err != nil {
+			return err
+		}
+
+		version, err := dataset.Version(&doc)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		if version < dataset.CurrentVersion {
+			slog.Warn("outdated dataset version (use 'mag migrate' to upgrade)",
+				"file", filename, "version", version)
+		}
+
+		verrs := schema.Validate(&doc)
+		for _, verr := range verrs {
+			fmt.Printf("%s:%s\n", filename, verr.Error())
+		}
+		errors += len(verrs)
+		if len(verrs) == 0 {
+			slog.Debug("valid dataset", "file", filename, "schema", name)
+		}
+	}
+
+	if errors > 0 {
+		return fmt.Errorf("%d schema error(s) found", errors)
+	}
+	return nil
+}
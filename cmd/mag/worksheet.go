@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gavincarr/mag/pdf"
+)
+
+const (
+	exerciseGloss = "gloss"
+	exercisePP    = "pp"
+	worksheetPDF  = "pdf"
+	worksheetMD   = "markdown"
+	worksheetSize = 11 // worksheet text size, in points
+)
+
+// ppPartNames are the principal parts, by --parts name
+var ppPartNames = []string{"pr", "fu", "ao", "pf", "pm", "ap"}
+
+// ppPartLabels are the worksheet column labels of the principal parts
+var ppPartLabels = []string{"Present", "Future", "Aorist", "Perfect", "Perfect Middle", "Aorist Passive"}
+
+// PrintWorksheetCommand prints fill-in-the-blank worksheets
+type PrintWorksheetCommand struct {
+	PrintOptions
+	Exercise string `short:"e" long:"exercise" choice:"gloss" choice:"pp" default:"gloss" description:"what students supply (gloss: the English glosses of Greek words; pp: the missing principal parts of verbs)"`
+	Parts    string `long:"parts" default:"fu,ao,pf,pm,ap" description:"principal parts to blank in --exercise pp, as a comma-separated list of pr, fu, ao, pf, pm, and ap"`
+	Format   string `short:"f" long:"format" choice:"pdf" choice:"markdown" description:"output format (default: markdown if --outfile ends in .md, else pdf)"`
+	Answers  string `long:"answers" description:"answer key file to write (default: --outfile with -answers before its extension)"`
+	Count    int    `short:"c" long:"count" description:"include only this many questions"`
+	Shuffle  bool   `long:"shuffle" description:"shuffle the questions, instead of keeping dataset order"`
+	Seed     int64  `long:"seed" description:"random seed for --shuffle, to reproduce a worksheet (default: the current time)"`
+	Title    string `long:"title" description:"worksheet title (default: from --exercise and --units)"`
+	Args     struct {
+		Filenames []string `positional-arg-name:"filename" required:"1" description:"vocab (for --exercise gloss) or pp (for --exercise pp) yml datasets or directories"`
+	} `positional-args:"yes"`
+}
+
+// worksheetCell is a worksheet table cell: text, or a blank for students
+// to fill in, with its answer
+type worksheetCell struct {
+	text  string
+	blank bool
+}
+
+// worksheet is a fill-in-the-blank worksheet: a table of questions, with
+// blanks in some cells
+type worksheet struct {
+	title   string
+	columns []string
+	rows    [][]worksheetCell
+}
+
+// parseParts returns the indexes of the principal parts in the --parts
+// list s
+func parseParts(s string) (map[int]bool, error) {
+	parts := make(map[int]bool)
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		i := slices.Index(ppPartNames, name)
+		if i < 0 {
+			return nil, fmt.Errorf("invalid --parts %q (want a list of %s)", name, strings.Join(ppPartNames, ", "))
+		}
+		parts[i] = true
+	}
+	if len(parts) == len(ppPartNames) {
+		return nil, fmt.Errorf("invalid --parts %q (leave at least one part to give as the question)", s)
+	}
+	return parts, nil
+}
+
+// build returns the worksheet for the words or verbs in s
+func (c *PrintWorksheetCommand) build(s *server) (*worksheet, error) {
+	ws := &worksheet{title: c.Title}
+	switch c.Exercise {
+	case exerciseGloss:
+		if len(s.vocab) == 0 {
+			return nil, fmt.Errorf("no vocab words for --exercise gloss (give vocab datasets)")
+		}
+		ws.columns = []string{"Greek", "English"}
+		for _, w := range s.vocab {
+			gr := strings.TrimSpace(w.Gr + " " + w.GrExt)
+			if w.Homonym != "" {
+				gr += " [" + w.Homonym + "]"
+			}
+			ws.rows = append(ws.rows, []worksheetCell{
+				{text: gr},
+				{text: reGlossSep.ReplaceAllString(w.En, "; "), blank: true},
+			})
+		}
+		if ws.title == "" {
+			ws.title = "Vocabulary: Glosses"
+		}
+	case exercisePP:
+		if len(s.pp) == 0 {
+			return nil, fmt.Errorf("no verbs for --exercise pp (give pp datasets)")
+		}
+		blanks, err := parseParts(c.Parts)
+		if err != nil {
+			return nil, err
+		}
+		ws.columns = ppPartLabels
+		for _, v := range s.pp {
+			var row []worksheetCell
+			for i, part := range verbParts(v) {
+				// Parts the verb lacks are shown as dashes, not blanked
+				row = append(row, worksheetCell{text: part, blank: blanks[i] && part != "—"})
+			}
+			ws.rows = append(ws.rows, row)
+		}
+		if ws.title == "" {
+			ws.title = "Principal Parts"
+		}
+	}
+	if c.Title == "" {
+		ws.title = unitsTitle(ws.title, c.Units)
+	}
+
+	if c.Shuffle {
+		seed := c.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		r := rand.New(rand.NewSource(seed))
+		r.Shuffle(len(ws.rows), func(i, j int) {
+			ws.rows[i], ws.rows[j] = ws.rows[j], ws.rows[i]
+		})
+	}
+	if c.Count > 0 && c.Count < len(ws.rows) {
+		ws.rows = ws.rows[:c.Count]
+	}
+	return ws, nil
+}
+
+// unitsTitle returns title with the --units range appended, if any
+func unitsTitle(title, units string) string {
+	if units == "" {
+		return title
+	}
+	label := "Unit "
+	if strings.Contains(units, "-") {
+		label = "Units "
+	}
+	return title + " (" + label + units + ")"
+}
+
+// markdownCell escapes s for a Markdown table cell
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// markdown returns the worksheet as a Markdown document, with the
+// answers filled in (in bold) if key is set
+func (ws *worksheet) markdown(key bool) []byte {
+	var b bytes.Buffer
+	title := ws.title
+	if key {
+		title += ": Answers"
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	if !key {
+		b.WriteString("Name: ____________________\n\n")
+	}
+	fmt.Fprintf(&b, "| # | %s |\n", strings.Join(ws.columns, " | "))
+	fmt.Fprintf(&b, "|---|%s\n", strings.Repeat("---|", len(ws.columns)))
+	for i, row := range ws.rows {
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			switch {
+			case cell.blank && key:
+				cells[j] = "**" + markdownCell(cell.text) + "**"
+			case cell.blank:
+				cells[j] = "____________"
+			default:
+				cells[j] = markdownCell(cell.text)
+			}
+		}
+		fmt.Fprintf(&b, "| %d | %s |\n", i+1, strings.Join(cells, " | "))
+	}
+	return b.Bytes()
+}
+
+// pdf draws the worksheet in doc, with the answers filled in (in gray)
+// if key is set
+func (ws *worksheet) pdf(doc *pdf.Document, key bool) {
+	font := doc.Font()
+	margin := 15 * pdf.MM
+	numWidth := 10 * pdf.MM
+	colWidth := (doc.Width - 2*margin - numWidth) / float64(len(ws.columns))
+	lineHeight := worksheetSize * 1.4
+	rule := pdf.LineStyle{Width: 0.4, Gray: 0.7}
+	blank := pdf.LineStyle{Width: 0.6, Gray: 0.3}
+	pad := 2 * pdf.MM
+
+	var page *pdf.Page
+	var y float64
+	header := func() {
+		page = doc.AddPage()
+		y = doc.Height - margin
+		title := ws.title
+		if key {
+			title += ": Answers"
+		}
+		if doc.Pages() == 1 {
+			y -= 18
+			page.Text(margin, y, 16, 0, title)
+			if !key {
+				page.Text(doc.Width-margin-font.Width("Name: ____________________", worksheetSize), y,
+					worksheetSize, 0, "Name: ____________________")
+			}
+			y -= 14
+		}
+		y -= lineHeight
+		for i, col := range ws.columns {
+			page.Text(margin+numWidth+float64(i)*colWidth, y, worksheetSize-1, 0.4, col)
+		}
+		y -= pad
+		page.Line(margin, y, doc.Width-margin, y, rule)
+	}
+
+	for i, row := range ws.rows {
+		// Wrap each cell, leaving room below blanks for writing answers
+		lines := make([][]string, len(row))
+		height := 1
+		for j, cell := range row {
+			lines[j] = font.Wrap(cell.text, worksheetSize, colWidth-2*pad)
+			height = max(height, len(lines[j]))
+		}
+		rowHeight := float64(height)*lineHeight + 2*pad + lineHeight/2
+		if page == nil || y-rowHeight < margin {
+			header()
+		}
+		top := y - pad
+		page.Text(margin, top-lineHeight, worksheetSize, 0.4, strconv.Itoa(i+1)+".")
+		for j, cell := range row {
+			x := margin + numWidth + float64(j)*colWidth
+			if cell.blank && !key {
+				page.Line(x, y-rowHeight+pad*2, x+colWidth-2*pad, y-rowHeight+pad*2, blank)
+				continue
+			}
+			gray := 0.0
+			if cell.blank {
+				gray = 0.35
+			}
+			for k, line := range lines[j] {
+				page.Text(x, top-float64(k+1)*lineHeight, worksheetSize, gray, line)
+			}
+		}
+		y -= rowHeight
+		page.Line(margin, y, doc.Width-margin, y, rule)
+	}
+}
+
+// answersFilename returns the default answer key filename for outfile
+func answersFilename(outfile string) string {
+	ext := filepath.Ext(outfile)
+	return strings.TrimSuffix(outfile, ext) + "-answers" + ext
+}
+
+func (c *PrintWorksheetCommand) Execute(args []string) error {
+	format := c.Format
+	if format == "" {
+		format = worksheetPDF
+		if strings.EqualFold(filepath.Ext(c.Outfile), ".md") {
+			format = worksheetMD
+		}
+	}
+	answers := c.Answers
+	if answers == "" {
+		answers = answersFilename(c.Outfile)
+	}
+	if answers == c.Outfile {
+		return fmt.Errorf("--answers must differ from --outfile")
+	}
+
+	s, err := printData(c.Args.Filenames, c.Units)
+	if err != nil {
+		return err
+	}
+	ws, err := c.build(s)
+	if err != nil {
+		return err
+	}
+
+	if format == worksheetMD {
+		err = os.WriteFile(c.Outfile, ws.markdown(false), 0o644)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(answers, ws.markdown(true), 0o644)
+	}
+	for _, key := range []bool{false, true} {
+		doc, err := newPrintDocument(c.PrintOptions, ws.title)
+		if err != nil {
+			return err
+		}
+		// Principal parts tables need the width of landscape pages
+		if c.Exercise == exercisePP {
+			doc.Width, doc.Height = doc.Height, doc.Width
+		}
+		if !key {
+			checker := glyphChecker{font: doc.Font()}
+			for _, row := range ws.rows {
+				for _, cell := range row {
+					checker.check(cell.text)
+				}
+			}
+			checker.warn()
+		}
+		ws.pdf(doc, key)
+		filename := c.Outfile
+		if key {
+			filename = answers
+		}
+		err = writePDF(doc, filename)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package dataset
+
+import (
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// CogAddition records English derivatives added to the 'cog' field of a
+// vocab word
+type CogAddition struct {
+	Line  int
+	Gr    string
+	Old   string
+	Added []string
+	New   string
+}
+
+// SplitCog returns the comma-separated terms of a 'cog' field value
+func SplitCog(cog string) []string {
+	var terms []string
+	for _, t := range strings.Split(cog, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+// addCog returns the terms of additions missing (ignoring case) from the
+// 'cog' field value cog
+func addCog(cog string, additions []string) []string {
+	seen := make(map[string]bool)
+	for _, t := range SplitCog(cog) {
+		seen[strings.ToLower(t)] = true
+	}
+	var added []string
+	for _, t := range additions {
+		t = strings.TrimSpace(t)
+		if t == "" || seen[strings.ToLower(t)] {
+			continue
+		}
+		seen[strings.ToLower(t)] = true
+		added = append(added, t)
+	}
+	return added
+}
+
+// AddCognates adds to the 'cog' field of each word of the vocab dataset
+// in data the terms returned by cognates for its 'gr' field that it
+// doesn't already list, returning the additions made and the dataset in
+// canonical form
+func AddCognates(data []byte, cognates func(gr string) []string) ([]byte, []CogAddition, error) {
+	var doc yaml.Node
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	units, err := UnitsNode(&doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	additions := []CogAddition{}
+	if units == nil {
+		return data, additions, nil
+	}
+	for _, u := range units.Content {
+		vocab := mappingValue(u, "vocab")
+		if vocab == nil {
+			continue
+		}
+		for _, w := range vocab.Content {
+			gr := mappingValue(w, "gr")
+			if gr == nil {
+				continue
+			}
+			cog := mappingValue(w, "cog")
+			old := ""
+			if cog != nil {
+				old = cog.Value
+			}
+			added := addCog(old, cognates(gr.Value))
+			if len(added) == 0 {
+				continue
+			}
+			value := strings.Join(append(SplitCog(old), added...), ", ")
+			if cog == nil {
+				cog = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str"}
+				w.Content = append(w.Content,
+					&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "cog"}, cog)
+			}
+			cog.Value = value
+			additions = append(additions, CogAddition{
+				Line: w.Line, Gr: gr.Value, Old: old, Added: added, New: value,
+			})
+		}
+	}
+	out, err := Encode(&doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, additions, nil
+}
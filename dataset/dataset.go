@@ -0,0 +1,50 @@
+// Package dataset provides support for reading and writing the mag
+// vocab.yml, pp.yml, paradigms.yml, sentences.yml, and exercises.yml
+// datasets
+
+package dataset
+
+var (
+	// docKeys is the canonical key order for versioned dataset documents
+	docKeys = []string{"version", "units"}
+	// unitKeys is the canonical key order for unit records
+	unitKeys = []string{
+		"name", "unit", "vocab", "pp", "paradigms", "sentences", "exercises",
+	}
+	// wordKeys is the canonical key order for vocab.yml words
+	wordKeys = []string{
+		"gr", "gr_mp", "gr_pl", "gr_ext", "id", "homonym", "en", "en_ext", "cog",
+		"pos", "decl", "vclass", "smyth", "ref", "img", "fields",
+	}
+	// partsKeys is the canonical key order for pp.yml records
+	partsKeys = []string{
+		"pr", "fu", "ao", "pf", "pm", "ap", "stems", "vclass", "irregular",
+		"smyth", "ref",
+	}
+	// paradigmKeys is the canonical key order for paradigms.yml records
+	// (whose cells keep their dataset order)
+	paradigmKeys = []string{"name", "lemma", "noun", "verb", "cells", "smyth", "ref"}
+	// sentenceKeys is the canonical key order for sentences.yml records
+	sentenceKeys = []string{"gr", "en", "words", "ref"}
+	// exerciseKeys is the canonical key order for exercises.yml records
+	exerciseKeys = []string{"number", "prompt", "answer", "ref"}
+
+	// entryKeys maps unit list keys to the key order for their entries
+	entryKeys = map[string][]string{
+		"vocab":     wordKeys,
+		"pp":        partsKeys,
+		"paradigms": paradigmKeys,
+		"sentences": sentenceKeys,
+		"exercises": exerciseKeys,
+	}
+	// childKeys maps keys to the key order for the records they contain
+	childKeys = map[string][]string{
+		"units":     unitKeys,
+		"vocab":     wordKeys,
+		"pp":        partsKeys,
+		"paradigms": paradigmKeys,
+		"sentences": sentenceKeys,
+		"exercises": exerciseKeys,
+		"cells":     nil,
+	}
+)
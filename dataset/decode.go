@@ -0,0 +1,149 @@
+package dataset
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Decoder reads units from a stream of one or more YAML dataset
+// documents (separated by "---"), decoding them one unit at a time.
+// Each document is parsed whole, so only one document's node tree is
+// held in memory at a time: large datasets need splitting into several
+// documents to be read in bounded memory. A Decoder returned by
+// OpenFiles reads a sequence of dataset files in turn.
+type Decoder struct {
+	dec    *yaml.Decoder
+	units  []*yaml.Node // undecoded units from the current document
+	files  []string     // dataset files still to be read
+	file   string       // dataset file currently being read
+	closer io.Closer
+}
+
+// NewDecoder returns a new Decoder reading from r
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{dec: yaml.NewDecoder(r)}
+}
+
+// OpenFiles returns a new Decoder reading the dataset files filenames
+// in turn. The caller should call Close when done.
+func OpenFiles(filenames []string) *Decoder {
+	return &Decoder{files: filenames}
+}
+
+// Filename returns the name of the dataset file currently being read
+func (d *Decoder) Filename() string {
+	return d.file
+}
+
+// Close closes the dataset file currently being read, if any
+func (d *Decoder) Close() error {
+	if d.closer == nil {
+		return nil
+	}
+	err := d.closer.Close()
+	d.closer = nil
+	return err
+}
+
+// openNext opens the next dataset file, returning io.EOF if there are
+// no more
+func (d *Decoder) openNext() error {
+	if len(d.files) == 0 {
+		return io.EOF
+	}
+	fh, err := Open(d.files[0])
+	if err != nil {
+		return err
+	}
+	d.file = d.files[0]
+	d.files = d.files[1:]
+	d.dec = yaml.NewDecoder(fh)
+	d.closer = fh
+	return nil
+}
+
+// next returns the next unit node in the stream, or io.EOF
+func (d *Decoder) next() (*yaml.Node, error) {
+	for len(d.units) == 0 {
+		if d.dec == nil {
+			err := d.openNext()
+			if err != nil {
+				return nil, err
+			}
+		}
+		var doc yaml.Node
+		err := d.dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			d.dec = nil
+			err = d.Close()
+			if err != nil {
+				return nil, err
+			}
+			if len(d.files) == 0 {
+				return nil, io.EOF
+			}
+			continue
+		}
+		if err == nil {
+			err = d.loadUnits(&doc)
+		}
+		if err != nil {
+			if d.file != "" {
+				return nil, fmt.Errorf("%s: %w", d.file, err)
+			}
+			return nil, err
+		}
+	}
+	unit := d.units[0]
+	d.units = d.units[1:]
+	return unit, nil
+}
+
+// loadUnits sets the pending units to those in the dataset document doc
+func (d *Decoder) loadUnits(doc *yaml.Node) error {
+	unode, err := UnitsNode(doc)
+	if err != nil || unode == nil {
+		return err
+	}
+	if unode.Kind != yaml.SequenceNode {
+		return fmt.Errorf("line %d: dataset units must be a list",
+			unode.Line)
+	}
+	d.units = unode.Content
+	return nil
+}
+
+// Decode decodes the next unit in the stream into v, returning io.EOF
+// when there are no more units
+func (d *Decoder) Decode(v interface{}) error {
+	unit, err := d.next()
+	if err != nil {
+		return err
+	}
+	return unit.Decode(v)
+}
+
+// DecodeAll decodes all remaining units in the stream into units, which
+// should be a pointer to a slice of unit records
+func (d *Decoder) DecodeAll(units interface{}) error {
+	rv := reflect.ValueOf(units)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("units must be a pointer to a slice, not %T", units)
+	}
+	slice := rv.Elem()
+	for {
+		unit := reflect.New(slice.Type().Elem())
+		err := d.Decode(unit.Interface())
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		slice.Set(reflect.Append(slice, unit.Elem()))
+	}
+}
@@ -0,0 +1,47 @@
+package dataset
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EntryError is an error in an individual dataset entry. Exporters
+// skip such entries and report them at the end, rather than aborting.
+type EntryError struct {
+	File  string // dataset filename
+	Line  int    // line number in File, if known
+	Unit  string // unit name
+	Entry string // entry headword
+	Err   error
+}
+
+func (e *EntryError) Error() string {
+	msg := fmt.Sprintf("unit %q, entry %q: %s", e.Unit, e.Entry, e.Err)
+	if e.File != "" && e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, msg)
+	}
+	return msg
+}
+
+func (e *EntryError) Unwrap() error {
+	return e.Err
+}
+
+// Errors is a list of errors collected while processing a dataset
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Err returns e as an error, or nil if e is empty
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,179 @@
+package dataset
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/gavincarr/mag/greek"
+)
+
+var (
+	reSpacePunct = regexp.MustCompile(`\pZ+([,;])`)
+
+	// GreekFields are the fields expected to contain Greek text
+	GreekFields = map[string]bool{
+		"gr": true, "gr_mp": true, "gr_pl": true, "gr_ext": true,
+		"pr": true, "fu": true, "ao": true, "pf": true, "pm": true, "ap": true,
+	}
+)
+
+// Fix records a mechanical fix applied to a dataset value
+type Fix struct {
+	Line  int
+	Field string
+	Old   string
+	New   string
+}
+
+// FixValue returns value with mechanical fixes applied: whitespace is
+// trimmed and collapsed (and removed before commas and semicolons) and
+// the text NFC-normalised, and for Greek
+// fields, Latin homoglyphs inside Greek words are replaced and final
+// sigmas corrected
+func FixValue(field, value string) string {
+	v := strings.Join(strings.Fields(norm.NFC.String(value)), " ")
+	v = reSpacePunct.ReplaceAllString(v, "$1")
+	if GreekFields[field] {
+		v = greek.FixLatinHomoglyphs(v)
+		v = greek.FixFinalSigma(v)
+	}
+	return v
+}
+
+// fixedNode is a scalar node with its fixed value
+type fixedNode struct {
+	node  *yaml.Node
+	field string
+	value string
+	flow  bool // in a flow collection
+}
+
+// fixNode applies FixValue to all string values in mappings under n,
+// adding the nodes it changes to fixed
+func fixNode(n *yaml.Node, flow bool, fixed *[]fixedNode) {
+	flow = flow || n.Style&yaml.FlowStyle != 0
+	switch n.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, c := range n.Content {
+			fixNode(c, flow, fixed)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if value.Kind != yaml.ScalarNode {
+				fixNode(value, flow, fixed)
+				continue
+			}
+			if value.Tag != "!!str" {
+				continue
+			}
+			v := FixValue(key.Value, value.Value)
+			if v != value.Value {
+				*fixed = append(*fixed, fixedNode{value, key.Value, v, flow})
+			}
+		}
+	}
+}
+
+// scalarEnd returns the index in line just after the source text of the
+// single-line scalar n starting at line[start], or -1 if n isn't a
+// single-line flow scalar
+func scalarEnd(line []rune, start int, n *yaml.Node) int {
+	switch n.Style {
+	case 0:
+		value := []rune(n.Value)
+		end := start + len(value)
+		if end > len(line) || string(line[start:end]) != n.Value {
+			return -1
+		}
+		return end
+	case yaml.DoubleQuotedStyle, yaml.SingleQuotedStyle:
+		quote := line[start]
+		for i := start + 1; i < len(line); i++ {
+			switch {
+			case quote == '"' && line[i] == '\\':
+				i++
+			case line[i] != quote:
+			case quote == '\'' && i+1 < len(line) && line[i+1] == '\'':
+				i++
+			default:
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// formatFixed returns the source text for the fixed value of f, in the
+// style of the original, or false if it can't be written on one line
+func formatFixed(f fixedNode) (string, bool) {
+	n := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: f.value, Style: f.node.Style}
+	if n.Style == 0 && f.flow && strings.ContainsAny(f.value, ",[]{}") {
+		n.Style = yaml.DoubleQuotedStyle
+	}
+	out, err := yaml.Marshal(n)
+	if err != nil {
+		return "", false
+	}
+	text := strings.TrimSuffix(string(out), "\n")
+	return text, !strings.Contains(text, "\n")
+}
+
+// FixData applies mechanical fixes (see FixValue) to the YAML dataset in
+// data, returning the fixes made and the fixed dataset. Only the fixed
+// values are rewritten, leaving the rest of the dataset (including its
+// layout and comments) as is. Values in block scalars or spanning
+// several lines aren't fixed.
+func FixData(data []byte) ([]byte, []Fix, error) {
+	var doc yaml.Node
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	var fixed []fixedNode
+	fixNode(&doc, false, &fixed)
+
+	// Fix the values on each line from the right, so earlier columns
+	// stay valid
+	sort.SliceStable(fixed, func(i, j int) bool {
+		if fixed[i].node.Line != fixed[j].node.Line {
+			return fixed[i].node.Line < fixed[j].node.Line
+		}
+		return fixed[i].node.Column > fixed[j].node.Column
+	})
+	lines := strings.SplitAfter(string(data), "\n")
+	fixes := []Fix{}
+	for _, f := range fixed {
+		idx, start := f.node.Line-1, f.node.Column-1
+		if idx >= len(lines) {
+			continue
+		}
+		line := []rune(lines[idx])
+		if start >= len(line) {
+			continue
+		}
+		end := scalarEnd(line, start, f.node)
+		if end < 0 {
+			continue
+		}
+		text, ok := formatFixed(f)
+		if !ok {
+			continue
+		}
+		lines[idx] = string(line[:start]) + text + string(line[end:])
+		fixes = append(fixes, Fix{
+			Line:  f.node.Line,
+			Field: f.field,
+			Old:   f.node.Value,
+			New:   f.value,
+		})
+	}
+	sort.SliceStable(fixes, func(i, j int) bool {
+		return fixes[i].Line < fixes[j].Line
+	})
+	return []byte(strings.Join(lines, "")), fixes, nil
+}
@@ -0,0 +1,52 @@
+package dataset
+
+import (
+	"testing"
+)
+
+func TestFixData(t *testing.T) {
+	data := `# Unit comment
+- name: Unit 1
+  vocab:
+    - gr: λόγοσ,  -ου, ὁ   # final sigma
+      pos: noun
+      en: word
+      # foot of word
+
+    - gr: "ἄγω ,  ἄξω"
+      gr_ext: 'kαί'
+      en: lead
+    - gr: ἀγaθός, -ή, -όν
+      cog: [agathism, "kαλός"]
+      en: 'good '
+    - gr: λvω
+      en: loose
+`
+	want := `# Unit comment
+- name: Unit 1
+  vocab:
+    - gr: λόγος, -ου, ὁ   # final sigma
+      pos: noun
+      en: word
+      # foot of word
+
+    - gr: "ἄγω, ἄξω"
+      gr_ext: 'καί'
+      en: lead
+    - gr: ἀγαθός, -ή, -όν
+      cog: [agathism, "kαλός"]
+      en: 'good'
+    - gr: λvω
+      en: loose
+`
+	out, fixes, err := FixData([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != want {
+		t.Errorf("FixData: got\n%s\nwant\n%s", out, want)
+	}
+	if len(fixes) != 5 {
+		t.Errorf("FixData: got %d fixes, want 5: %v", len(fixes), fixes)
+	}
+}
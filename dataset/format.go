@@ -0,0 +1,303 @@
+package dataset
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Format parses the YAML dataset in data and returns it in canonical
+// form: keys in schema order, minimal quoting, two-space block
+// indentation with bare "-" list item lines, and NFC-normalised text.
+// Comments are preserved, with comments at the foot of a record kept at
+// its foot and followed by a blank line. Format(Format(data)) is
+// Format(data).
+func Format(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+	return Encode(&doc)
+}
+
+// Encode returns the YAML dataset document doc in canonical form
+// (see Format). doc is canonicalised in place.
+func Encode(doc *yaml.Node) ([]byte, error) {
+	if doc.Kind == 0 {
+		// Empty document
+		return []byte{}, nil
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 {
+		return nil, errors.New("unexpected YAML document structure")
+	}
+
+	root := doc.Content[0]
+	if root.Kind == yaml.MappingNode {
+		canonicaliseNode(root, docKeys)
+	} else {
+		canonicaliseNode(root, unitKeys)
+	}
+
+	var e emitter
+	e.comment(doc.HeadComment, 0)
+	err := e.node(root, 0)
+	if err != nil {
+		return nil, err
+	}
+	e.comment(doc.FootComment, 0)
+	// Foot comments at the end of the file need no separating blank
+	// line, since they reparse as the document's foot comment
+	out := strings.TrimRight(e.b.String(), "\n") + "\n"
+	return []byte(out), nil
+}
+
+// keyRank returns the position of key in order, with unknown keys
+// ranked after all known ones
+func keyRank(order []string, key string) int {
+	for i, k := range order {
+		if k == key {
+			return i
+		}
+	}
+	return len(order)
+}
+
+// canonicaliseNode NFC-normalises all scalars under n, and sorts
+// mapping keys using order (and the childKeys order for nested records)
+func canonicaliseNode(n *yaml.Node, order []string) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		n.Value = norm.NFC.String(n.Value)
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			canonicaliseNode(c, order)
+		}
+	case yaml.MappingNode:
+		type pair struct {
+			key, value *yaml.Node
+		}
+		pairs := make([]pair, 0, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			pairs = append(pairs, pair{n.Content[i], n.Content[i+1]})
+		}
+		// yaml.v3 attaches a comment at the foot of a mapping to its
+		// last key, so move it to the mapping itself to keep it at the
+		// foot when the keys are reordered
+		if len(pairs) > 0 {
+			last := pairs[len(pairs)-1]
+			n.FootComment = joinComments(n.FootComment,
+				last.key.FootComment, footComment(last.value))
+			last.key.FootComment = ""
+			clearFootComment(last.value)
+		}
+		sort.SliceStable(pairs, func(i, j int) bool {
+			return keyRank(order, pairs[i].key.Value) <
+				keyRank(order, pairs[j].key.Value)
+		})
+		n.Content = n.Content[:0]
+		for _, p := range pairs {
+			canonicaliseNode(p.key, order)
+			if sub, ok := childKeys[p.key.Value]; ok {
+				canonicaliseNode(p.value, sub)
+			} else {
+				canonicaliseNode(p.value, order)
+			}
+			n.Content = append(n.Content, p.key, p.value)
+		}
+	}
+}
+
+// joinComments joins the non-empty comments in cs with newlines
+func joinComments(cs ...string) string {
+	var out []string
+	for _, c := range cs {
+		if c != "" {
+			out = append(out, c)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// isInline reports whether n is emitted on the same line as its key
+func isInline(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode || len(n.Content) == 0
+}
+
+// footComment returns the foot comment of the inline node n. The foot
+// comments of block collections are emitted by the collections
+// themselves.
+func footComment(n *yaml.Node) string {
+	if !isInline(n) {
+		return ""
+	}
+	return n.FootComment
+}
+
+// clearFootComment removes the foot comment returned by footComment
+func clearFootComment(n *yaml.Node) {
+	if isInline(n) {
+		n.FootComment = ""
+	}
+}
+
+// emitter writes yaml.Nodes in the canonical dataset layout
+type emitter struct {
+	b strings.Builder
+}
+
+func (e *emitter) line(indent int, s string) {
+	if s != "" {
+		e.b.WriteString(strings.Repeat(" ", indent))
+		e.b.WriteString(s)
+	}
+	e.b.WriteString("\n")
+}
+
+func (e *emitter) comment(c string, indent int) {
+	if c == "" {
+		return
+	}
+	for _, l := range strings.Split(c, "\n") {
+		e.line(indent, l)
+	}
+}
+
+// footComment writes the foot comment c followed by a blank line, which
+// yaml.v3 needs to attach c to the node that owns it rather than to the
+// most deeply nested preceding key
+func (e *emitter) footComment(c string, indent int) {
+	if c == "" {
+		return
+	}
+	e.comment(c, indent)
+	e.line(0, "")
+}
+
+// lineComment returns the trailing comments for n, if any
+func lineComment(nodes ...*yaml.Node) string {
+	for _, n := range nodes {
+		if n.LineComment != "" {
+			return " " + n.LineComment
+		}
+	}
+	return ""
+}
+
+// formatScalar returns the canonical YAML representation of scalar n
+func formatScalar(n *yaml.Node) (string, error) {
+	if n.Tag == "!!null" && n.Value == "" {
+		return "", nil
+	}
+	s := &yaml.Node{Kind: yaml.ScalarNode, Tag: n.Tag, Value: n.Value}
+	if strings.Contains(n.Value, "\n") {
+		s.Style = yaml.DoubleQuotedStyle
+	}
+	out, err := yaml.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// isEmptyCollection reports whether n is a sequence or mapping with no
+// content, returning its flow representation
+func isEmptyCollection(n *yaml.Node) (string, bool) {
+	if len(n.Content) > 0 {
+		return "", false
+	}
+	switch n.Kind {
+	case yaml.SequenceNode:
+		return "[]", true
+	case yaml.MappingNode:
+		return "{}", true
+	}
+	return "", false
+}
+
+func (e *emitter) node(n *yaml.Node, indent int) error {
+	switch n.Kind {
+	case yaml.SequenceNode:
+		return e.sequence(n, indent)
+	case yaml.MappingNode:
+		return e.mapping(n, indent)
+	case yaml.ScalarNode:
+		s, err := formatScalar(n)
+		if err != nil {
+			return err
+		}
+		e.comment(n.HeadComment, indent)
+		e.line(indent, s+lineComment(n))
+		e.footComment(n.FootComment, indent)
+		return nil
+	}
+	return fmt.Errorf("unsupported YAML node at line %d", n.Line)
+}
+
+func (e *emitter) sequence(n *yaml.Node, indent int) error {
+	e.comment(n.HeadComment, indent)
+	for _, item := range n.Content {
+		e.comment(item.HeadComment, indent)
+		if item.Kind == yaml.ScalarNode {
+			s, err := formatScalar(item)
+			if err != nil {
+				return err
+			}
+			e.line(indent, strings.TrimRight("- "+s, " ")+lineComment(item))
+		} else if flow, ok := isEmptyCollection(item); ok {
+			e.line(indent, "- "+flow+lineComment(item))
+		} else {
+			e.line(indent, "-"+lineComment(item))
+			// Item head comments have been emitted above, and foot
+			// comments are emitted by the collection itself
+			head := item.HeadComment
+			item.HeadComment = ""
+			err := e.node(item, indent+2)
+			item.HeadComment = head
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		e.footComment(item.FootComment, indent)
+	}
+	e.footComment(n.FootComment, indent)
+	return nil
+}
+
+func (e *emitter) mapping(n *yaml.Node, indent int) error {
+	e.comment(n.HeadComment, indent)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		k, err := formatScalar(key)
+		if err != nil {
+			return err
+		}
+		e.comment(key.HeadComment, indent)
+		if value.Kind == yaml.ScalarNode {
+			v, err := formatScalar(value)
+			if err != nil {
+				return err
+			}
+			e.line(indent, strings.TrimRight(k+": "+v, " ")+
+				lineComment(key, value))
+		} else if flow, ok := isEmptyCollection(value); ok {
+			e.line(indent, k+": "+flow+lineComment(key, value))
+		} else {
+			e.line(indent, k+":"+lineComment(key))
+			// Collections emit their own foot comments
+			err = e.node(value, indent+2)
+			if err != nil {
+				return err
+			}
+		}
+		e.footComment(joinComments(key.FootComment, footComment(value)),
+			indent)
+	}
+	e.footComment(n.FootComment, indent)
+	return nil
+}
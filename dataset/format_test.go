@@ -0,0 +1,113 @@
+package dataset
+
+import (
+	"testing"
+)
+
+const commentedList = `# head of file
+- name: Unit 1
+  vocab:
+    # head of first word
+    - gr: λόγος, -ου, ὁ
+      pos: noun
+      en: word  # trailing
+      # foot of vocab item
+
+    - gr: ἄγω
+      en: lead
+    # foot of vocab seq
+
+# between units
+- name: Unit 2
+  vocab:
+    - gr: καί
+      en: and
+# end of file
+`
+
+const commentedMapping = `version: 1
+# head of units
+units:
+  - name: Unit 1 # unit line
+    unit: 1
+    vocab:
+      - gr: ἀγαθός, -ή, -όν
+        pos: adj
+        en: good
+        cog:
+          - agathism
+          # foot of cog item
+        # foot of word, after a nested list
+  - name: Unit 2
+    pp:
+      - pr: ἄγω
+        fu: ἄξω
+        # foot of pp
+# foot of last unit
+`
+
+func TestFormat(t *testing.T) {
+	out, err := Format([]byte(commentedList))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# head of file
+-
+  name: Unit 1
+  vocab:
+    # head of first word
+    -
+      gr: λόγος, -ου, ὁ
+      en: word # trailing
+      pos: noun
+      # foot of vocab item
+
+    -
+      gr: ἄγω
+      en: lead
+  # foot of vocab seq
+
+# between units
+-
+  name: Unit 2
+  vocab:
+    -
+      gr: καί
+      en: and
+# end of file
+`
+	if string(out) != want {
+		t.Errorf("Format: got\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestFormatIdempotent(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"commented list", commentedList},
+		{"commented mapping", commentedMapping},
+		{"scalar list foot", "- name: Unit 1\n  vocab:\n    - gr: a\n      cog:\n        - x\n        - y\n        # foot of y\n"},
+		{"non-last key foot", "- name: Unit 1\n  vocab:\n    - gr: a\n      # foot of gr\n\n      en: b\n"},
+		{"unit foot without blank", "- name: Unit 1\n  vocab:\n    - gr: a\n      en: b\n  # unit foot\n- name: Unit 2\n"},
+		{"empty", ""},
+	}
+
+	for _, tc := range tests {
+		once, err := Format([]byte(tc.data))
+		if err != nil {
+			t.Errorf("%s: %s", tc.name, err)
+			continue
+		}
+		twice, err := Format(once)
+		if err != nil {
+			t.Errorf("%s: %s", tc.name, err)
+			continue
+		}
+		if string(once) != string(twice) {
+			t.Errorf("%s: not idempotent: first pass\n%s\nsecond pass\n%s",
+				tc.name, once, twice)
+		}
+	}
+}
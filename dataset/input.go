@@ -0,0 +1,102 @@
+package dataset
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Stdin is the dataset filename used to read from standard input
+const Stdin = "-"
+
+// SidecarSources are the enrichment sources whose sidecar files (e.g.
+// vocab.morpheus.yml, written by mag enrich) sit alongside the datasets
+var SidecarSources = []string{"morpheus", "lsj", "wiktionary"}
+
+// IsSidecar reports whether the file name is an enrichment sidecar
+// rather than a dataset
+func IsSidecar(name string) bool {
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	source := strings.TrimPrefix(filepath.Ext(base), ".")
+	return source != "" && slices.Contains(SidecarSources, source)
+}
+
+// Open opens the dataset filename for reading, returning standard input
+// if filename is Stdin
+func Open(filename string) (io.ReadCloser, error) {
+	if filename == Stdin {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(filename)
+}
+
+// ReadFile returns the contents of the dataset filename, reading
+// standard input if filename is Stdin
+func ReadFile(filename string) ([]byte, error) {
+	if filename == Stdin {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(filename)
+}
+
+// ExpandPaths returns paths with any directories replaced by the YAML
+// (.yml or .yaml) files they contain (other than sidecars), in lexical
+// order
+func ExpandPaths(paths []string) ([]string, error) {
+	filenames := []string{}
+	for _, path := range paths {
+		if path == Stdin {
+			filenames = append(filenames, path)
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			filenames = append(filenames, path)
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		found := 0
+		for _, e := range entries {
+			ext := filepath.Ext(e.Name())
+			if e.IsDir() || (ext != ".yml" && ext != ".yaml") || IsSidecar(e.Name()) {
+				continue
+			}
+			filenames = append(filenames, filepath.Join(path, e.Name()))
+			found++
+		}
+		if found == 0 {
+			return nil, fmt.Errorf("%s: no .yml datasets found", path)
+		}
+	}
+	return filenames, nil
+}
+
+// DatasetEnv returns the environment variable listing the default
+// datasets (separated by the OS path list separator) of the type whose
+// default filename is def: MAG_ and its upper-cased base name (e.g.
+// MAG_VOCAB for vocab.yml, MAG_PP for pp.yml)
+func DatasetEnv(def string) string {
+	base := filepath.Base(def)
+	return "MAG_" + strings.ToUpper(strings.TrimSuffix(base, filepath.Ext(base)))
+}
+
+// DefaultPaths returns paths, or if that is empty the datasets listed
+// in the environment variable for def (see DatasetEnv), or else def
+func DefaultPaths(paths []string, def string) []string {
+	if len(paths) > 0 {
+		return paths
+	}
+	if env := os.Getenv(DatasetEnv(def)); env != "" {
+		return filepath.SplitList(env)
+	}
+	return []string{def}
+}
@@ -0,0 +1,31 @@
+package dataset
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDefaultPaths(t *testing.T) {
+	t.Setenv("MAG_VOCAB", "a/vocab.yml")
+	t.Setenv("MAG_PP", "")
+	tests := []struct {
+		paths []string
+		def   string
+		want  []string
+	}{
+		{[]string{"x.yml"}, "vocab.yml", []string{"x.yml"}},
+		{nil, "vocab.yml", []string{"a/vocab.yml"}},
+		{nil, "pp.yml", []string{"pp.yml"}},
+		{nil, "sentences.yml", []string{"sentences.yml"}},
+	}
+	for _, tc := range tests {
+		got := DefaultPaths(tc.paths, tc.def)
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("DefaultPaths(%v, %q): got %v, want %v",
+				tc.paths, tc.def, got, tc.want)
+		}
+	}
+	if env := DatasetEnv("paradigms.yml"); env != "MAG_PARADIGMS" {
+		t.Errorf("DatasetEnv(paradigms.yml): got %q", env)
+	}
+}
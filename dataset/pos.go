@@ -0,0 +1,100 @@
+package dataset
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	// PartsOfSpeech maps the valid vocab 'pos' values to their full names
+	PartsOfSpeech = map[string]string{
+		"adj":      "adjective",
+		"adv":      "adverb",
+		"conj":     "conjunction",
+		"n":        "noun",
+		"part":     "participle",
+		"particle": "particle",
+		"prep":     "preposition",
+		"pron":     "pronoun",
+		"v":        "verb",
+	}
+)
+
+// maxSuggestDistance is the maximum edit distance for POS suggestions
+const maxSuggestDistance = 2
+
+// Levenshtein returns the edit distance between a and b
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j-1] + cost
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// SuggestPOS returns the valid 'pos' values closest to the invalid
+// value pos, or nil if none are close
+func SuggestPOS(pos string) []string {
+	pos = strings.ToLower(strings.TrimSpace(pos))
+	if pos == "" {
+		return nil
+	}
+
+	// Full names (or their prefixes) suggest the matching abbreviation
+	suggestions := []string{}
+	for abbrev, name := range PartsOfSpeech {
+		if strings.HasPrefix(name, pos) && len(pos) > len(abbrev) {
+			suggestions = append(suggestions, abbrev)
+		}
+	}
+	if len(suggestions) > 0 {
+		sort.Strings(suggestions)
+		return suggestions
+	}
+
+	best := maxSuggestDistance + 1
+	for abbrev := range PartsOfSpeech {
+		d := Levenshtein(pos, abbrev)
+		if d < best {
+			best = d
+			suggestions = []string{abbrev}
+		} else if d == best {
+			suggestions = append(suggestions, abbrev)
+		}
+	}
+	sort.Strings(suggestions)
+	return suggestions
+}
+
+// FormatSuggestions returns a " (did you mean ...?)" hint for
+// suggestions, or "" if there are none
+func FormatSuggestions(suggestions []string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	quoted := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		quoted[i] = fmt.Sprintf("%q", s)
+	}
+	return " (did you mean " + strings.Join(quoted, " or ") + "?)"
+}
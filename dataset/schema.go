@@ -0,0 +1,254 @@
+package dataset
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+//go:embed schemas/*.schema.json
+var schemaFS embed.FS
+
+// Schema is the subset of JSON Schema used to describe the datasets
+type Schema struct {
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 schemaTypes        `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+}
+
+// schemaTypes is a JSON Schema "type", which may be a string or a list
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = []string{s}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*t = list
+	return nil
+}
+
+// SchemaError is a schema violation found at a given source position
+type SchemaError struct {
+	Line    int
+	Column  int
+	Path    string
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", e.Line, e.Column, e.Path, e.Message)
+}
+
+// SchemaJSON returns the raw JSON Schema document for the named dataset
+// schema ("vocab", "pp", "paradigms", "sentences", or "exercises")
+func SchemaJSON(name string) ([]byte, error) {
+	data, err := schemaFS.ReadFile("schemas/" + name + ".schema.json")
+	if err != nil {
+		return nil, fmt.Errorf("unknown schema %q", name)
+	}
+	return data, nil
+}
+
+// LoadSchema returns the named dataset schema ("vocab", "pp",
+// "paradigms", "sentences", or "exercises")
+func LoadSchema(name string) (*Schema, error) {
+	data, err := SchemaJSON(name)
+	if err != nil {
+		return nil, err
+	}
+	var s Schema
+	err = json.Unmarshal(data, &s)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s schema: %w", name, err)
+	}
+	return &s, nil
+}
+
+// DetectSchema returns the name of the schema matching the dataset in
+// doc, based on the unit list keys used, or "" if it cannot tell
+func DetectSchema(doc *yaml.Node) string {
+	units, err := UnitsNode(doc)
+	if err != nil || units == nil || units.Kind != yaml.SequenceNode {
+		return ""
+	}
+	for _, unit := range units.Content {
+		if unit.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(unit.Content); i += 2 {
+			if _, ok := entryKeys[unit.Content[i].Value]; ok {
+				return unit.Content[i].Value
+			}
+		}
+	}
+	return ""
+}
+
+// Validate checks the YAML dataset doc against s, returning all
+// violations found in document order. Version 1 datasets (a bare list
+// of units) are validated against the schema for the units list.
+func (s *Schema) Validate(doc *yaml.Node) []SchemaError {
+	root := rootNode(doc)
+	if root == nil {
+		return []SchemaError{{Line: 1, Column: 1, Message: "empty document"}}
+	}
+	if units, ok := s.Properties["units"]; ok && root.Kind == yaml.SequenceNode {
+		s = units
+	}
+	var errs []SchemaError
+	s.validate(root, "", &errs)
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Line != errs[j].Line {
+			return errs[i].Line < errs[j].Line
+		}
+		return errs[i].Column < errs[j].Column
+	})
+	return errs
+}
+
+// nodeType returns the JSON Schema type of the YAML node n
+func nodeType(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.SequenceNode:
+		return "array"
+	case yaml.MappingNode:
+		return "object"
+	case yaml.AliasNode:
+		return nodeType(n.Alias)
+	}
+	switch n.Tag {
+	case "!!int":
+		return "integer"
+	case "!!float":
+		return "number"
+	case "!!bool":
+		return "boolean"
+	case "!!null":
+		return "null"
+	}
+	return "string"
+}
+
+func (s *Schema) typeMatches(t string) bool {
+	if len(s.Type) == 0 {
+		return true
+	}
+	for _, st := range s.Type {
+		if st == t || (st == "number" && t == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schema) validate(n *yaml.Node, path string, errs *[]SchemaError) {
+	addError := func(n *yaml.Node, path, format string, args ...interface{}) {
+		*errs = append(*errs, SchemaError{
+			Line:    n.Line,
+			Column:  n.Column,
+			Path:    path,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	t := nodeType(n)
+	if !s.typeMatches(t) {
+		addError(n, path, "wrong type: expected %s, got %s",
+			strings.Join(s.Type, " or "), t)
+		return
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if fmt.Sprint(e) == n.Value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			addError(n, path, "invalid value %q: must be one of %v",
+				n.Value, s.Enum)
+		}
+	}
+
+	if (s.Minimum != nil || s.Maximum != nil) &&
+		(t == "integer" || t == "number") {
+		v, err := strconv.ParseFloat(n.Value, 64)
+		if err == nil {
+			if s.Minimum != nil && v < *s.Minimum {
+				addError(n, path, "value %s is less than minimum %v",
+					n.Value, *s.Minimum)
+			}
+			if s.Maximum != nil && v > *s.Maximum {
+				addError(n, path, "value %s is greater than maximum %v",
+					n.Value, *s.Maximum)
+			}
+		}
+	}
+
+	switch n.Kind {
+	case yaml.SequenceNode:
+		if s.Items == nil {
+			return
+		}
+		for i, item := range n.Content {
+			s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+
+	case yaml.MappingNode:
+		seen := make(map[string]bool)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			kpath := key.Value
+			if path != "" {
+				kpath = path + "." + key.Value
+			}
+			if seen[key.Value] {
+				addError(key, kpath, "duplicate key %q", key.Value)
+			}
+			seen[key.Value] = true
+
+			prop, ok := s.Properties[key.Value]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					addError(key, kpath, "unknown key %q", key.Value)
+				}
+				continue
+			}
+			prop.validate(value, kpath, errs)
+		}
+
+		missing := []string{}
+		for _, r := range s.Required {
+			if !seen[r] {
+				missing = append(missing, r)
+			}
+		}
+		sort.Strings(missing)
+		for _, r := range missing {
+			addError(n, path, "missing required field %q", r)
+		}
+	}
+}
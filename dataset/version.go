@@ -0,0 +1,142 @@
+package dataset
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the current dataset schema version. Version 1
+// datasets are either a bare list of units, or a mapping with the units
+// under a top-level "units" key, alongside the "version" field.
+const CurrentVersion = 1
+
+// migration upgrades a dataset document in place to the next version
+type migration func(root *yaml.Node) (*yaml.Node, error)
+
+// migrations[i] upgrades a version i+1 dataset to version i+2. Schema
+// changes to the unit records (e.g. renamed fields) need a new version
+// and migration here, as well as changes to the decoders. There are no
+// migrations yet.
+var migrations = []migration{}
+
+// rootNode returns the root content node of doc, or nil if doc is empty
+func rootNode(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == 0 {
+		return nil
+	}
+	if doc.Kind == yaml.DocumentNode {
+		if len(doc.Content) == 0 {
+			return nil
+		}
+		return doc.Content[0]
+	}
+	return doc
+}
+
+// mappingValue returns the value node for key in mapping n, or nil
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// Version returns the schema version of the dataset document doc
+func Version(doc *yaml.Node) (int, error) {
+	root := rootNode(doc)
+	if root == nil {
+		return CurrentVersion, nil
+	}
+	switch root.Kind {
+	case yaml.SequenceNode:
+		return 1, nil
+	case yaml.MappingNode:
+		vnode := mappingValue(root, "version")
+		if vnode == nil {
+			return 0, fmt.Errorf("line %d: missing dataset 'version' field",
+				root.Line)
+		}
+		version, err := strconv.Atoi(vnode.Value)
+		if err != nil || version < 1 {
+			return 0, fmt.Errorf("line %d: invalid dataset version %q",
+				vnode.Line, vnode.Value)
+		}
+		return version, nil
+	}
+	return 0, fmt.Errorf("line %d: dataset must be a list or mapping",
+		root.Line)
+}
+
+// UnitsNode returns the node holding the list of units in the dataset
+// document doc (or nil if doc is empty), checking that doc's version is
+// supported
+func UnitsNode(doc *yaml.Node) (*yaml.Node, error) {
+	version, err := Version(doc)
+	if err != nil {
+		return nil, err
+	}
+	if version > CurrentVersion {
+		return nil, fmt.Errorf("dataset version %d is newer than supported version %d",
+			version, CurrentVersion)
+	}
+	root := rootNode(doc)
+	if root == nil || root.Kind == yaml.SequenceNode {
+		return root, nil
+	}
+	units := mappingValue(root, "units")
+	if units == nil {
+		return nil, fmt.Errorf("line %d: missing dataset 'units' field",
+			root.Line)
+	}
+	return units, nil
+}
+
+// Unmarshal decodes the YAML dataset in data (which may contain
+// multiple documents) into units, which should be a pointer to a slice
+// of unit records. All dataset versions up to CurrentVersion are
+// supported.
+func Unmarshal(data []byte, units interface{}) error {
+	return NewDecoder(bytes.NewReader(data)).DecodeAll(units)
+}
+
+// Migrate upgrades the dataset document doc in place to CurrentVersion,
+// returning the version it was migrated from
+func Migrate(doc *yaml.Node) (int, error) {
+	version, err := Version(doc)
+	if err != nil {
+		return 0, err
+	}
+	if version > CurrentVersion {
+		return version, fmt.Errorf("dataset version %d is newer than supported version %d",
+			version, CurrentVersion)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return version, nil
+	}
+
+	root := doc.Content[0]
+	for v := version; v < CurrentVersion; v++ {
+		root, err = migrations[v-1](root)
+		if err != nil {
+			return version, fmt.Errorf("migrating from version %d: %w", v, err)
+		}
+		setVersion(root, v+1)
+	}
+	doc.Content[0] = root
+	return version, nil
+}
+
+// setVersion sets the "version" field of the mapping root to version
+func setVersion(root *yaml.Node, version int) {
+	vnode := mappingValue(root, "version")
+	if vnode == nil {
+		return
+	}
+	vnode.Tag = "!!int"
+	vnode.Value = strconv.Itoa(version)
+}
@@ -0,0 +1,83 @@
+package dataset
+
+import (
+	"testing"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		from    int
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "version 1 list",
+			data: "# Units\n- name: Unit 1\n  vocab:\n    - gr: ἄγω\n      en: lead\n",
+			from: 1,
+			want: "# Units\n-\n  name: Unit 1\n  vocab:\n    -\n      gr: ἄγω\n      en: lead\n",
+		},
+		{
+			name: "version 1 mapping",
+			data: "version: 1\nunits:\n  - name: Unit 1\n",
+			from: 1,
+			want: "version: 1\nunits:\n  -\n    name: Unit 1\n",
+		},
+		{
+			name: "empty",
+			data: "",
+			from: CurrentVersion,
+			want: "",
+		},
+		{name: "newer version", data: "version: 2\nunits: []\n", wantErr: true},
+		{name: "invalid version", data: "version: two\nunits: []\n", wantErr: true},
+		{name: "missing version", data: "units: []\n", wantErr: true},
+		{name: "scalar", data: "vocab\n", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		var doc yaml.Node
+		err := yaml.Unmarshal([]byte(tc.data), &doc)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.name, err)
+		}
+		from, err := Migrate(&doc)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: want error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", tc.name, err)
+			continue
+		}
+		if from != tc.from {
+			t.Errorf("%s: migrated from version %d, want %d", tc.name, from, tc.from)
+		}
+		out, err := Encode(&doc)
+		if err != nil {
+			t.Errorf("%s: %s", tc.name, err)
+			continue
+		}
+		if string(out) != tc.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tc.name, out, tc.want)
+		}
+
+		if again, _ := Format(out); string(again) != string(out) {
+			t.Errorf("%s: migrated dataset not canonical: reformatted as\n%s",
+				tc.name, again)
+		}
+
+		// Migrated datasets decode as units
+		var units []struct {
+			Name string `yaml:"name"`
+		}
+		if err := Unmarshal(out, &units); err != nil {
+			t.Errorf("%s: decoding migrated dataset: %s", tc.name, err)
+		}
+	}
+}
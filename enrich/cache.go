@@ -0,0 +1,198 @@
+package enrich
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrOffline is returned for requests not answerable from the cache in
+// offline mode
+var ErrOffline = errors.New("not cached (offline)")
+
+// cacheEntry is a cached HTTP response, as stored on disk
+type cacheEntry struct {
+	URL         string    `json:"url"`
+	Status      int       `json:"status"`
+	ContentType string    `json:"content_type,omitempty"`
+	Fetched     time.Time `json:"fetched"`
+	Body        string    `json:"body"`
+}
+
+// Cache is a persistent on-disk cache of the responses of enrichment
+// services, shared by all the enrichment clients using it as their HTTP
+// transport. Responses to GET requests that are definitive (200 OK or
+// 404 Not Found) are stored as JSON files named by the SHA-256 hash of
+// the request URL, so repeated runs don't fetch them again.
+type Cache struct {
+	Dir       string            // cache directory
+	TTL       time.Duration     // maximum age of cached responses (0 for no limit)
+	Refresh   bool              // ignore cached responses, fetching (and caching) them again
+	Offline   bool              // never fetch, failing requests not in the cache with ErrOffline
+	Transport http.RoundTripper // transport for uncached requests (nil for http.DefaultTransport)
+}
+
+// DefaultCacheDir returns the default enrichment cache directory (mag/enrich
+// in the user cache directory, e.g. ~/.cache/mag/enrich)
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mag", "enrich"), nil
+}
+
+// NewCache returns a cache in dir for responses up to ttl old
+func NewCache(dir string, ttl time.Duration) *Cache {
+	return &Cache{Dir: dir, TTL: ttl}
+}
+
+// Use makes client use c as its transport, wrapping its current one
+func (c *Cache) Use(client *http.Client) {
+	if client.Transport != nil && client.Transport != c {
+		c.Transport = client.Transport
+	}
+	client.Transport = c
+}
+
+// path returns the cache file path for the url
+func (c *Cache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(c.Dir, key[:2], key+".json")
+}
+
+// get returns the cached entry for url, or nil if it isn't cached or has
+// expired (unless offline, when expired entries are better than none)
+func (c *Cache) get(url string) (*cacheEntry, error) {
+	data, err := os.ReadFile(c.path(url))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var e cacheEntry
+	err = json.Unmarshal(data, &e)
+	if err != nil || e.URL != url {
+		// Treat corrupt entries (and hash collisions) as misses
+		slog.Warn("ignoring bad cache entry", "file", c.path(url), "error", err)
+		return nil, nil
+	}
+	if c.TTL > 0 && !c.Offline && time.Since(e.Fetched) > c.TTL {
+		return nil, nil
+	}
+	return &e, nil
+}
+
+// put stores e in the cache, writing it atomically
+func (c *Cache) put(e *cacheEntry) error {
+	path := c.path(e.URL)
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// response returns e as an HTTP response to req
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	header := make(http.Header)
+	if e.ContentType != "" {
+		header.Set("Content-Type", e.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status)),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(e.Body))),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// RoundTrip implements http.RoundTripper, answering GET requests from the
+// cache where possible, and caching definitive responses
+func (c *Cache) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := c.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	url := req.URL.String()
+	if req.Method != http.MethodGet {
+		if c.Offline {
+			return nil, fmt.Errorf("%w: %s %s", ErrOffline, req.Method, url)
+		}
+		return transport.RoundTrip(req)
+	}
+	if !c.Refresh || c.Offline {
+		e, err := c.get(url)
+		if err != nil {
+			return nil, err
+		}
+		if e != nil {
+			slog.Debug("cache hit", "url", url)
+			return e.response(req), nil
+		}
+	}
+	if c.Offline {
+		return nil, fmt.Errorf("%w: %s", ErrOffline, url)
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	e := &cacheEntry{
+		URL:         url,
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Fetched:     time.Now().UTC(),
+		Body:        string(body),
+	}
+	err = c.put(e)
+	if err != nil {
+		slog.Warn("cannot cache response", "url", url, "error", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
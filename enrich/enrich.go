@@ -0,0 +1,122 @@
+// Package enrich looks up the headwords of the mag datasets in external
+// services (such as the Morpheus morphological analyser), storing the
+// results in sidecar files alongside the datasets
+
+package enrich
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/gavincarr/mag/dataset"
+	"github.com/gavincarr/mag/greek"
+)
+
+// Headword returns the headword of the Greek dataset entry gr, for
+// lookups: its first word, without any punctuation, digits, or optional
+// letters' parentheses (e.g. λόγος for "λόγος, -ου, ὁ", or ἐθέλω for
+// "(ἐ)θέλω")
+func Headword(gr string) string {
+	first, _, _ := strings.Cut(gr, ",")
+	fields := strings.Fields(first)
+	if len(fields) == 0 {
+		return ""
+	}
+	word := strings.Map(func(r rune) rune {
+		if greek.IsGreekLetter(r) || unicode.Is(unicode.Mn, r) {
+			return r
+		}
+		return -1
+	}, norm.NFD.String(fields[0]))
+	return norm.NFC.String(word)
+}
+
+// SidecarPath returns the path of the sidecar file for the results of
+// source for the dataset path (e.g. vocab.morpheus.yml for vocab.yml)
+func SidecarPath(path, source string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + source + ext
+}
+
+// Sidecar holds the results of an enrichment source for the headwords
+// of a dataset
+type Sidecar[T any] struct {
+	Source string       `yaml:"source"`
+	Words  map[string]T `yaml:"words"` // results keyed by headword
+}
+
+// NewSidecar returns an empty sidecar for source
+func NewSidecar[T any](source string) *Sidecar[T] {
+	return &Sidecar[T]{Source: source, Words: make(map[string]T)}
+}
+
+// LoadSidecar reads the sidecar file path for source, returning an empty
+// sidecar if it doesn't exist
+func LoadSidecar[T any](path, source string) (*Sidecar[T], error) {
+	s := NewSidecar[T](source)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = yaml.Unmarshal(data, s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if s.Source != source {
+		return nil, fmt.Errorf("%s: not a %s sidecar (source %q)", path, source, s.Source)
+	}
+	if s.Words == nil {
+		s.Words = make(map[string]T)
+	}
+	return s, nil
+}
+
+// LoadSidecars returns the results of source in the sidecars of the
+// datasets filenames (where they exist), keyed by dataset filename and
+// then headword
+func LoadSidecars[T any](filenames []string, source string) (map[string]map[string]T, error) {
+	results := make(map[string]map[string]T)
+	for _, filename := range filenames {
+		if _, done := results[filename]; done || filename == dataset.Stdin {
+			continue
+		}
+		path := SidecarPath(filename, source)
+		s, err := LoadSidecar[T](path, source)
+		if err != nil {
+			return nil, err
+		}
+		if len(s.Words) > 0 {
+			slog.Debug("loaded sidecar", "file", path, "words", len(s.Words))
+		}
+		results[filename] = s.Words
+	}
+	return results, nil
+}
+
+// Write writes s to the sidecar file path, for the dataset dataset
+func (s *Sidecar[T]) Write(path, dataset string) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s results for %s, written by mag enrich\n",
+		s.Source, filepath.Base(dataset))
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	err := enc.Encode(s)
+	if err != nil {
+		return err
+	}
+	enc.Close()
+	return os.WriteFile(path, b.Bytes(), 0o644)
+}
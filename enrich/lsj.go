@@ -0,0 +1,116 @@
+package enrich
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/gavincarr/mag/greek"
+	"github.com/gavincarr/mag/httpclient"
+)
+
+const (
+	// SourceLSJ is the source name of LSJ definitions (as listed in
+	// dataset.SidecarSources)
+	SourceLSJ = "lsj"
+	// LSJURL is the default Perseus endpoint for LSJ entries
+	LSJURL = "https://www.perseus.tufts.edu/hopper/xmlchunk"
+	// lsjDoc is the Perseus document id of the LSJ lexicon
+	lsjDoc = "Perseus:text:1999.04.0057"
+	// lsjMaxGlosses is the maximum number of glosses in a short definition
+	lsjMaxGlosses = 5
+)
+
+// LSJ is a client for the Liddell-Scott-Jones lexicon entries served by
+// the Perseus Digital Library
+type LSJ struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewLSJ returns an LSJ client for the default Perseus service
+func NewLSJ() *LSJ {
+	return &LSJ{
+		URL:    LSJURL,
+		Client: httpclient.New(httpclient.DefaultConfig),
+	}
+}
+
+// Define returns a short LSJ definition of the Greek headword (its first
+// few glosses, e.g. "computation, reckoning, account"), or "" if LSJ
+// has no entry for it
+func (l *LSJ) Define(headword string) (string, error) {
+	key := greek.BetaCode(strings.ToLower(headword))
+	params := url.Values{"doc": {lsjDoc + ":entry=" + key}}
+	req, err := http.NewRequest(http.MethodGet, l.URL+"?"+params.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "mag-utils")
+
+	resp, err := l.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("lsj: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return parseLSJ(resp.Body)
+}
+
+// parseLSJ returns the short definition from the LSJ TEI entry read from
+// r: the first distinct translation (<tr>) glosses of its senses
+func parseLSJ(r io.Reader) (string, error) {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+
+	var glosses []string
+	var gloss strings.Builder
+	depth := 0 // depth within a <tr> element
+	for len(glosses) < lsjMaxGlosses {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("lsj: bad entry: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if depth > 0 || t.Name.Local == "tr" {
+				depth++
+			}
+		case xml.EndElement:
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth > 0 {
+				continue
+			}
+			g := strings.Trim(strings.Join(strings.Fields(gloss.String()), " "), " ,;:.")
+			if g != "" && !slices.Contains(glosses, g) {
+				glosses = append(glosses, g)
+			}
+			gloss.Reset()
+		case xml.CharData:
+			if depth > 0 {
+				gloss.Write(t)
+			}
+		}
+	}
+	return strings.Join(glosses, ", "), nil
+}
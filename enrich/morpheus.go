@@ -0,0 +1,199 @@
+package enrich
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gavincarr/mag/httpclient"
+)
+
+const (
+	// SourceMorpheus is the source name of Morpheus analyses (as listed
+	// in dataset.SidecarSources)
+	SourceMorpheus = "morpheus"
+	// MorpheusURL is the default Morpheus analysis service endpoint
+	MorpheusURL = "https://morph.perseids.org/analysis/word"
+)
+
+// Analysis is a Morpheus analysis of a word, as one of its lemmas
+type Analysis struct {
+	Lemma       string       `yaml:"lemma"`
+	POS         string       `yaml:"pos,omitempty"` // e.g. noun, verb
+	Decl        string       `yaml:"decl,omitempty"`
+	Gender      string       `yaml:"gender,omitempty"`
+	Inflections []Inflection `yaml:"inflections,omitempty"`
+}
+
+// Inflection is a Morpheus parse of a word as a form of a lemma
+type Inflection struct {
+	Stem   string `yaml:"stem,omitempty"`
+	Suffix string `yaml:"suffix,omitempty"`
+	POS    string `yaml:"pos,omitempty"`
+	Case   string `yaml:"case,omitempty"`
+	Number string `yaml:"number,omitempty"`
+	Gender string `yaml:"gender,omitempty"`
+	Person string `yaml:"person,omitempty"`
+	Tense  string `yaml:"tense,omitempty"`
+	Mood   string `yaml:"mood,omitempty"`
+	Voice  string `yaml:"voice,omitempty"`
+}
+
+// Describe returns a short description of a, e.g. "λόγος: noun, 2nd
+// declension, masculine"
+func (a Analysis) Describe() string {
+	var parts []string
+	if a.POS != "" {
+		parts = append(parts, a.POS)
+	}
+	if a.Decl != "" {
+		parts = append(parts, a.Decl+" declension")
+	}
+	if a.Gender != "" {
+		parts = append(parts, a.Gender)
+	}
+	if len(parts) == 0 {
+		return a.Lemma
+	}
+	return a.Lemma + ": " + strings.Join(parts, ", ")
+}
+
+// Morpheus is a client for the Morpheus morphological analysis service
+// (as hosted by Perseids/Alpheios)
+type Morpheus struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewMorpheus returns a Morpheus client for the default service
+func NewMorpheus() *Morpheus {
+	return &Morpheus{
+		URL:    MorpheusURL,
+		Client: httpclient.New(httpclient.DefaultConfig),
+	}
+}
+
+// Analyze returns the Morpheus analyses of the Greek word, or none if
+// Morpheus doesn't recognise it
+func (m *Morpheus) Analyze(word string) ([]Analysis, error) {
+	params := url.Values{
+		"lang":   {"grc"},
+		"engine": {"morpheusgrc"},
+		"word":   {word},
+	}
+	req, err := http.NewRequest(http.MethodGet, m.URL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "mag-utils")
+
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("morpheus: %s: %s", resp.Status,
+			bytes.TrimSpace(msg))
+	}
+	return parseMorpheus(resp.Body)
+}
+
+// morphValue is an Alpheios annotation value ({"$": "noun"})
+type morphValue struct {
+	Text string `json:"$"`
+}
+
+// morphList is an Alpheios annotation list, which is given as a single
+// object when it has only one element
+type morphList[T any] []T
+
+// UnmarshalJSON decodes a morphList from either an array or an object
+func (l *morphList[T]) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		return json.Unmarshal(data, (*[]T)(l))
+	}
+	var v T
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+	*l = []T{v}
+	return nil
+}
+
+// morphResponse is the Alpheios annotation format returned by Morpheus
+type morphResponse struct {
+	RDF struct {
+		Annotation struct {
+			Body morphList[struct {
+				Rest struct {
+					Entry struct {
+						Dict struct {
+							Hdwd morphValue `json:"hdwd"`
+							Pofs morphValue `json:"pofs"`
+							Decl morphValue `json:"decl"`
+							Gend morphValue `json:"gend"`
+						} `json:"dict"`
+						Infl morphList[struct {
+							Term struct {
+								Stem morphValue `json:"stem"`
+								Suff morphValue `json:"suff"`
+							} `json:"term"`
+							Pofs  morphValue `json:"pofs"`
+							Case  morphValue `json:"case"`
+							Num   morphValue `json:"num"`
+							Gend  morphValue `json:"gend"`
+							Pers  morphValue `json:"pers"`
+							Tense morphValue `json:"tense"`
+							Mood  morphValue `json:"mood"`
+							Voice morphValue `json:"voice"`
+						}] `json:"infl"`
+					} `json:"entry"`
+				} `json:"rest"`
+			}] `json:"Body"`
+		} `json:"Annotation"`
+	} `json:"RDF"`
+}
+
+// parseMorpheus parses the Morpheus JSON response read from r
+func parseMorpheus(r io.Reader) ([]Analysis, error) {
+	var resp morphResponse
+	err := json.NewDecoder(r).Decode(&resp)
+	if err != nil {
+		return nil, fmt.Errorf("morpheus: bad response: %w", err)
+	}
+	var analyses []Analysis
+	for _, body := range resp.RDF.Annotation.Body {
+		entry := body.Rest.Entry
+		a := Analysis{
+			Lemma:  entry.Dict.Hdwd.Text,
+			POS:    entry.Dict.Pofs.Text,
+			Decl:   entry.Dict.Decl.Text,
+			Gender: entry.Dict.Gend.Text,
+		}
+		for _, infl := range entry.Infl {
+			a.Inflections = append(a.Inflections, Inflection{
+				Stem:   infl.Term.Stem.Text,
+				Suffix: infl.Term.Suff.Text,
+				POS:    infl.Pofs.Text,
+				Case:   infl.Case.Text,
+				Number: infl.Num.Text,
+				Gender: infl.Gend.Text,
+				Person: infl.Pers.Text,
+				Tense:  infl.Tense.Text,
+				Mood:   infl.Mood.Text,
+				Voice:  infl.Voice.Text,
+			})
+		}
+		analyses = append(analyses, a)
+	}
+	return analyses, nil
+}
@@ -0,0 +1,333 @@
+package enrich
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gavincarr/mag/httpclient"
+)
+
+const (
+	// SourceWiktionary is the source name of Wiktionary etymologies (as
+	// listed in dataset.SidecarSources)
+	SourceWiktionary = "wiktionary"
+	// WiktionaryURL is the default Wiktionary MediaWiki API endpoint
+	WiktionaryURL = "https://en.wiktionary.org/w/api.php"
+)
+
+var (
+	reWikiHeading = regexp.MustCompile(`^(={2,6})\s*(.+?)\s*={2,6}\s*$`)
+	reWikiRef     = regexp.MustCompile(`(?s)<ref[^>]*/>|<ref[^>]*>.*?</ref>|<!--.*?-->`)
+	reWikiLink    = regexp.MustCompile(`\[\[(?:[^|\]]*\|)?([^\]]*)\]\]`)
+	reWikiMarkup  = regexp.MustCompile(`<[^>]+>|'{2,}`)
+	reWikiList    = regexp.MustCompile(`(?m)^[*:#]+\s*`)
+	reWikiSpace   = regexp.MustCompile(`\s+([,.;:)])`)
+
+	// wikiLanguages maps the Wiktionary language codes common in Ancient
+	// Greek etymologies to their names
+	wikiLanguages = map[string]string{
+		"ang": "Old English", "cel-pro": "Proto-Celtic", "de": "German",
+		"el": "Greek", "en": "English", "enm": "Middle English",
+		"fr": "French", "gem-pro": "Proto-Germanic", "got": "Gothic",
+		"grc": "Ancient Greek", "grc-myc": "Mycenaean Greek",
+		"grk-pro": "Proto-Hellenic", "hit": "Hittite",
+		"iir-pro": "Proto-Indo-Iranian", "ine-pro": "Proto-Indo-European",
+		"it": "Italian", "itc-pro": "Proto-Italic", "la": "Latin",
+		"lt": "Lithuanian", "non": "Old Norse", "peo": "Old Persian",
+		"sa": "Sanskrit", "sem": "Semitic", "sla-pro": "Proto-Slavic",
+		"xcl": "Old Armenian",
+	}
+)
+
+// Etymology is the Wiktionary etymology of an Ancient Greek headword
+type Etymology struct {
+	Text     string   `yaml:"text,omitempty"`          // etymology, as plain text
+	Cognates []string `yaml:"cognates,omitempty,flow"` // English terms from the etymology and descendants
+}
+
+// Wiktionary is a client for the English Wiktionary MediaWiki API
+type Wiktionary struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWiktionary returns a Wiktionary client for the default service
+func NewWiktionary() *Wiktionary {
+	return &Wiktionary{
+		URL:    WiktionaryURL,
+		Client: httpclient.New(httpclient.DefaultConfig),
+	}
+}
+
+// wiktionaryResponse is a MediaWiki API parse response
+type wiktionaryResponse struct {
+	Parse struct {
+		Wikitext string `json:"wikitext"`
+	} `json:"parse"`
+	Error struct {
+		Code string `json:"code"`
+		Info string `json:"info"`
+	} `json:"error"`
+}
+
+// Etymology returns the Wiktionary etymology of the Ancient Greek
+// headword, which is empty if Wiktionary has no entry for it
+func (w *Wiktionary) Etymology(headword string) (Etymology, error) {
+	params := url.Values{
+		"action":        {"parse"},
+		"page":          {headword},
+		"prop":          {"wikitext"},
+		"redirects":     {"1"},
+		"format":        {"json"},
+		"formatversion": {"2"},
+	}
+	req, err := http.NewRequest(http.MethodGet, w.URL+"?"+params.Encode(), nil)
+	if err != nil {
+		return Etymology{}, err
+	}
+	req.Header.Set("User-Agent", "mag-utils")
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return Etymology{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Etymology{}, fmt.Errorf("wiktionary: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	var r wiktionaryResponse
+	err = json.NewDecoder(resp.Body).Decode(&r)
+	if err != nil {
+		return Etymology{}, fmt.Errorf("wiktionary: bad response: %w", err)
+	}
+	switch r.Error.Code {
+	case "":
+	case "missingtitle":
+		return Etymology{}, nil
+	default:
+		return Etymology{}, fmt.Errorf("wiktionary: %s: %s", r.Error.Code, r.Error.Info)
+	}
+	return parseWiktionary(r.Parse.Wikitext), nil
+}
+
+// parseWiktionary returns the etymology from the Ancient Greek section of
+// the Wiktionary page wikitext: the text of its (first) Etymology
+// section, and the English terms mentioned there or in its Descendants
+func parseWiktionary(wikitext string) Etymology {
+	var etymology, descendants strings.Builder
+	language, section := "", ""
+	etymologyDone := false
+	for _, line := range strings.Split(wikitext, "\n") {
+		if m := reWikiHeading.FindStringSubmatch(line); m != nil {
+			if section != "" && strings.HasPrefix(section, "Etymology") && etymology.Len() > 0 {
+				etymologyDone = true
+			}
+			if len(m[1]) == 2 {
+				language, section = m[2], ""
+			} else {
+				section = m[2]
+			}
+			continue
+		}
+		if language != "Ancient Greek" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(section, "Etymology") && !etymologyDone:
+			etymology.WriteString(line + "\n")
+		case section == "Descendants":
+			descendants.WriteString(line + "\n")
+		}
+	}
+
+	var e Etymology
+	e.Text = renderWiki(etymology.String(), &e.Cognates)
+	renderWiki(descendants.String(), &e.Cognates)
+	return e
+}
+
+// wikiTemplate is a parsed wikitext template invocation
+type wikiTemplate struct {
+	name  string
+	args  []string          // positional arguments
+	named map[string]string // named arguments
+}
+
+// arg returns the i'th positional argument of t, or ""
+func (t wikiTemplate) arg(i int) string {
+	if i < len(t.args) {
+		return t.args[i]
+	}
+	return ""
+}
+
+// term renders the term at positional argument i of t, with its
+// alternative form (at i+1) and gloss (at i+2, or t= or gloss=)
+func (t wikiTemplate) term(i int) string {
+	text := t.arg(i + 1)
+	if text == "" {
+		text = t.arg(i)
+	}
+	gloss := t.arg(i + 2)
+	for _, key := range []string{"t", "gloss"} {
+		if gloss == "" {
+			gloss = t.named[key]
+		}
+	}
+	if gloss != "" {
+		text += " (“" + gloss + "”)"
+	}
+	return text
+}
+
+// wikiLanguage returns the name of the Wiktionary language code
+func wikiLanguage(code string) string {
+	if name, ok := wikiLanguages[code]; ok {
+		return name
+	}
+	return code
+}
+
+// withLanguage returns term prefixed with the name of the language code
+func withLanguage(code, term string) string {
+	return strings.TrimSpace(wikiLanguage(code) + " " + term)
+}
+
+// render returns the plain text rendering of the etymology template t
+// (or "" for templates without useful text), appending any English
+// terms it mentions to cognates
+func (t wikiTemplate) render(cognates *[]string) string {
+	switch t.name {
+	case "cog", "cognate", "noncog", "ncog", "noncognate",
+		"m", "mention", "l", "link", "ll", "desc", "desctree":
+		if word := t.arg(1); t.arg(0) == "en" && word != "" && !slices.Contains(*cognates, word) {
+			*cognates = append(*cognates, word)
+		}
+	}
+	switch t.name {
+	case "inh", "inh+", "der", "der+", "bor", "bor+", "lbor", "slbor",
+		"uder", "lder", "cal", "calque", "sl", "ubor":
+		return withLanguage(t.arg(1), t.term(2))
+	case "cog", "cognate", "noncog", "ncog", "noncognate", "m+":
+		return withLanguage(t.arg(0), t.term(1))
+	case "desc", "desctree":
+		return wikiLanguage(t.arg(0)) + ": " + t.arg(1)
+	case "m", "mention", "l", "link", "ll":
+		return t.term(1)
+	case "af", "affix", "compound", "com", "prefix", "pre", "suffix", "suf", "confix", "con":
+		var parts []string
+		for _, p := range t.args[min(1, len(t.args)):] {
+			if p != "" {
+				parts = append(parts, p)
+			}
+		}
+		return strings.Join(parts, " + ")
+	case "etyl":
+		return wikiLanguage(t.arg(0))
+	case "lang":
+		return t.arg(1)
+	case "gloss", "gl":
+		return "(“" + t.arg(0) + "”)"
+	case "q", "qual", "qualifier", "i":
+		return "(" + t.arg(0) + ")"
+	case "unk", "unknown":
+		return "Unknown"
+	}
+	return ""
+}
+
+// closeTemplate returns the index in s just after the end of the template
+// starting at s[0], or -1 if it is unterminated
+func closeTemplate(s string) int {
+	depth := 0
+	for i := 0; i+1 < len(s); i++ {
+		switch s[i : i+2] {
+		case "{{":
+			depth++
+			i++
+		case "}}":
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// splitTemplate splits the template body s into its name and arguments,
+// at the pipes not within nested templates or links
+func splitTemplate(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "{{"), strings.HasPrefix(s[i:], "[["):
+			depth++
+			i++
+		case strings.HasPrefix(s[i:], "}}"), strings.HasPrefix(s[i:], "]]"):
+			depth--
+			i++
+		case s[i] == '|' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// expandTemplates returns s with its templates rendered (see render)
+func expandTemplates(s string, cognates *[]string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "{{")
+		if i < 0 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:i])
+		end := closeTemplate(s[i:])
+		if end < 0 {
+			break
+		}
+		parts := splitTemplate(s[i+2 : i+end-2])
+		t := wikiTemplate{name: strings.TrimSpace(parts[0]), named: make(map[string]string)}
+		for _, p := range parts[1:] {
+			p = plainWiki(expandTemplates(p, cognates))
+			if k, v, ok := strings.Cut(p, "="); ok && !strings.ContainsAny(k, " ([") {
+				t.named[k] = v
+				continue
+			}
+			t.args = append(t.args, p)
+		}
+		b.WriteString(t.render(cognates))
+		s = s[i+end:]
+	}
+	return b.String()
+}
+
+// plainWiki returns s with wikitext links and markup removed, and its
+// whitespace collapsed
+func plainWiki(s string) string {
+	s = reWikiLink.ReplaceAllString(s, "$1")
+	s = reWikiMarkup.ReplaceAllString(s, "")
+	s = strings.Join(strings.Fields(s), " ")
+	return reWikiSpace.ReplaceAllString(s, "$1")
+}
+
+// renderWiki returns the wikitext s as plain text, appending the English
+// terms mentioned in its templates to cognates
+func renderWiki(s string, cognates *[]string) string {
+	s = reWikiRef.ReplaceAllString(s, "")
+	s = reWikiList.ReplaceAllString(s, "")
+	return plainWiki(expandTemplates(s, cognates))
+}
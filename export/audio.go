@@ -0,0 +1,82 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/gavincarr/mag/tts"
+)
+
+// ErrOffline is returned for audio that would need to be synthesized
+// by an Audio in offline mode
+var ErrOffline = errors.New("audio not generated (offline)")
+
+// AudioData is the data the audio filename template is executed with
+type AudioData struct {
+	ID   string // card id
+	Text string // text spoken
+}
+
+// Audio generates audio files for card text using a text-to-speech
+// engine, and references them on cards using Anki's [sound:...] syntax
+type Audio struct {
+	Engine tts.Engine
+	// Filename is the template for audio filenames, executed with
+	// AudioData (e.g. "{{.ID}}.mp3")
+	Filename *template.Template
+	// Media records the generated files, which are written to its Dir
+	Media *Media
+	// Offline restricts audio to the files already in the media
+	// directory, for engines needing the network (Engine may be nil)
+	Offline bool
+}
+
+// NewAudio returns an Audio using engine to generate files named by the
+// filename template in media
+func NewAudio(engine tts.Engine, filename string, media *Media) (*Audio, error) {
+	tmpl, err := template.New("audio-filename").
+		Option("missingkey=error").Parse(filename)
+	if err != nil {
+		return nil, fmt.Errorf("parsing audio filename template: %w", err)
+	}
+	return &Audio{Engine: engine, Filename: tmpl, Media: media}, nil
+}
+
+// Generate writes audio of text for card id to a file in the media
+// directory, unless it already exists, and returns the Anki [sound:...]
+// reference to it
+func (a *Audio) Generate(id, text string) (string, error) {
+	var b strings.Builder
+	err := a.Filename.Execute(&b, AudioData{ID: id, Text: text})
+	if err != nil {
+		return "", err
+	}
+	filename := b.String()
+	if filename == "" || filename != filepath.Base(filename) {
+		return "", fmt.Errorf("invalid audio filename %q (must be a plain filename)", filename)
+	}
+
+	path := filepath.Join(a.Media.Dir, filename)
+	if _, err := os.Stat(path); err != nil {
+		if a.Offline {
+			return "", fmt.Errorf("%w: %s", ErrOffline, filename)
+		}
+		err = os.MkdirAll(a.Media.Dir, 0o755)
+		if err != nil {
+			return "", err
+		}
+		err = a.Engine.Synthesize(text, path)
+		if err != nil {
+			return "", err
+		}
+	}
+	err = a.Media.add(id, filename, "")
+	if err != nil {
+		return "", err
+	}
+	return "[sound:" + filename + "]", nil
+}
@@ -0,0 +1,102 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// DeckOptions are the description and default options given to each
+// exported deck. Anki's CSV import can't set these, so they're written
+// to a separate decks file, for applying after import (e.g. via
+// AnkiConnect's updateDeckConfig, or by hand).
+type DeckOptions struct {
+	Description string `yaml:"description" json:"description,omitempty"` // HTML
+	NewPerDay   int    `yaml:"new_per_day" json:"new_per_day,omitempty"`
+}
+
+// DeckInfo is a single deck entry in a decks file
+type DeckInfo struct {
+	Name  string `json:"name"`
+	Cards int    `json:"cards"`
+	DeckOptions
+}
+
+// LoadDeckOptions reads deck options from filename, which is either a
+// yml file with description (in Markdown) and new_per_day fields, or a
+// Markdown text file used as the description
+func LoadDeckOptions(filename string) (DeckOptions, error) {
+	var opts DeckOptions
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return opts, err
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(data, &opts)
+		if err != nil {
+			return opts, fmt.Errorf("%s: %w", filename, err)
+		}
+		if opts.NewPerDay < 0 {
+			return opts, fmt.Errorf("%s: invalid new_per_day %d", filename,
+				opts.NewPerDay)
+		}
+		opts.Description = Markdown(opts.Description)
+	default:
+		opts.Description = Markdown(string(data))
+	}
+	return opts, nil
+}
+
+// NewDeckOptions returns the deck options read from the description
+// file (if set, as for LoadDeckOptions), with new cards per day set to
+// newPerDay if positive
+func NewDeckOptions(description string, newPerDay int) (DeckOptions, error) {
+	var opts DeckOptions
+	var err error
+	if description != "" {
+		opts, err = LoadDeckOptions(description)
+		if err != nil {
+			return opts, err
+		}
+	}
+	if newPerDay > 0 {
+		opts.NewPerDay = newPerDay
+	}
+	return opts, nil
+}
+
+// WriteDecksFile writes a JSON decks file to filename, listing each of
+// decks (deck names mapped to card counts) with opts. Relative
+// filenames are created in $MAG_OUTDIR if set, as with Create.
+func WriteDecksFile(filename string, decks map[string]int, opts DeckOptions) error {
+	names := make([]string, 0, len(decks))
+	for name := range decks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	infos := make([]DeckInfo, len(names))
+	for i, name := range names {
+		infos[i] = DeckInfo{Name: name, Cards: decks[name], DeckOptions: opts}
+	}
+
+	out, err := Create(filename, false, false)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	err = enc.Encode(struct {
+		Decks []DeckInfo `json:"decks"`
+	}{infos})
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
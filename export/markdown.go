@@ -0,0 +1,40 @@
+package export
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	reMdBold       = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	reMdItalicStar = regexp.MustCompile(`\*([^*]+)\*`)
+	reMdItalicUnd  = regexp.MustCompile(`(^|[\s(])_([^_]+)_([\s).,;:!?]|$)`)
+	reMdBreak      = regexp.MustCompile(` *\r?\n`)
+	reHTMLBreak    = regexp.MustCompile(`(?i)<br\s*/?>`)
+	reHTMLTag      = regexp.MustCompile(`<[^>]*>`)
+	reSpaces       = regexp.MustCompile(`\s+`)
+)
+
+// Markdown converts s, containing light Markdown (**bold**, *italics*
+// or _italics_, and line breaks), to HTML, escaping any other HTML
+// special characters
+func Markdown(s string) string {
+	s = html.EscapeString(strings.TrimSpace(s))
+	s = reMdBold.ReplaceAllString(s, "<b>$1</b>")
+	s = reMdItalicStar.ReplaceAllString(s, "<i>$1</i>")
+	s = reMdItalicUnd.ReplaceAllString(s, "$1<i>$2</i>$3")
+	return reMdBreak.ReplaceAllString(s, "<br>")
+}
+
+// PlainText returns s, containing light Markdown or HTML, as plain text
+// on a single line: with Markdown markers and HTML tags removed, HTML
+// entities unescaped, and whitespace collapsed
+func PlainText(s string) string {
+	s = reMdBold.ReplaceAllString(s, "$1")
+	s = reMdItalicStar.ReplaceAllString(s, "$1")
+	s = reMdItalicUnd.ReplaceAllString(s, "$1$2$3")
+	s = reHTMLTag.ReplaceAllString(reHTMLBreak.ReplaceAllString(s, " "), "")
+	s = html.UnescapeString(s)
+	return strings.TrimSpace(reSpaces.ReplaceAllString(s, " "))
+}
@@ -0,0 +1,235 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// manifestSuffix is appended to the tool name for the name of the
+// media manifest written to the media directory, so that exporters can
+// share a media directory
+const manifestSuffix = ".manifest.json"
+
+// MediaFile is a media manifest file entry
+type MediaFile struct {
+	Source string `json:"source,omitempty"` // source path, for copied files
+}
+
+// manifest is the media manifest, recording the media files used by
+// an export, the files used by each card, and any orphaned files not
+// yet removed
+type manifest struct {
+	Files   map[string]*MediaFile `json:"files"`
+	IDs     map[string][]string   `json:"ids"`
+	Orphans []string              `json:"orphans,omitempty"`
+}
+
+// Media collects the media files referenced by exported cards in Dir
+// (typically to be copied into the Anki collection.media directory),
+// recording them in a manifest
+type Media struct {
+	Dir   string
+	Tool  string                // exporter name, used to name the manifest
+	Files map[string]*MediaFile // by media filename
+	IDs   map[string][]string   // media filenames, by card id
+	// Unused are unused media files from previous exports, kept in the
+	// manifest until they are removed
+	Unused []string
+}
+
+// NewMedia returns a new Media collecting files in dir for tool
+func NewMedia(dir, tool string) *Media {
+	return &Media{
+		Dir:   dir,
+		Tool:  tool,
+		Files: make(map[string]*MediaFile),
+		IDs:   make(map[string][]string),
+	}
+}
+
+// ManifestPath returns the path of the media manifest
+func (m *Media) ManifestPath() string {
+	return filepath.Join(m.Dir, m.Tool+manifestSuffix)
+}
+
+// add records that card id uses the media file filename, copied from
+// src (or generated, if src is empty)
+func (m *Media) add(id, filename, src string) error {
+	f, exists := m.Files[filename]
+	if !exists {
+		m.Files[filename] = &MediaFile{Source: src}
+	} else if f.Source != src {
+		return fmt.Errorf("media filename %q used for both %q and %q",
+			filename, f.Source, src)
+	}
+	m.IDs[id] = append(m.IDs[id], filename)
+	return nil
+}
+
+// Copy copies the media file src into m.Dir for card id, returning its
+// media filename. Anki media directories are flat, so files are named
+// by their base name.
+func (m *Media) Copy(id, src string) (string, error) {
+	filename := filepath.Base(src)
+	if _, copied := m.Files[filename]; !copied {
+		err := copyFile(src, filepath.Join(m.Dir, filename))
+		if err != nil {
+			return "", err
+		}
+	}
+	return filename, m.add(id, filename, src)
+}
+
+// copyFile copies the file src to dst, creating dst's directory
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	err = os.MkdirAll(filepath.Dir(dst), 0o755)
+	if err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// ManifestPaths returns the paths of the media manifests in dir
+func ManifestPaths(dir string) ([]string, error) {
+	return filepath.Glob(filepath.Join(dir, "*"+manifestSuffix))
+}
+
+// ManifestFiles returns the media filenames recorded as used in the
+// media manifest path, sorted
+func ManifestFiles(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var man manifest
+	err = json.Unmarshal(data, &man)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	filenames := make([]string, 0, len(man.Files))
+	for filename := range man.Files {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	return filenames, nil
+}
+
+// readManifest reads the manifest written by a previous export, if any
+func (m *Media) readManifest() (*manifest, error) {
+	data, err := os.ReadFile(m.ManifestPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return &manifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var prev manifest
+	err = json.Unmarshal(data, &prev)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", m.ManifestPath(), err)
+	}
+	return &prev, nil
+}
+
+// Orphans returns the media files recorded in the previous manifest
+// that are no longer used (e.g. because their entries were removed),
+// and still exist in m.Dir, sorted. It should be called before
+// WriteManifest.
+func (m *Media) Orphans() ([]string, error) {
+	prev, err := m.readManifest()
+	if err != nil {
+		return nil, err
+	}
+	candidates := prev.Orphans
+	for filename := range prev.Files {
+		candidates = append(candidates, filename)
+	}
+	var orphans []string
+	for _, filename := range candidates {
+		if _, used := m.Files[filename]; used {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(m.Dir, filename)); err == nil {
+			orphans = append(orphans, filename)
+		}
+	}
+	sort.Strings(orphans)
+	return orphans, nil
+}
+
+// Remove removes the media files filenames from m.Dir
+func (m *Media) Remove(filenames []string) error {
+	for _, filename := range filenames {
+		err := os.Remove(filepath.Join(m.Dir, filename))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteManifest writes the manifest of media files to m.Dir, if any
+// media files were used or a previous manifest exists
+func (m *Media) WriteManifest() error {
+	_, err := os.Stat(m.ManifestPath())
+	if len(m.Files) == 0 && err != nil {
+		return nil
+	}
+	data, err := json.MarshalIndent(manifest{
+		Files: m.Files, IDs: m.IDs, Orphans: m.Unused,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(m.Dir, 0o755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.ManifestPath(), append(data, '\n'), 0o644)
+}
+
+// Finish logs orphaned media files, removing them if prune is set, and
+// then writes the manifest
+func (m *Media) Finish(prune bool) error {
+	orphans, err := m.Orphans()
+	if err != nil {
+		return err
+	}
+	if prune {
+		err = m.Remove(orphans)
+		if err != nil {
+			return err
+		}
+	} else {
+		m.Unused = orphans
+	}
+	for _, filename := range orphans {
+		if prune {
+			slog.Info("removed orphaned media file", "dir", m.Dir, "file", filename)
+		} else {
+			slog.Warn("orphaned media file (use --prune-media to remove)",
+				"dir", m.Dir, "file", filename)
+		}
+	}
+	return m.WriteManifest()
+}
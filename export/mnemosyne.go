@@ -0,0 +1,80 @@
+package export
+
+import (
+	"encoding/xml"
+	"html"
+	"io"
+	"regexp"
+)
+
+// reAnkiSound matches Anki [sound:...] references, as written by Audio
+var reAnkiSound = regexp.MustCompile(`\[sound:([^\]]+)\]`)
+
+// mnemosyneXML is a Mnemosyne 1.x XML file, which Mnemosyne 2 imports
+// (File > Import > Mnemosyne 1.x *.XML files)
+type mnemosyneXML struct {
+	XMLName     xml.Name            `xml:"mnemosyne"`
+	CoreVersion int                 `xml:"core_version,attr"`
+	Categories  []mnemosyneCategory `xml:"category"`
+	Items       []MnemosyneItem     `xml:"item"`
+}
+
+// mnemosyneCategory is a Mnemosyne 1.x category, which Mnemosyne 2
+// imports as a tag
+type mnemosyneCategory struct {
+	Active int    `xml:"active,attr"`
+	Name   string `xml:"name"`
+}
+
+// MnemosyneItem is a Mnemosyne card, with an HTML question and answer
+type MnemosyneItem struct {
+	ID       string `xml:"id,attr"`
+	Category string `xml:"cat"`
+	Q        string `xml:"Q"`
+	A        string `xml:"A"`
+}
+
+// MnemosyneItems returns the records collected by a Writer, in the
+// standard ID, Front, Back, Tags, DeckName column layout, as Mnemosyne
+// items in a category per deck. If isHTML is false the fronts and backs
+// are escaped as HTML. Anki [sound:...] references are converted to
+// <audio> tags, for media files copied to the Mnemosyne media directory.
+func MnemosyneItems(records [][]string, isHTML bool) []MnemosyneItem {
+	items := make([]MnemosyneItem, 0, len(records))
+	for _, record := range records {
+		front, back := record[1], record[2]
+		if !isHTML {
+			front, back = html.EscapeString(front), html.EscapeString(back)
+		}
+		back = reAnkiSound.ReplaceAllString(back, `<audio src="$1">`)
+		items = append(items, MnemosyneItem{
+			ID: record[0], Category: record[4], Q: front, A: back,
+		})
+	}
+	return items
+}
+
+// WriteMnemosyne writes items to wtr as a Mnemosyne 1.x XML file
+func WriteMnemosyne(wtr io.Writer, items []MnemosyneItem) error {
+	doc := mnemosyneXML{CoreVersion: 1, Items: items}
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if !seen[item.Category] {
+			seen[item.Category] = true
+			doc.Categories = append(doc.Categories,
+				mnemosyneCategory{Active: 1, Name: item.Category})
+		}
+	}
+	_, err := io.WriteString(wtr, xml.Header)
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(wtr)
+	enc.Indent("", "  ")
+	err = enc.Encode(doc)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(wtr, "\n")
+	return err
+}
@@ -0,0 +1,186 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Names of the Anki notetypes the mag exporters create notes for
+const (
+	NotetypeVocabGrEn  = "MAG Vocab GrEn"
+	NotetypeVocabCloze = "MAG Vocab Cloze"
+	NotetypePPGrEn     = "MAG PP GrEn"
+	NotetypePPEnGr     = "MAG PP EnGr"
+	NotetypePPCloze    = "MAG PP Cloze"
+	NotetypeParadigm   = "MAG Paradigm"
+	NotetypeSentence   = "MAG Sentence"
+	NotetypeExercise   = "MAG Exercise"
+	// TypedSuffix is appended to the names of type-in-the-answer
+	// variants of the basic notetypes
+	TypedSuffix = " Typed"
+)
+
+// Default notetype styling: a font stack with good polytonic Greek
+// coverage, and a larger size for the Greek on card fronts
+const (
+	DefaultFonts     = `"GFS Didot", "Gentium Plus", "New Athena Unicode", "Noto Serif", "Palatino Linotype", "Times New Roman", serif`
+	DefaultFontSize  = 20
+	DefaultFrontSize = 32
+)
+
+// Style configures the CSS of generated notetypes
+type Style struct {
+	Fonts     string // CSS font-family list
+	FontSize  int    // base font size, in px
+	FrontSize int    // font size of card fronts, in px
+}
+
+// DefaultStyle returns the default notetype Style
+func DefaultStyle() Style {
+	return Style{
+		Fonts:     DefaultFonts,
+		FontSize:  DefaultFontSize,
+		FrontSize: DefaultFrontSize,
+	}
+}
+
+// CSS returns the notetype styling for s (for a cloze notetype if cloze
+// is set)
+func (s Style) CSS(cloze bool) string {
+	css := fmt.Sprintf(`.card {
+  font-family: %s;
+  font-size: %dpx;
+  text-align: center;
+  color: black;
+  background-color: white;
+}
+
+.front {
+  font-size: %dpx;
+}
+
+.stem {
+  font-style: italic;
+  color: dimgray;
+}`, s.Fonts, s.FontSize, s.FrontSize)
+	if cloze {
+		css += "\n\n.cloze {\n  font-weight: bold;\n  color: blue;\n}"
+	}
+	return css
+}
+
+// Template is an Anki card template
+type Template struct {
+	Name  string `json:"name"`
+	Front string `json:"front"`
+	Back  string `json:"back"`
+}
+
+// Notetype is an Anki notetype definition, for notetypes that users
+// need to create in Anki before importing an export
+type Notetype struct {
+	Name      string     `json:"name"`
+	Cloze     bool       `json:"cloze"`
+	Fields    []string   `json:"fields"`
+	Templates []Template `json:"templates"`
+	CSS       string     `json:"css"`
+}
+
+// BasicNotetype returns a front/back notetype called name, with ID,
+// Front, and Back fields
+func BasicNotetype(name string, style Style) Notetype {
+	return Notetype{
+		Name:   name,
+		Fields: []string{"ID", "Front", "Back"},
+		Templates: []Template{{
+			Name:  "Card 1",
+			Front: `<div class="front">{{Front}}</div>`,
+			Back:  "{{FrontSide}}\n\n<hr id=answer>\n\n{{Back}}",
+		}},
+		CSS: style.CSS(false),
+	}
+}
+
+// TypedNotetype returns a type-in-the-answer notetype called name, with
+// ID, Front, and Back fields, requiring the Back field to be typed
+func TypedNotetype(name string, style Style) Notetype {
+	n := BasicNotetype(name, style)
+	n.Templates[0].Front = `<div class="front">{{Front}}</div>` + "\n\n{{type:Back}}"
+	n.Templates[0].Back = `<div class="front">{{Front}}</div>` +
+		"\n\n<hr id=answer>\n\n{{type:Back}}"
+	return n
+}
+
+// ClozeNotetype returns a cloze notetype called name, with ID, Text,
+// and Back Extra fields
+func ClozeNotetype(name string, style Style) Notetype {
+	return Notetype{
+		Name:   name,
+		Cloze:  true,
+		Fields: []string{"ID", "Text", "Back Extra"},
+		Templates: []Template{{
+			Name:  "Cloze",
+			Front: "{{cloze:Text}}",
+			Back:  "{{cloze:Text}}<br>\n{{Back Extra}}",
+		}},
+		CSS: style.CSS(true),
+	}
+}
+
+// Notetypes returns the definitions of all the mag notetypes, styled
+// with style
+func Notetypes(style Style) []Notetype {
+	return []Notetype{
+		BasicNotetype(NotetypeVocabGrEn, style),
+		TypedNotetype(NotetypeVocabGrEn+TypedSuffix, style),
+		ClozeNotetype(NotetypeVocabCloze, style),
+		BasicNotetype(NotetypePPGrEn, style),
+		BasicNotetype(NotetypePPEnGr, style),
+		TypedNotetype(NotetypePPGrEn+TypedSuffix, style),
+		TypedNotetype(NotetypePPEnGr+TypedSuffix, style),
+		ClozeNotetype(NotetypePPCloze, style),
+		BasicNotetype(NotetypeParadigm, style),
+		BasicNotetype(NotetypeSentence, style),
+		BasicNotetype(NotetypeExercise, style),
+	}
+}
+
+// FindNotetype returns the definition of the mag notetype called name,
+// styled with style
+func FindNotetype(name string, style Style) (Notetype, error) {
+	for _, n := range Notetypes(style) {
+		if n.Name == name {
+			return n, nil
+		}
+	}
+	return Notetype{}, fmt.Errorf("unknown notetype %q", name)
+}
+
+// Write writes a description of the notetype to wtr, for recreating
+// it in Anki (Tools > Manage Note Types)
+func (n Notetype) Write(wtr io.Writer) {
+	kind := "Standard"
+	if n.Cloze {
+		kind = "Cloze"
+	}
+	fmt.Fprintf(wtr, "Notetype: %s (%s)\n", n.Name, kind)
+	fmt.Fprintf(wtr, "Fields: %s\n", strings.Join(n.Fields, ", "))
+	for _, t := range n.Templates {
+		fmt.Fprintf(wtr, "\n%s front template:\n%s\n", t.Name, t.Front)
+		fmt.Fprintf(wtr, "\n%s back template:\n%s\n", t.Name, t.Back)
+	}
+	fmt.Fprintf(wtr, "\nStyling:\n%s\n", n.CSS)
+}
+
+// WriteNotetypesJSON writes the notetype definitions to wtr as JSON
+func WriteNotetypesJSON(wtr io.Writer, notetypes []Notetype) error {
+	enc := json.NewEncoder(wtr)
+	enc.SetIndent("", "  ")
+	// Keep template markup readable
+	enc.SetEscapeHTML(false)
+	return enc.Encode(struct {
+		Notetypes []Notetype `json:"notetypes"`
+	}{notetypes})
+}
@@ -0,0 +1,70 @@
+// Package export provides output helpers shared by the mag exporters
+
+package export
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Output is an export destination, which must be closed to flush any
+// compressed data
+type Output struct {
+	io.Writer
+	closers []io.Closer
+}
+
+// Close flushes and closes the output
+func (o *Output) Close() error {
+	var err error
+	for _, c := range o.closers {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	o.closers = nil
+	return err
+}
+
+// BOM is the UTF-8 byte order mark
+const BOM = "\uFEFF"
+
+// OutdirEnv is the environment variable naming the directory relative
+// output filenames are created in
+const OutdirEnv = "MAG_OUTDIR"
+
+// Create returns an Output writing to filename (or stdout if filename
+// is empty), gzip-compressed if compress is set or filename ends in .gz,
+// and starting with a UTF-8 BOM if bom is set. Relative filenames are
+// created in $MAG_OUTDIR if set.
+func Create(filename string, compress, bom bool) (*Output, error) {
+	o := &Output{Writer: os.Stdout}
+	if filename != "" {
+		if outdir := os.Getenv(OutdirEnv); outdir != "" && !filepath.IsAbs(filename) {
+			filename = filepath.Join(outdir, filename)
+		}
+		fh, err := os.Create(filename)
+		if err != nil {
+			return nil, err
+		}
+		o.Writer = fh
+		o.closers = []io.Closer{fh}
+	}
+	if compress || strings.HasSuffix(filename, ".gz") {
+		gz := gzip.NewWriter(o.Writer)
+		o.Writer = gz
+		// Close the gzip writer before the underlying file
+		o.closers = append([]io.Closer{gz}, o.closers...)
+	}
+	if bom {
+		_, err := io.WriteString(o.Writer, BOM)
+		if err != nil {
+			o.Close()
+			return nil, err
+		}
+	}
+	return o, nil
+}
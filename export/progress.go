@@ -0,0 +1,65 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// progressInterval is the minimum interval between progress updates
+const progressInterval = time.Second
+
+// Progress reports a periodic count of cards exported, for exports
+// that take a while. Nothing is written until the first interval has
+// passed, so quick exports stay silent.
+type Progress struct {
+	wtr     io.Writer
+	count   int
+	started time.Time
+	last    time.Time
+	shown   bool
+}
+
+// NewProgress returns a new Progress reporting to wtr
+func NewProgress(wtr io.Writer) *Progress {
+	now := time.Now()
+	return &Progress{wtr: wtr, started: now, last: now}
+}
+
+// StderrProgress returns a Progress reporting to stderr if it is a
+// terminal and quiet is not set, and nil otherwise
+func StderrProgress(quiet bool) *Progress {
+	if quiet {
+		return nil
+	}
+	info, err := os.Stderr.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+	return NewProgress(os.Stderr)
+}
+
+// Add adds n to the count of cards exported, reporting progress if
+// the update interval has passed. Add on a nil Progress does nothing.
+func (p *Progress) Add(n int) {
+	if p == nil {
+		return
+	}
+	p.count += n
+	if now := time.Now(); now.Sub(p.last) >= progressInterval {
+		p.last = now
+		p.shown = true
+		fmt.Fprintf(p.wtr, "\r%d card(s) exported (%s)", p.count,
+			now.Sub(p.started).Round(time.Second))
+	}
+}
+
+// Done finishes the progress report, if any was shown
+func (p *Progress) Done() {
+	if p == nil || !p.shown {
+		return
+	}
+	fmt.Fprintf(p.wtr, "\r%d card(s) exported (%s)\n", p.count,
+		time.Since(p.started).Round(time.Second))
+}
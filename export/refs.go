@@ -0,0 +1,72 @@
+package export
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// reRefSep matches the separators between references in a field
+	reRefSep = regexp.MustCompile(`[\pZ,;]+`)
+	// reListSep matches the separators between references that may
+	// contain spaces (e.g. "p. 145")
+	reListSep = regexp.MustCompile(`\pZ*[,;]\pZ*`)
+	// reMagPage matches Mastronarde page references, e.g. "p. 145"
+	reMagPage = regexp.MustCompile(`^pp?\.?\pZ*(\S+)$`)
+)
+
+// splitRefs splits the reference field s into its references, without
+// any prefix (e.g. "§")
+func splitRefs(s, prefix string) []string {
+	var refs []string
+	for _, ref := range reRefSep.Split(strings.TrimSpace(s), -1) {
+		ref = strings.TrimLeft(ref, prefix)
+		if ref != "" {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// SmythRefs parses a smyth: field of Smyth's Greek Grammar section
+// numbers (e.g. "§383, 385a"), returning the reference line for the
+// card back (e.g. "Smyth §383, §385a") and a smyth:: tag for each
+// section. Both are empty if s is.
+func SmythRefs(s string) (string, []string) {
+	refs := splitRefs(s, "§")
+	if len(refs) == 0 {
+		return "", nil
+	}
+	tags := make([]string, len(refs))
+	for i, ref := range refs {
+		tags[i] = "smyth::" + ref
+		refs[i] = "§" + ref
+	}
+	return "Smyth " + strings.Join(refs, ", "), tags
+}
+
+// MastronardeRefs parses a ref: field of Mastronarde textbook section
+// (unit.section, e.g. "12.3") and page (e.g. "p. 145") references,
+// returning the reference line for the card back (e.g. "Mastronarde
+// §12.3, p. 145") and a mag:: tag for each reference (e.g. mag::u12.3,
+// mag::p145). Both are empty if s is.
+func MastronardeRefs(s string) (string, []string) {
+	var refs, tags []string
+	for _, ref := range reListSep.Split(strings.TrimSpace(s), -1) {
+		if m := reMagPage.FindStringSubmatch(ref); m != nil {
+			refs = append(refs, "p. "+m[1])
+			tags = append(tags, "mag::p"+m[1])
+			continue
+		}
+		ref = strings.TrimLeft(ref, "§u")
+		if ref == "" {
+			continue
+		}
+		refs = append(refs, "§"+ref)
+		tags = append(tags, "mag::u"+ref)
+	}
+	if len(refs) == 0 {
+		return "", nil
+	}
+	return "Mastronarde " + strings.Join(refs, ", "), tags
+}
@@ -0,0 +1,29 @@
+package export
+
+import (
+	"strings"
+)
+
+// Separator is a CSV field separator supported by Anki
+type Separator struct {
+	Delim rune   // csv.Writer delimiter
+	Name  string // Anki "#separator:" header name
+}
+
+// Separators maps the --separator option choices to their separators
+var Separators = map[string]Separator{
+	"comma":     {',', "Comma"},
+	"semicolon": {';', "Semicolon"},
+	"tab":       {'\t', "Tab"},
+}
+
+// Header returns the Anki "#separator:" file header for s
+func (s Separator) Header() string {
+	return "#separator:" + s.Name
+}
+
+// Columns returns the Anki "#columns:" file header for columns,
+// separated by s
+func (s Separator) Columns(columns []string) string {
+	return "#columns:" + strings.Join(columns, string(s.Delim))
+}
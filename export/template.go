@@ -0,0 +1,86 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Templates holds optional user templates for the front and back of
+// exported cards. The templates are executed with exporter-specific
+// data, which includes the default Front and Back content.
+type Templates struct {
+	Front *template.Template
+	Back  *template.Template
+}
+
+// LoadTemplates parses the front and back template files, either of
+// which may be empty to keep the default content. It returns nil if
+// both are empty.
+func LoadTemplates(front, back string) (*Templates, error) {
+	if front == "" && back == "" {
+		return nil, nil
+	}
+	t := &Templates{}
+	var err error
+	t.Front, err = loadTemplate(front)
+	if err != nil {
+		return nil, err
+	}
+	t.Back, err = loadTemplate(back)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// loadTemplate parses the template file filename, if set
+func loadTemplate(filename string) (*template.Template, error) {
+	if filename == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(filepath.Base(filename)).
+		Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// execute executes tmpl with data, returning def if tmpl is nil
+func execute(tmpl *template.Template, data interface{}, def string) (string, error) {
+	if tmpl == nil {
+		return def, nil
+	}
+	var b strings.Builder
+	err := tmpl.Execute(&b, data)
+	if err != nil {
+		return "", err
+	}
+	// Template files usually end with a newline
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// Apply returns the card front and back rendered from data by t, with
+// front and back the defaults for any templates not set. A nil t
+// returns the defaults unchanged.
+func (t *Templates) Apply(data interface{}, front, back string) (string, string, error) {
+	if t == nil {
+		return front, back, nil
+	}
+	f, err := execute(t.Front, data, front)
+	if err != nil {
+		return "", "", err
+	}
+	b, err := execute(t.Back, data, back)
+	if err != nil {
+		return "", "", err
+	}
+	return f, b, nil
+}
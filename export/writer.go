@@ -0,0 +1,88 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Writer writes cards as Anki CSV records, counting the cards written
+// to each deck and collecting any warnings raised during the export
+type Writer struct {
+	*csv.Writer
+	deckColumn int // 1-based deck column position
+	Decks      map[string]int
+	Warnings   []string
+	Strict     bool      // treat warnings as errors
+	Progress   *Progress // progress reporter, if any
+	Collect    bool      // collect records in Records, instead of writing them
+	Records    [][]string
+}
+
+// NewWriter returns a new Writer writing records separated by sep to w,
+// with the deck name in (1-based) column deckColumn
+func NewWriter(w io.Writer, sep Separator, deckColumn int) *Writer {
+	cwtr := csv.NewWriter(w)
+	cwtr.Comma = sep.Delim
+	return &Writer{
+		Writer:     cwtr,
+		deckColumn: deckColumn,
+		Decks:      make(map[string]int),
+	}
+}
+
+// Write writes a single card record, or collects it if w.Collect is set
+func (w *Writer) Write(record []string) error {
+	if w.deckColumn > 0 && w.deckColumn <= len(record) {
+		w.Decks[record[w.deckColumn-1]]++
+	}
+	w.Progress.Add(1)
+	if w.Collect {
+		w.Records = append(w.Records, record)
+		return nil
+	}
+	return w.Writer.Write(record)
+}
+
+// Warnf records a warning about the export. In strict mode the warning
+// is returned as an error instead, so the export can be aborted.
+func (w *Writer) Warnf(format string, args ...interface{}) error {
+	if w.Strict {
+		return fmt.Errorf("%s (aborting in strict mode)",
+			fmt.Sprintf(format, args...))
+	}
+	w.Warnings = append(w.Warnings, fmt.Sprintf(format, args...))
+	return nil
+}
+
+// Cards returns the total number of cards written
+func (w *Writer) Cards() int {
+	total := 0
+	for _, n := range w.Decks {
+		total += n
+	}
+	return total
+}
+
+// WriteWarnings writes any warnings to wtr, one per line
+func (w *Writer) WriteWarnings(wtr io.Writer) {
+	for _, warning := range w.Warnings {
+		fmt.Fprintf(wtr, "Warning: %s\n", warning)
+	}
+}
+
+// WriteSummary writes a summary of the cards written per deck, and any
+// warnings, to wtr
+func (w *Writer) WriteSummary(wtr io.Writer) {
+	decks := make([]string, 0, len(w.Decks))
+	for deck := range w.Decks {
+		decks = append(decks, deck)
+	}
+	sort.Strings(decks)
+	fmt.Fprintf(wtr, "%d card(s) in %d deck(s):\n", w.Cards(), len(decks))
+	for _, deck := range decks {
+		fmt.Fprintf(wtr, "  %s: %d\n", deck, w.Decks[deck])
+	}
+	w.WriteWarnings(wtr)
+}
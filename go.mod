@@ -1,9 +1,12 @@
 module github.com/gavincarr/mag
 
-go 1.18
+go 1.21
 
 require (
-	github.com/jessevdk/go-flags v1.5.0 // indirect
-	golang.org/x/sys v0.0.0-20210320140829-1e4c9ba3b0c4 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	github.com/fsnotify/fsnotify v1.7.0
+	github.com/jessevdk/go-flags v1.5.0
+	golang.org/x/text v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
 )
+
+require golang.org/x/sys v0.5.0 // indirect
@@ -0,0 +1,164 @@
+package greek
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	acute = '́'
+	grave = '̀'
+)
+
+// Tolerance is the differences from the expected answer that CheckAnswer
+// accepts
+type Tolerance struct {
+	Accents    bool // missing, extra, or wrong accents
+	Breathings bool // missing, extra, or wrong breathings
+	FinalSigma bool // σ for ς at the end of a word, or ς for σ elsewhere
+}
+
+// Diff kinds, for AnswerDiff.Kind
+const (
+	DiffAccent     = "accent"
+	DiffBreathing  = "breathing"
+	DiffIota       = "iota subscript"
+	DiffDiaeresis  = "diaeresis"
+	DiffFinalSigma = "final sigma"
+)
+
+// AnswerDiff is a difference in diacritics (or sigma form) between a
+// typed answer and the expected answer, at a letter they share
+type AnswerDiff struct {
+	Kind      string
+	Letter    rune   // expected letter, without diacritics
+	Position  int    // letter number in the answer, from 1
+	Want, Got string // names of the expected and typed diacritics
+	Tolerated bool   // accepted by the Tolerance
+}
+
+func (d AnswerDiff) String() string {
+	return fmt.Sprintf("%s on letter %d (%c): expected %s, typed %s",
+		d.Kind, d.Position, d.Letter, d.Want, d.Got)
+}
+
+// answerCluster is a letter of an answer with its combining diacritics
+type answerCluster struct {
+	letter rune // lowercase, with ς as σ
+	final  bool // written as ς
+	marks  []rune
+}
+
+// answerClusters splits s into lowercase letters with their diacritics,
+// ignoring case, and collapsing and trimming whitespace. A capital Σ at
+// the end of a word counts as final, since it has no separate final form.
+func answerClusters(s string) []answerCluster {
+	var clusters []answerCluster
+	var capitalSigmas []int
+	for _, r := range norm.NFD.String(strings.Join(strings.Fields(s), " ")) {
+		if unicode.Is(unicode.Mn, r) && len(clusters) > 0 {
+			c := &clusters[len(clusters)-1]
+			c.marks = append(c.marks, r)
+			continue
+		}
+		c := answerCluster{letter: unicode.ToLower(r)}
+		if c.letter == 'ς' {
+			c.letter, c.final = 'σ', true
+		}
+		if r == 'Σ' {
+			capitalSigmas = append(capitalSigmas, len(clusters))
+		}
+		clusters = append(clusters, c)
+	}
+	for _, i := range capitalSigmas {
+		clusters[i].final = i+1 == len(clusters) || !unicode.IsLetter(clusters[i+1].letter)
+	}
+	return clusters
+}
+
+// markNames returns the names of the marks of c in the set names, or
+// "none"
+func (c answerCluster) markNames(names map[rune]string) string {
+	var found []string
+	for _, m := range c.marks {
+		if name, ok := names[m]; ok {
+			found = append(found, name)
+		}
+	}
+	if len(found) == 0 {
+		return "none"
+	}
+	return strings.Join(found, "+")
+}
+
+// answerMarks are the diacritics compared by CheckAnswer, by diff kind
+var answerMarks = []struct {
+	kind  string
+	names map[rune]string
+}{
+	{DiffAccent, map[rune]string{acute: "acute", grave: "grave", perispomeni: "circumflex"}},
+	{DiffBreathing, map[rune]string{smoothBreathing: "smooth", roughBreathing: "rough"}},
+	{DiffIota, map[rune]string{ypogegrammeni: "iota subscript"}},
+	{DiffDiaeresis, map[rune]string{diaeresis: "diaeresis"}},
+}
+
+// CheckAnswer reports whether the typed answer got matches the expected
+// answer want, allowing the differences in tol, and returns the
+// differences in diacritics and sigma forms it found. Case and extra
+// whitespace are ignored. If the letters themselves differ, the answer
+// is wrong, and no differences are returned.
+func CheckAnswer(got, want string, tol Tolerance) (bool, []AnswerDiff) {
+	g, w := answerClusters(got), answerClusters(want)
+	if len(g) != len(w) {
+		return false, nil
+	}
+	for i := range w {
+		if g[i].letter != w[i].letter {
+			return false, nil
+		}
+	}
+
+	ok := true
+	var diffs []AnswerDiff
+	position := 0
+	for i := range w {
+		if unicode.IsLetter(w[i].letter) {
+			position++
+		}
+		diff := AnswerDiff{Letter: w[i].letter, Position: position}
+		if w[i].final {
+			diff.Letter = 'ς'
+		}
+		if g[i].final != w[i].final {
+			d := diff
+			d.Kind, d.Want, d.Got, d.Tolerated = DiffFinalSigma, sigmaName(w[i].final), sigmaName(g[i].final), tol.FinalSigma
+			diffs = append(diffs, d)
+		}
+		for _, marks := range answerMarks {
+			wantNames, gotNames := w[i].markNames(marks.names), g[i].markNames(marks.names)
+			if wantNames == gotNames {
+				continue
+			}
+			d := diff
+			d.Kind, d.Want, d.Got = marks.kind, wantNames, gotNames
+			d.Tolerated = (marks.kind == DiffAccent && tol.Accents) ||
+				(marks.kind == DiffBreathing && tol.Breathings)
+			diffs = append(diffs, d)
+		}
+	}
+	for _, d := range diffs {
+		ok = ok && d.Tolerated
+	}
+	return ok, diffs
+}
+
+// sigmaName returns the name of the sigma form, final if final is set
+func sigmaName(final bool) string {
+	if final {
+		return "ς"
+	}
+	return "σ"
+}
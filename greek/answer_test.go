@@ -0,0 +1,66 @@
+package greek
+
+import (
+	"testing"
+)
+
+func TestCheckAnswer(t *testing.T) {
+	strict := Tolerance{}
+	lenient := Tolerance{Accents: true, Breathings: true, FinalSigma: true}
+	tests := []struct {
+		got, want string
+		tol       Tolerance
+		ok        bool
+		kinds     []string // kinds of the differences found
+	}{
+		{"λόγος", "λόγος", strict, true, nil},
+		{"  ΛΌΓΟΣ ", "λόγος", strict, true, nil},
+		{"ΣΟΦΌΣ ΛΌΓΟΣ", "σοφός λόγος", strict, true, nil},
+		{"Λόγος", "λόγος", strict, true, nil},
+		{"λογος", "λόγος", strict, false, []string{DiffAccent}},
+		{"λογος", "λόγος", lenient, true, []string{DiffAccent}},
+		{"λὸγος", "λόγος", strict, false, []string{DiffAccent}},
+		{"αγω", "ἄγω", Tolerance{Accents: true}, false, []string{DiffAccent, DiffBreathing}},
+		{"αγω", "ἄγω", lenient, true, []string{DiffAccent, DiffBreathing}},
+		{"λόγοσ", "λόγος", strict, false, []string{DiffFinalSigma}},
+		{"λόγοσ", "λόγος", lenient, true, []string{DiffFinalSigma}},
+		{"λόγῳ", "λόγω", lenient, false, []string{DiffIota}},
+		{"λόγω", "λόγῳ", lenient, false, []string{DiffIota}},
+		{"προϊέναι", "προιέναι", lenient, false, []string{DiffDiaeresis}},
+		// Different letters are wrong, without differences
+		{"λόγου", "λόγος", lenient, false, nil},
+		{"λόγο", "λόγος", lenient, false, nil},
+		// NFC and NFD input compare the same
+		{"λο\u0301γος", "λόγος", strict, true, nil},
+	}
+
+	for _, tc := range tests {
+		ok, diffs := CheckAnswer(tc.got, tc.want, tc.tol)
+		if ok != tc.ok {
+			t.Errorf("CheckAnswer(%q, %q, %+v): got %v, want %v (%v)",
+				tc.got, tc.want, tc.tol, ok, tc.ok, diffs)
+		}
+		if len(diffs) != len(tc.kinds) {
+			t.Errorf("CheckAnswer(%q, %q): got diffs %v, want kinds %v",
+				tc.got, tc.want, diffs, tc.kinds)
+			continue
+		}
+		for i, d := range diffs {
+			if d.Kind != tc.kinds[i] {
+				t.Errorf("CheckAnswer(%q, %q): diff %d: got %s, want %s",
+					tc.got, tc.want, i, d.Kind, tc.kinds[i])
+			}
+		}
+	}
+}
+
+func TestAnswerDiffPosition(t *testing.T) {
+	_, diffs := CheckAnswer("ανθρωπος", "ἄνθρωπος", Tolerance{})
+	if len(diffs) != 2 {
+		t.Fatalf("got %d diffs, want 2: %v", len(diffs), diffs)
+	}
+	want := "accent on letter 1 (α): expected acute, typed none"
+	if got := diffs[0].String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
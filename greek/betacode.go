@@ -0,0 +1,60 @@
+package greek
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// betaLetters maps lowercase Greek letters to their Beta Code letters
+var betaLetters = map[rune]string{
+	'α': "a", 'β': "b", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "h",
+	'θ': "q", 'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "c",
+	'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "u",
+	'φ': "f", 'χ': "x", 'ψ': "y", 'ω': "w",
+}
+
+// betaMarks maps combining diacritics to their Beta Code symbols
+var betaMarks = map[rune]string{
+	'\u0313': ")", '\u0314': "(", '\u0301': "/", '\u0300': "\\",
+	'\u0342': "=", '\u0308': "+", '\u0345': "|",
+}
+
+// BetaCode returns s transliterated into (TLG/Perseus) Beta Code, e.g.
+// "a)gaqo/s" for ἀγαθός, with capitals marked by a leading * (and their
+// breathings and accents following it, e.g. "*)aqh=nai" for Ἀθῆναι).
+// Characters other than Greek letters and diacritics are kept as is.
+func BetaCode(s string) string {
+	var b strings.Builder
+	runes := []rune(norm.NFD.String(s))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		lower := unicode.ToLower(r)
+		letter, ok := betaLetters[lower]
+		if !ok {
+			if mark, ok := betaMarks[r]; ok {
+				b.WriteString(mark)
+			} else {
+				b.WriteRune(r)
+			}
+			continue
+		}
+		if lower == r {
+			b.WriteString(letter)
+			continue
+		}
+		// Capitals take their diacritics before the letter
+		b.WriteByte('*')
+		for i+1 < len(runes) {
+			mark, ok := betaMarks[runes[i+1]]
+			if !ok {
+				break
+			}
+			b.WriteString(mark)
+			i++
+		}
+		b.WriteString(letter)
+	}
+	return b.String()
+}
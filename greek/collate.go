@@ -0,0 +1,168 @@
+package greek
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	separatorWeight = 0
+	nonGreekWeight  = 1000
+)
+
+var (
+	// letterVariants maps letter variants, archaic letters and ligatures
+	// to the standard letter(s) they are collated as
+	letterVariants = map[rune]string{
+		'ς': "σ",
+		'ϲ': "σ", // lunate sigma
+		'ϐ': "β",
+		'ϑ': "θ",
+		'ϕ': "φ",
+		'ϖ': "π",
+		'ϰ': "κ",
+		'ϱ': "ρ",
+		'ϵ': "ε",
+		'ϛ': "στ", // stigma
+		'ȣ': "ου", // ou ligature
+	}
+)
+
+// collationKey is the multi-level sort key for a string: primary weights
+// are the base letters (with runs of non-letters collapsed to a single
+// separator), secondary keys are the diacritics on each letter, and
+// tertiary keys flag uppercase letters
+type collationKey struct {
+	primary   []int
+	secondary []string
+	tertiary  []bool
+}
+
+// letterWeight returns the primary collation weight for lowercase rune r
+func letterWeight(r rune) int {
+	switch {
+	case r >= 'α' && r <= 'ω':
+		return int(r-'α'+1) * 2
+	case r == 'ϝ':
+		// Digamma collates in its original alphabetic position after epsilon
+		return int('ε'-'α'+1)*2 + 1
+	}
+	return nonGreekWeight + int(r)
+}
+
+func newCollationKey(s string) collationKey {
+	var k collationKey
+	separator := false
+	lastLetter := false
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			if lastLetter {
+				k.secondary[len(k.secondary)-1] += string(r)
+			}
+			continue
+		}
+		if !unicode.IsLetter(r) {
+			separator = len(k.primary) > 0
+			lastLetter = false
+			continue
+		}
+
+		if separator {
+			k.primary = append(k.primary, separatorWeight)
+			k.secondary = append(k.secondary, "")
+			k.tertiary = append(k.tertiary, false)
+			separator = false
+		}
+		lower := unicode.ToLower(r)
+		letters := string(lower)
+		if variant, ok := letterVariants[lower]; ok {
+			letters = variant
+		}
+		for _, l := range letters {
+			k.primary = append(k.primary, letterWeight(l))
+			k.secondary = append(k.secondary, "")
+			k.tertiary = append(k.tertiary, lower != r)
+		}
+		lastLetter = true
+	}
+	return k
+}
+
+func compareInts(a, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return compareLengths(len(a), len(b))
+}
+
+func compareStrings(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := strings.Compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return compareLengths(len(a), len(b))
+}
+
+func compareBools(a, b []bool) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return compareLengths(len(a), len(b))
+}
+
+func compareLengths(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Compare compares a and b in Greek dictionary order, returning -1, 0,
+// or +1. Breathings, accents, iota subscripts and case are ignored
+// unless the strings are otherwise identical, final sigma collates as
+// sigma, and ligatures like stigma collate as their component letters
+func Compare(a, b string) int {
+	if a == b {
+		return 0
+	}
+	ka, kb := newCollationKey(a), newCollationKey(b)
+	if c := compareInts(ka.primary, kb.primary); c != 0 {
+		return c
+	}
+	if c := compareStrings(ka.secondary, kb.secondary); c != 0 {
+		return c
+	}
+	if c := compareBools(ka.tertiary, kb.tertiary); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// Less reports whether a sorts before b in Greek dictionary order
+func Less(a, b string) bool {
+	return Compare(a, b) < 0
+}
+
+// Sort sorts ss in place in Greek dictionary order
+func Sort(ss []string) {
+	sort.SliceStable(ss, func(i, j int) bool {
+		return Less(ss[i], ss[j])
+	})
+}
@@ -0,0 +1,52 @@
+package greek
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"ἄλφα", "βῆτα", -1},
+		{"ὠμός", "ἀγαθός", 1},
+		// Diacritics only break ties between the same letters
+		{"ἄλφα", "ἀλφ", 1},
+		{"αλφα", "ἄλφα", -1},
+		{"ᾅδης", "ἅδης", 1},
+		// Final and lunate sigmas collate as σ
+		{"λόγος", "λόγοσα", -1},
+		{"λόγος", "λόγοτ", -1},
+		{"ϲοφός", "σοφόν", 1},
+		// Case only breaks ties
+		{"Ἀθῆναι", "ἀθάνατος", 1},
+		{"ος", "Ος", -1},
+		// Stigma collates as στ
+		{"ϛ", "στ", 1},
+		// Separators sort before letters
+		{"ἀ-", "ἀλ", -1},
+		{"ἀγαθός, -ή, -όν", "ἀγαθότης", -1},
+		// Digamma sorts after ε
+		{"ϝ", "ε", 1},
+		{"ϝ", "ζ", -1},
+	}
+	for _, tc := range tests {
+		if got := Compare(tc.a, tc.b); got != tc.want {
+			t.Errorf("Compare(%q, %q): got %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+		if rev := Compare(tc.b, tc.a); rev != -tc.want {
+			t.Errorf("Compare(%q, %q): got %d, want %d", tc.b, tc.a, rev, -tc.want)
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	ss := []string{"σοφός", "ὅς", "βῆτα", "ἄλφα", "ος", "ἀ-", "ἄγω", "Ος", "ᾅδης", "ἅδης"}
+	want := []string{"ἀ-", "ἄγω", "ἅδης", "ᾅδης", "ἄλφα", "βῆτα", "ος", "Ος", "ὅς", "σοφός"}
+	Sort(ss)
+	if !slices.Equal(ss, want) {
+		t.Errorf("Sort: got %v, want %v", ss, want)
+	}
+}
@@ -0,0 +1,149 @@
+package greek
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var (
+	// LatinHomoglyphs maps Latin letters to the Greek letters they are
+	// visually indistinguishable from, which FixLatinHomoglyphs replaces
+	LatinHomoglyphs = map[rune]rune{
+		'A': 'Α', 'B': 'Β', 'E': 'Ε', 'Z': 'Ζ', 'H': 'Η', 'I': 'Ι',
+		'K': 'Κ', 'M': 'Μ', 'N': 'Ν', 'O': 'Ο', 'P': 'Ρ', 'T': 'Τ',
+		'X': 'Χ', 'Y': 'Υ',
+		'a': 'α', 'i': 'ι', 'k': 'κ', 'o': 'ο', 'p': 'ρ', 'x': 'χ',
+	}
+
+	// LatinMistypings maps Latin letters to the Greek letters they are
+	// commonly mistyped for, but don't look like closely enough to be
+	// replaced automatically
+	LatinMistypings = map[rune]rune{
+		'n': 'η', 'u': 'υ', 'v': 'ν', 'y': 'γ', 'w': 'ω',
+	}
+)
+
+// IsGreekLetter reports whether r is a Greek letter
+func IsGreekLetter(r rune) bool {
+	return unicode.IsLetter(r) && unicode.Is(unicode.Greek, r)
+}
+
+// isWordRune reports whether r is part of a word (a letter or a
+// combining mark)
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.Is(unicode.Mn, r)
+}
+
+// mapWords calls fn on each word (run of letters and combining marks)
+// in s, passing the rune following the word (or 0 at the end of s),
+// and returns s with each word replaced by fn's result
+func mapWords(s string, fn func(word []rune, next rune) []rune) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		if !isWordRune(runes[i]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && isWordRune(runes[j]) {
+			j++
+		}
+		var next rune
+		if j < len(runes) {
+			next = runes[j]
+		}
+		b.WriteString(string(fn(runes[i:j], next)))
+		i = j
+	}
+	return b.String()
+}
+
+// hasGreek reports whether word contains any Greek letters
+func hasGreek(word []rune) bool {
+	for _, r := range word {
+		if IsGreekLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// FixFinalSigma returns s with sigmas in Greek words corrected, using
+// final sigma (ς) at the end of a word and σ elsewhere. Sigmas before
+// a hyphen (as in stems like "λυσ-") are left as σ.
+func FixFinalSigma(s string) string {
+	return mapWords(s, func(word []rune, next rune) []rune {
+		out := make([]rune, len(word))
+		copy(out, word)
+		for i, r := range out {
+			last := i == len(out)-1 && next != '-'
+			if r == 'σ' && last && len(out) > 1 {
+				out[i] = 'ς'
+			} else if r == 'ς' && !last {
+				out[i] = 'σ'
+			}
+		}
+		return out
+	})
+}
+
+// FixLatinHomoglyphs returns s with any Latin letters inside otherwise
+// Greek words replaced by their Greek lookalikes. Words without any
+// Greek letters are left unchanged.
+func FixLatinHomoglyphs(s string) string {
+	return mapWords(s, func(word []rune, next rune) []rune {
+		if !hasGreek(word) {
+			return word
+		}
+		out := make([]rune, len(word))
+		for i, r := range word {
+			if g, ok := LatinHomoglyphs[r]; ok {
+				out[i] = g
+			} else {
+				out[i] = r
+			}
+		}
+		return out
+	})
+}
+
+// NonGreekLetters returns the distinct non-Greek letters found in s, in
+// order of first occurrence. Whole words listed in except (e.g. "or"
+// in principal part alternates) are ignored.
+func NonGreekLetters(s string, except ...string) []rune {
+	skip := make(map[string]bool)
+	for _, e := range except {
+		skip[e] = true
+	}
+	found := []rune{}
+	seen := make(map[rune]bool)
+	mapWords(s, func(word []rune, next rune) []rune {
+		if skip[string(word)] {
+			return word
+		}
+		for _, r := range word {
+			if unicode.IsLetter(r) && !IsGreekLetter(r) && !seen[r] {
+				seen[r] = true
+				found = append(found, r)
+			}
+		}
+		return word
+	})
+	return found
+}
+
+// DescribeRune returns a description of r including its codepoint, and
+// the Greek letter it looks like if it is a Latin homoglyph, or may have
+// been mistyped for
+func DescribeRune(r rune) string {
+	if g, ok := LatinHomoglyphs[r]; ok {
+		return fmt.Sprintf("%q (U+%04X, Latin homoglyph of %q)", r, r, g)
+	}
+	if g, ok := LatinMistypings[r]; ok {
+		return fmt.Sprintf("%q (U+%04X, Latin letter, perhaps for %q)", r, r, g)
+	}
+	return fmt.Sprintf("%q (U+%04X)", r, r)
+}
@@ -0,0 +1,39 @@
+// Package greek provides helpers for working with the polytonic Greek
+// text used in the mag datasets
+
+package greek
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// StripDiacritics returns s with all accents, breathings, diaereses and
+// iota subscripts removed (the result is NFC-normalised)
+func StripDiacritics(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return norm.NFC.String(b.String())
+}
+
+// StripAccents returns s with its accents (acute, grave, and
+// circumflex) removed, keeping breathings, diaereses and iota
+// subscripts (the result is NFC-normalised)
+func StripAccents(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		switch r {
+		case '\u0300', '\u0301', '\u0342':
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return norm.NFC.String(b.String())
+}
@@ -0,0 +1,85 @@
+package greek
+
+import (
+	"sort"
+	"strings"
+)
+
+// Preverb is a prepositional prefix of compound verbs, with its forms
+// (without diacritics) before consonants, and elided, aspirated, or
+// assimilated before other sounds
+type Preverb struct {
+	Prefix string
+	Forms  []string
+}
+
+// Preverbs are the common preverbs of Attic compound verbs
+var Preverbs = []Preverb{
+	{"ἀμφι-", []string{"αμφι", "αμφ"}},
+	{"ἀνα-", []string{"ανα", "αν"}},
+	{"ἀντι-", []string{"αντι", "αντ", "ανθ"}},
+	{"ἀπο-", []string{"απο", "απ", "αφ"}},
+	{"δια-", []string{"δια", "δι"}},
+	{"εἰσ-", []string{"εισ"}},
+	{"ἐκ-", []string{"εκ", "εξ"}},
+	{"ἐν-", []string{"εν", "εμ", "εγ", "ελ"}},
+	{"ἐπι-", []string{"επι", "επ", "εφ"}},
+	{"κατα-", []string{"κατα", "κατ", "καθ"}},
+	{"μετα-", []string{"μετα", "μετ", "μεθ"}},
+	{"παρα-", []string{"παρα", "παρ"}},
+	{"περι-", []string{"περι"}},
+	{"προ-", []string{"προ"}},
+	{"προσ-", []string{"προσ"}},
+	{"συν-", []string{"συν", "συμ", "συλ", "συγ", "συσ", "συρ", "συ"}},
+	{"ὑπερ-", []string{"υπερ"}},
+	{"ὑπο-", []string{"υπο", "υπ", "υφ"}},
+}
+
+// preverbForm is a single preverb form, for matching
+type preverbForm struct {
+	form   string
+	prefix string
+}
+
+// preverbForms are all the preverb forms, longest first
+var preverbForms = func() []preverbForm {
+	var forms []preverbForm
+	for _, p := range Preverbs {
+		for _, form := range p.Forms {
+			forms = append(forms, preverbForm{form, p.Prefix})
+		}
+	}
+	sort.SliceStable(forms, func(i, j int) bool {
+		return len(forms[i].form) > len(forms[j].form)
+	})
+	return forms
+}()
+
+// minSimplexLength is the minimum length in bytes of a simplex verb
+// remainder, to avoid matching short verbs as compounds
+const minSimplexLength = 6
+
+// StripKey returns the matching key of a Greek word: lowercase, without
+// diacritics, and with final sigmas made medial
+func StripKey(s string) string {
+	return strings.ReplaceAll(strings.ToLower(StripDiacritics(s)), "ς", "σ")
+}
+
+// SplitCompound splits the compound verb into its preverb prefix (e.g.
+// "ἀπο-") and the key (see StripKey) of its simplex verb, trying each
+// preverb form, longest first, until isSimplex accepts the remainder.
+// It returns false if verb isn't a compound of a simplex isSimplex
+// accepts.
+func SplitCompound(verb string, isSimplex func(key string) bool) (string, string, bool) {
+	key := StripKey(verb)
+	for _, p := range preverbForms {
+		rest, ok := strings.CutPrefix(key, p.form)
+		if !ok || len(rest) < minSimplexLength {
+			continue
+		}
+		if isSimplex(rest) {
+			return p.prefix, rest, true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,125 @@
+package greek
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	roughBreathing  = '̔'
+	smoothBreathing = '̓'
+	perispomeni     = '͂'
+	ypogegrammeni   = 'ͅ'
+	diaeresis       = '̈'
+	combCircumflex  = '̂'
+)
+
+// translitLetters maps lowercase Greek letters to their romanizations
+var translitLetters = map[rune]string{
+	'α': "a", 'β': "b", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "ē",
+	'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x",
+	'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y",
+	'φ': "ph", 'χ': "ch", 'ψ': "ps", 'ω': "ō",
+}
+
+// translitCluster is a letter with its combining diacritics
+type translitCluster struct {
+	letter rune // lowercase letter
+	upper  bool
+	marks  []rune
+	greek  bool
+}
+
+// has reports whether c has the combining mark
+func (c translitCluster) has(mark rune) bool {
+	for _, m := range c.marks {
+		if m == mark {
+			return true
+		}
+	}
+	return false
+}
+
+// isVowel reports whether the lowercase Greek letter r is a vowel
+func isVowel(r rune) bool {
+	return strings.ContainsRune("αεηιουω", r)
+}
+
+// Transliterate returns s romanized, for use as a pronunciation guide
+// (e.g. "lógos" for λόγος, "hēdýs" for ἡδύς): η and ω are written ē and
+// ō, υ as y (but u in diphthongs), γ before a velar as n, rough
+// breathings as h, and iota subscripts as i. Accents are kept on the
+// romanized vowels (the circumflex as ^), and other characters as is.
+func Transliterate(s string) string {
+	var clusters []translitCluster
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) && len(clusters) > 0 {
+			c := &clusters[len(clusters)-1]
+			c.marks = append(c.marks, r)
+			continue
+		}
+		lower := unicode.ToLower(r)
+		_, greek := translitLetters[lower]
+		if !greek {
+			lower = r
+		}
+		clusters = append(clusters, translitCluster{
+			letter: lower, upper: greek && lower != r, greek: greek,
+		})
+	}
+
+	var b strings.Builder
+	for i, c := range clusters {
+		if !c.greek {
+			b.WriteRune(c.letter)
+			for _, m := range c.marks {
+				b.WriteRune(m)
+			}
+			continue
+		}
+		prev, next := translitCluster{}, translitCluster{}
+		if i > 0 {
+			prev = clusters[i-1]
+		}
+		if i+1 < len(clusters) {
+			next = clusters[i+1]
+		}
+
+		lat := translitLetters[c.letter]
+		switch {
+		case c.letter == 'γ' && strings.ContainsRune("γκξχ", next.letter):
+			lat = "n"
+		case c.letter == 'υ' && strings.ContainsRune("αεηο", prev.letter) && !c.has(diaeresis):
+			lat = "u"
+		case c.letter == 'ρ' && c.has(roughBreathing):
+			lat = "rh"
+		}
+		// A word-initial rough breathing is on its first vowel, or on the
+		// second vowel of an initial diphthong
+		wordStart := !prev.greek
+		if wordStart && isVowel(c.letter) && (c.has(roughBreathing) ||
+			(isVowel(next.letter) && next.has(roughBreathing))) {
+			lat = "h" + lat
+		}
+		if c.upper {
+			lat = strings.ToUpper(lat[:1]) + lat[1:]
+		}
+		b.WriteString(lat)
+
+		for _, m := range c.marks {
+			switch m {
+			case roughBreathing, smoothBreathing, ypogegrammeni:
+			case perispomeni:
+				b.WriteRune(combCircumflex)
+			default:
+				b.WriteRune(m)
+			}
+		}
+		if c.has(ypogegrammeni) {
+			b.WriteByte('i')
+		}
+	}
+	return norm.NFC.String(b.String())
+}
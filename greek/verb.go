@@ -0,0 +1,81 @@
+package greek
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Verb classes, as used in vclass: fields and vclass:: tags
+const (
+	VerbOmega     = "omega"
+	VerbContractA = "contract-a"
+	VerbContractE = "contract-e"
+	VerbContractO = "contract-o"
+	VerbMi        = "mi"
+	VerbDeponent  = "deponent"
+)
+
+// VerbClassNames are the valid verb classes
+var VerbClassNames = []string{
+	VerbOmega, VerbContractA, VerbContractE, VerbContractO, VerbMi, VerbDeponent,
+}
+
+// reVerbClassSep matches the separators in vclass: fields
+var reVerbClassSep = regexp.MustCompile(`[\pZ,/]+`)
+
+// verbEndings maps present endings (without diacritics) to verb
+// classes, longest first within each ending family
+var verbEndings = []struct {
+	ending  string
+	classes []string
+}{
+	{"αομαι", []string{VerbContractA, VerbDeponent}},
+	{"εομαι", []string{VerbContractE, VerbDeponent}},
+	{"οομαι", []string{VerbContractO, VerbDeponent}},
+	{"ομαι", []string{VerbOmega, VerbDeponent}},
+	{"μαι", []string{VerbMi, VerbDeponent}},
+	{"αω", []string{VerbContractA}},
+	{"εω", []string{VerbContractE}},
+	{"οω", []string{VerbContractO}},
+	{"μι", []string{VerbMi}},
+	{"ω", []string{VerbOmega}},
+}
+
+// VerbClasses returns the classes of the verb with the (uncontracted)
+// present form present, inferred from its ending: omega, contract
+// (-άω/-έω/-όω), or -μι, plus deponent for middle-only (-μαι) presents.
+// Only the first word of present is used, and nil is returned if its
+// ending isn't recognised.
+func VerbClasses(present string) []string {
+	words := strings.FieldsFunc(StripDiacritics(present), func(r rune) bool {
+		return !IsGreekLetter(r)
+	})
+	if len(words) == 0 {
+		return nil
+	}
+	for _, e := range verbEndings {
+		if strings.HasSuffix(words[0], e.ending) {
+			return e.classes
+		}
+	}
+	return nil
+}
+
+// ParseVerbClasses parses a vclass: field, a list of verb classes
+// separated by spaces, commas, or slashes
+func ParseVerbClasses(s string) ([]string, error) {
+	var classes []string
+	for _, class := range reVerbClassSep.Split(strings.TrimSpace(s), -1) {
+		valid := false
+		for _, name := range VerbClassNames {
+			valid = valid || class == name
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid vclass %q (want %s)", class,
+				strings.Join(VerbClassNames, ", "))
+		}
+		classes = append(classes, class)
+	}
+	return classes, nil
+}
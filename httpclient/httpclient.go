@@ -0,0 +1,187 @@
+// Package httpclient provides the HTTP client shared by the mag tools'
+// external service integrations (TTS engines and enrichment services),
+// with rate limiting, retries with exponential backoff, and per-request
+// timeouts
+
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Config is the HTTP client configuration shared by the mag tools, for
+// embedding in their command line Options
+type Config struct {
+	HTTPRate    float64       `long:"http-rate" env:"MAG_HTTP_RATE" default:"1" description:"maximum requests per second to each external service (0 for no limit)"`
+	HTTPRetries int           `long:"http-retries" env:"MAG_HTTP_RETRIES" default:"3" description:"maximum retries of failed requests to external services, with exponential backoff"`
+	HTTPTimeout time.Duration `long:"http-timeout" env:"MAG_HTTP_TIMEOUT" default:"30s" description:"timeout for each request attempt to external services"`
+}
+
+// DefaultConfig is the configuration of clients not configured by the
+// user, matching the Config flag defaults
+var DefaultConfig = Config{HTTPRate: 1, HTTPRetries: 3, HTTPTimeout: 30 * time.Second}
+
+const (
+	// backoff is the delay before the first retry, doubled for each
+	// subsequent one
+	backoff = time.Second
+	// maxBackoff is the maximum delay between retries
+	maxBackoff = 30 * time.Second
+)
+
+// Transport is an http.RoundTripper that limits the rate of requests,
+// times out each attempt, and retries failed requests (network errors,
+// 429 Too Many Requests, and 5xx server errors) with exponential
+// backoff, honouring any Retry-After delay
+type Transport struct {
+	Config
+	Base http.RoundTripper // underlying transport (nil for http.DefaultTransport)
+
+	mu   sync.Mutex
+	next time.Time // earliest time for the next request
+}
+
+// New returns a client using a new Transport configured by cfg. Each
+// client has its own rate limit, so should be used for a single service.
+func New(cfg Config) *http.Client {
+	return &http.Client{Transport: &Transport{Config: cfg}}
+}
+
+// wait waits until the rate limit allows another request, or ctx is done
+func (t *Transport) wait(ctx context.Context) error {
+	if t.HTTPRate <= 0 {
+		return nil
+	}
+	interval := time.Duration(float64(time.Second) / t.HTTPRate)
+	t.mu.Lock()
+	now := time.Now()
+	at := t.next
+	if at.Before(now) {
+		at = now
+	}
+	t.next = at.Add(interval)
+	t.mu.Unlock()
+	return sleep(ctx, time.Until(at))
+}
+
+// sleep waits for d, or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryable reports whether a request with the response resp or error
+// err should be retried
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// retryAfter returns the delay requested by the Retry-After header of
+// resp (in seconds), or 0
+func retryAfter(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs < 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// cancelBody cancels the context of its attempt when closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// attempt makes a single attempt at req, with a timeout
+func (t *Transport) attempt(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	ctx, cancel := req.Context(), context.CancelFunc(func() {})
+	if t.HTTPTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, t.HTTPTimeout)
+	}
+	resp, err := base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// The timeout covers reading the body, so cancel only once it's closed
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	delay := backoff
+	for try := 0; ; try++ {
+		if try > 0 {
+			// Retried requests need a fresh copy of the body
+			if req.Body != nil && req.Body != http.NoBody {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req = req.Clone(ctx)
+				req.Body = body
+			}
+		}
+		err := t.wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := t.attempt(req)
+		replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+		if try >= t.HTTPRetries || !replayable || !retryable(resp, err) {
+			return resp, err
+		}
+
+		wait := max(delay, retryAfter(resp))
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+		slog.Warn("retrying request", "url", req.URL.String(), "error", reason,
+			"retry", fmt.Sprintf("%d/%d", try+1, t.HTTPRetries), "delay", wait)
+		err = sleep(ctx, wait)
+		if err != nil {
+			return nil, err
+		}
+		delay = min(2*delay, maxBackoff)
+	}
+}
@@ -0,0 +1,19 @@
+package httpclient
+
+import (
+	"testing"
+
+	flags "github.com/jessevdk/go-flags"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	var cfg Config
+	_, err := flags.NewParser(&cfg, flags.None).ParseArgs(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg != DefaultConfig {
+		t.Errorf("DefaultConfig %+v doesn't match the flag defaults %+v",
+			DefaultConfig, cfg)
+	}
+}
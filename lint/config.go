@@ -0,0 +1,96 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// ConfigFilename is the name of the lint config file
+const ConfigFilename = ".maglint.yml"
+
+// Config is the lint configuration, mapping rule ids to severities
+type Config struct {
+	Rules map[string]Severity
+}
+
+// configFile is the on-disk format of Config
+type configFile struct {
+	Rules map[string]string `yaml:"rules"`
+}
+
+// LoadConfig reads the lint config file at path
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cf configFile
+	err = yaml.Unmarshal(data, &cf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	config := &Config{Rules: make(map[string]Severity)}
+	for id, name := range cf.Rules {
+		if _, ok := LookupRule(id); !ok {
+			return nil, fmt.Errorf("%s: unknown lint rule %q", path, id)
+		}
+		severity, err := ParseSeverity(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: rule %q: %w", path, id, err)
+		}
+		config.Rules[id] = severity
+	}
+	return config, nil
+}
+
+// FindConfig looks for a lint config file in dir and its parents,
+// returning its path, or "" if none is found
+func FindConfig(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, ConfigFilename)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Severity returns the configured severity for rule id
+func (c *Config) Severity(id string) Severity {
+	if s, ok := c.Rules[id]; ok {
+		return s
+	}
+	if r, ok := LookupRule(id); ok {
+		return r.Default
+	}
+	return Error
+}
+
+// ResolveConfig loads the lint config from path if set, or otherwise
+// from the first config file found searching upwards from datasetDir
+// and then the current directory. If no config file is found, the
+// default configuration is returned.
+func ResolveConfig(path, datasetDir string) (*Config, error) {
+	if path == "" {
+		path = FindConfig(datasetDir)
+	}
+	if path == "" {
+		path = FindConfig(".")
+	}
+	if path == "" {
+		return &Config{}, nil
+	}
+	return LoadConfig(path)
+}
@@ -0,0 +1,152 @@
+package lint
+
+import (
+	"fmt"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/gavincarr/mag/dataset"
+)
+
+// Exercise is an exercises dataset textbook exercise
+type Exercise struct {
+	Number string // exercise number within the unit, e.g. "3" or "II.4"
+	Prompt string
+	Answer string
+	Line   int `yaml:"-"` // source line number
+}
+
+// UnmarshalYAML decodes an Exercise, recording its source line
+func (e *Exercise) UnmarshalYAML(value *yaml.Node) error {
+	type exercise Exercise
+	err := value.Decode((*exercise)(e))
+	if err != nil {
+		return err
+	}
+	e.Line = value.Line
+	return nil
+}
+
+// ExerciseUnit is an exercises dataset unit
+type ExerciseUnit struct {
+	Name      string
+	Unit      int
+	Exercises []Exercise
+	Line      int    `yaml:"-"` // source line number
+	File      string `yaml:"-"` // dataset filename
+}
+
+// UnmarshalYAML decodes an ExerciseUnit, recording its source line
+func (u *ExerciseUnit) UnmarshalYAML(value *yaml.Node) error {
+	type exerciseUnit ExerciseUnit
+	err := value.Decode((*exerciseUnit)(u))
+	if err != nil {
+		return err
+	}
+	u.Line = value.Line
+	return nil
+}
+
+// LintExercises runs a series of checks on exercises (or just unit
+// number unit, if non-zero), reporting any problems found to l
+func LintExercises(l *Linter, exercises []ExerciseUnit, unit int, stats *map[string]int) {
+	prev := 0 // previous unit number
+	if len(exercises) == 0 {
+		l.Reportf("empty-dataset", Location{Entry: NoEntry},
+			"Empty exercises list!")
+		return
+	}
+
+	for _, u := range exercises {
+		prevUnit := prev
+		if u.Unit != 0 {
+			prev = u.Unit
+		}
+		if unit > 0 && u.Unit != unit {
+			continue
+		}
+
+		(*stats)["units"]++
+		var label string
+		if u.Name != "" {
+			label = fmt.Sprintf(" for unit %q", u.Name)
+		} else if u.Unit >= 3 {
+			label = fmt.Sprintf(" for unit %d", u.Unit)
+		}
+		loc := Location{
+			File:  u.File,
+			Line:  u.Line,
+			Unit:  UnitLabel(u.Name, u.Unit),
+			Entry: NoEntry,
+		}
+		if u.Name == "" {
+			l.Reportf("unit-name", loc, "Empty unit 'name' field found%s", label)
+		}
+		if u.Unit == 0 {
+			l.Reportf("unit-number", loc, "Empty unit 'unit' field found%s", label)
+		} else if u.Unit < 3 || u.Unit > 42 {
+			l.Reportf("unit-number", loc, "Invalid unit 'unit' field found%s: %d",
+				label, u.Unit)
+		}
+		LintUnitSequence(l, u.Unit, prevUnit, loc, label)
+		if len(u.Exercises) == 0 {
+			l.Reportf("empty-unit", loc, "Empty unit 'exercises' list found%s", label)
+			continue
+		}
+		if label == "" {
+			continue
+		}
+
+		numbers := make(map[string]int)
+		for i, e := range u.Exercises {
+			(*stats)["exercises"]++
+			loc.Entry = i
+			loc.Line = e.Line
+			LintExercise(l, e, loc, label)
+			if prev, exists := numbers[e.Number]; exists && e.Number != "" {
+				l.Reportf("duplicate-id", loc,
+					"Duplicate exercise number %q found%s, exercise %d (first used for exercise %d)",
+					e.Number, label, i, prev)
+			} else {
+				numbers[e.Number] = i
+			}
+		}
+	}
+}
+
+// LintExercise checks that the exercise e has a number, prompt, and
+// answer
+func LintExercise(l *Linter, e Exercise, loc Location, label string) {
+	fields := []struct {
+		name, value string
+	}{
+		{"number", e.Number},
+		{"prompt", e.Prompt},
+		{"answer", e.Answer},
+	}
+	for _, f := range fields {
+		if f.value == "" {
+			l.Reportf("required-field", loc,
+				"Empty '%s' field found%s, exercise %d", f.name, label, loc.Entry)
+		}
+	}
+}
+
+// ParseExercises decodes the exercises dataset data read from filename,
+// and returns its units and inline suppressions (for unit number unit
+// only, if non-zero)
+func ParseExercises(data []byte, filename string, unit int) ([]ExerciseUnit, []*Suppression, error) {
+	var units []ExerciseUnit
+	err := dataset.Unmarshal(data, &units)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	for i := range units {
+		units[i].File = filename
+	}
+	sups, err := ParseSuppressions(data, filename, unit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return units, sups, nil
+}
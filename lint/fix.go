@@ -0,0 +1,36 @@
+package lint
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gavincarr/mag/dataset"
+)
+
+// FixFile applies mechanical fixes (see dataset.FixData) to data, read
+// from filename, reporting each fix to wtr and rewriting filename if any
+// changes were made. It returns the fixed data and number of fixes.
+func FixFile(wtr io.Writer, filename string, data []byte) ([]byte, int, error) {
+	fixed, fixes, err := dataset.FixData(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(fixes) == 0 {
+		return data, 0, nil
+	}
+	for _, f := range fixes {
+		fmt.Fprintf(wtr, "Fixed %q field at line %d: %q => %q\n",
+			f.Field, f.Line, f.Old, f.New)
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, 0, err
+	}
+	err = os.WriteFile(filename, fixed, info.Mode())
+	if err != nil {
+		return nil, 0, err
+	}
+	return fixed, len(fixes), nil
+}
@@ -0,0 +1,37 @@
+package lint
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Escaping for GitHub workflow command data and property values
+	githubDataEscaper = strings.NewReplacer(
+		"%", "%25", "\r", "%0D", "\n", "%0A")
+	githubPropertyEscaper = strings.NewReplacer(
+		"%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+// WriteGitHub writes the findings to wtr as GitHub Actions workflow
+// commands, so they appear as annotations on the dataset lines
+func (l *Linter) WriteGitHub(wtr io.Writer) {
+	for _, f := range l.Findings {
+		command := "error"
+		if f.Severity == Warning {
+			command = "warning"
+		}
+		props := []string{}
+		if f.File != "" {
+			props = append(props, "file="+githubPropertyEscaper.Replace(f.File))
+		}
+		if f.Line > 0 {
+			props = append(props, "line="+strconv.Itoa(f.Line))
+		}
+		props = append(props, "title="+githubPropertyEscaper.Replace(f.Rule))
+		fmt.Fprintf(wtr, "::%s %s::%s\n", command, strings.Join(props, ","),
+			githubDataEscaper.Replace(f.Message))
+	}
+}
@@ -0,0 +1,157 @@
+// Package lint provides the framework for the mag dataset linters:
+// rules with configurable severities, and the findings they report
+
+package lint
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Severity is the severity level of a lint rule
+type Severity int
+
+const (
+	Off Severity = iota
+	Warning
+	Error
+)
+
+var severityNames = map[Severity]string{
+	Off:     "off",
+	Warning: "warning",
+	Error:   "error",
+}
+
+func (s Severity) String() string {
+	return severityNames[s]
+}
+
+// ParseSeverity parses a severity name ("error", "warning"/"warn", or "off")
+func ParseSeverity(name string) (Severity, error) {
+	switch strings.ToLower(name) {
+	case "error":
+		return Error, nil
+	case "warning", "warn":
+		return Warning, nil
+	case "off":
+		return Off, nil
+	}
+	return Off, fmt.Errorf("invalid severity %q (must be error, warning, or off)",
+		name)
+}
+
+// Location identifies where in a dataset a finding occurred
+type Location struct {
+	File  string // dataset filename
+	Line  int    // line number in File, if known
+	Unit  string // unit name (or number), if any
+	Entry int    // entry index within the unit, or -1 for the whole unit
+}
+
+// NoEntry is the Entry value for unit- or dataset-level findings
+const NoEntry = -1
+
+// Finding is a single problem reported by a lint rule
+type Finding struct {
+	Rule     string
+	Severity Severity
+	Location
+	Message string
+}
+
+// Linter collects findings, applying the configured rule severities
+type Linter struct {
+	Tool         string // name of the linting tool, for reports
+	config       *Config
+	suppressions []*Suppression
+	Findings     []Finding
+}
+
+// New returns a new Linter for tool using config (which may be nil, for
+// the default rule severities)
+func New(tool string, config *Config) *Linter {
+	if config == nil {
+		config = &Config{}
+	}
+	return &Linter{Tool: tool, config: config}
+}
+
+// Reportf records a finding for rule at loc, unless the rule is disabled
+// or suppressed at loc
+func (l *Linter) Reportf(rule string, loc Location, format string, args ...interface{}) {
+	if l.suppressed(rule, loc) {
+		return
+	}
+	l.add(rule, loc, format, args...)
+}
+
+// add records a finding for rule at loc, unless the rule is disabled
+func (l *Linter) add(rule string, loc Location, format string, args ...interface{}) {
+	severity := l.config.Severity(rule)
+	if severity == Off {
+		return
+	}
+	l.Findings = append(l.Findings, Finding{
+		Rule:     rule,
+		Severity: severity,
+		Location: loc,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// count returns the number of findings with severity s
+func (l *Linter) count(s Severity) int {
+	n := 0
+	for _, f := range l.Findings {
+		if f.Severity == s {
+			n++
+		}
+	}
+	return n
+}
+
+// Errors returns the number of error-level findings
+func (l *Linter) Errors() int {
+	return l.count(Error)
+}
+
+// Warnings returns the number of warning-level findings
+func (l *Linter) Warnings() int {
+	return l.count(Warning)
+}
+
+// Prefix returns the "file:line: " prefix for findings at loc, or ""
+// if the line is unknown
+func (loc Location) Prefix() string {
+	if loc.File == "" || loc.Line == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d: ", loc.File, loc.Line)
+}
+
+// WriteText writes findings to wtr as text, one per line, prefixed by
+// their file and line number where known
+func (l *Linter) WriteText(wtr io.Writer) {
+	for _, f := range l.Findings {
+		if f.Severity == Warning {
+			fmt.Fprintf(wtr, "%sWarning: %s\n", f.Prefix(), f.Message)
+		} else {
+			fmt.Fprintf(wtr, "%s%s\n", f.Prefix(), f.Message)
+		}
+	}
+}
+
+// Err returns an error if any error-level findings were reported, or
+// if there were more than maxWarnings warnings (if maxWarnings >= 0)
+func (l *Linter) Err(maxWarnings int) error {
+	if errors := l.Errors(); errors > 0 {
+		return fmt.Errorf("%d lint error(s) found", errors)
+	}
+	if warnings := l.Warnings(); maxWarnings >= 0 && warnings > maxWarnings {
+		return fmt.Errorf("%d lint warning(s) found (maximum %d)",
+			warnings, maxWarnings)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package lint
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/gavincarr/mag/dataset"
+	"github.com/gavincarr/mag/export"
+)
+
+// LintImage checks that the image file referenced by w exists, relative
+// to the dataset file it was read from
+func LintImage(l *Linter, w Word, loc Location, label string) {
+	if w.Img == "" {
+		return
+	}
+	path := w.Img
+	if !filepath.IsAbs(path) && loc.File != "" && loc.File != dataset.Stdin {
+		path = filepath.Join(filepath.Dir(loc.File), path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		l.Reportf("missing-media", loc,
+			"Missing 'img' file found%s, word %d: %q", label, loc.Entry, w.Img)
+	}
+}
+
+// LintManifests checks that the media files recorded in the media
+// manifests in dir (written by the exporters) all exist. A missing dir
+// is not an error.
+func LintManifests(l *Linter, dir string) error {
+	paths, err := export.ManifestPaths(dir)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		filenames, err := export.ManifestFiles(path)
+		if err != nil {
+			return err
+		}
+		for _, filename := range filenames {
+			_, err := os.Stat(filepath.Join(dir, filename))
+			if errors.Is(err, fs.ErrNotExist) {
+				l.Reportf("missing-media", Location{File: path, Entry: NoEntry},
+					"Missing media file %q recorded in manifest %s", filename, path)
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package lint
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gavincarr/mag/enrich"
+	"github.com/gavincarr/mag/morph"
+)
+
+// morpheusPOS maps vocab 'pos' values to Morpheus parts of speech
+// (participles have no Morpheus equivalent, so aren't checked)
+var morpheusPOS = map[string]string{
+	"adj":      "adjective",
+	"adv":      "adverb",
+	"conj":     "conjunction",
+	"n":        "noun",
+	"particle": "particle",
+	"prep":     "preposition",
+	"pron":     "pronoun",
+	"v":        "verb",
+}
+
+// Entry returns the full dictionary entry of w: its 'gr' field, plus
+// its 'gr_ext' field if set
+func (w Word) Entry() string {
+	if w.GrExt == "" {
+		return w.Gr
+	}
+	return w.Gr + ", " + w.GrExt
+}
+
+// LintMorpheus cross-checks the words of vocab against the Morpheus
+// analyses of their headwords (see enrich.Headword), keyed by dataset
+// filename and then headword, as written by mag enrich --morpheus.
+// Words Morpheus analysed whose 'pos' (or, for nouns, gender) none of
+// its analyses agree with are reported; unanalysed words are skipped.
+func LintMorpheus(l *Linter, vocab []VocabUnit, analyses map[string]map[string][]enrich.Analysis) {
+	for _, u := range vocab {
+		words := analyses[u.File]
+		if len(words) == 0 {
+			continue
+		}
+		label := fmt.Sprintf(" for unit %d", u.Unit)
+		if u.Name != "" {
+			label = fmt.Sprintf(" for unit %q", u.Name)
+		}
+		loc := Location{File: u.File, Unit: UnitLabel(u.Name, u.Unit)}
+		for i, w := range u.Vocab {
+			loc.Entry = i
+			loc.Line = w.Line
+			LintWordAnalyses(l, w, words[enrich.Headword(w.Gr)], loc, label)
+		}
+	}
+}
+
+// LintWordAnalyses checks the 'pos' (and, for nouns, the gender) of the
+// vocab word w against its Morpheus analyses
+func LintWordAnalyses(l *Linter, w Word, analyses []enrich.Analysis, loc Location, label string) {
+	pos, ok := morpheusPOS[w.Pos]
+	if !ok || len(analyses) == 0 {
+		return
+	}
+	var posList, genders []string
+	for _, a := range analyses {
+		posList = append(posList, a.POS)
+		if a.POS == pos && a.Gender != "" {
+			genders = append(genders, a.Gender)
+		}
+	}
+	if !slices.Contains(posList, pos) {
+		l.Reportf("morpheus-mismatch", loc,
+			"Word 'pos' %q disagrees with Morpheus (%s)%s, word %d: %q",
+			w.Pos, strings.Join(posList, ", "), label, loc.Entry, w.Gr)
+		return
+	}
+	if w.Pos != "n" || len(genders) == 0 {
+		return
+	}
+	n, err := morph.ParseNoun(w.Entry())
+	if err != nil {
+		return
+	}
+	for _, gender := range n.Genders {
+		for _, g := range genders {
+			if strings.Contains(g, gender) {
+				return
+			}
+		}
+	}
+	l.Reportf("morpheus-mismatch", loc,
+		"Noun gender %s disagrees with Morpheus (%s)%s, word %d: %q",
+		strings.Join(n.Genders, "/"), strings.Join(genders, ", "), label, loc.Entry, w.Gr)
+}
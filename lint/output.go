@@ -0,0 +1,70 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// jsonFinding is the JSON representation of a Finding
+type jsonFinding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Unit     string `json:"unit,omitempty"`
+	Entry    *int   `json:"entry,omitempty"`
+	Message  string `json:"message"`
+}
+
+// jsonReport is the JSON output document
+type jsonReport struct {
+	Findings []jsonFinding  `json:"findings"`
+	Stats    map[string]int `json:"stats"`
+}
+
+// Write writes the findings and stats to wtr in the given format
+func (l *Linter) Write(wtr io.Writer, format string, stats map[string]int) error {
+	switch format {
+	case "", "text":
+		l.WriteText(wtr)
+		jstats, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(wtr, string(jstats))
+		return nil
+	case "json":
+		return l.WriteJSON(wtr, stats)
+	case "sarif":
+		return l.WriteSARIF(wtr)
+	case "github":
+		l.WriteGitHub(wtr)
+		return nil
+	}
+	return fmt.Errorf("unsupported output format %q", format)
+}
+
+// WriteJSON writes the findings and stats to wtr as a JSON document
+func (l *Linter) WriteJSON(wtr io.Writer, stats map[string]int) error {
+	report := jsonReport{Findings: []jsonFinding{}, Stats: stats}
+	for _, f := range l.Findings {
+		jf := jsonFinding{
+			Rule:     f.Rule,
+			Severity: f.Severity.String(),
+			File:     f.File,
+			Line:     f.Line,
+			Unit:     f.Unit,
+			Message:  f.Message,
+		}
+		if f.Entry != NoEntry {
+			entry := f.Entry
+			jf.Entry = &entry
+		}
+		report.Findings = append(report.Findings, jf)
+	}
+
+	enc := json.NewEncoder(wtr)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
@@ -0,0 +1,237 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/gavincarr/mag/dataset"
+	"github.com/gavincarr/mag/greek"
+)
+
+var (
+	// ppKeywords are the non-Greek words allowed in principal part entries
+	ppKeywords = []string{"or", "and", "rare", "stem"}
+
+	reStemNote = regexp.MustCompile(`\(stem \p{Greek}+-\)`)
+	reStem     = regexp.MustCompile(`^\p{Greek}+-( or \p{Greek}+-)*$`)
+)
+
+// Record is a principal parts dataset record
+type Record struct {
+	Pr     string
+	Fu     string
+	Ao     string
+	Pf     string
+	Pm     string
+	Ap     string
+	VClass string `yaml:"vclass"`
+	Stems  map[string]string
+	Line   int `yaml:"-"` // source line number
+}
+
+// UnmarshalYAML decodes a Record, recording its source line
+func (r *Record) UnmarshalYAML(value *yaml.Node) error {
+	type record Record
+	err := value.Decode((*record)(r))
+	if err != nil {
+		return err
+	}
+	r.Line = value.Line
+	return nil
+}
+
+// PPUnit is a principal parts dataset unit
+type PPUnit struct {
+	Name string
+	Unit int
+	PP   []Record
+	Line int    `yaml:"-"` // source line number
+	File string `yaml:"-"` // dataset filename
+}
+
+// UnmarshalYAML decodes a PPUnit, recording its source line
+func (u *PPUnit) UnmarshalYAML(value *yaml.Node) error {
+	type ppUnit PPUnit
+	err := value.Decode((*ppUnit)(u))
+	if err != nil {
+		return err
+	}
+	u.Line = value.Line
+	return nil
+}
+
+// ppFieldKeys are the principal part keys of a record
+var ppFieldKeys = map[string]bool{
+	"pr": true, "fu": true, "ao": true, "pf": true, "pm": true, "ap": true,
+}
+
+// checkWord checks that word is a well-formed principal part entry
+func checkWord(word, pptype, label string) error {
+	if problem := parseEntry(word); problem != "" {
+		return fmt.Errorf("Bad %q entry found%s: %q: %s",
+			pptype, label, word, problem)
+	}
+	return nil
+}
+
+// LintRecord checks the principal part entries of rec
+func LintRecord(l *Linter, rec Record, loc Location, label string) {
+	fields := []struct {
+		pptype, word string
+	}{
+		{"pr", rec.Pr},
+		{"fu", rec.Fu},
+		{"ao", rec.Ao},
+		{"pf", rec.Pf},
+		{"pm", rec.Pm},
+		{"ap", rec.Ap},
+	}
+	for _, f := range fields {
+		if f.word == "" {
+			continue
+		}
+		err := checkWord(f.word, f.pptype, label)
+		if err != nil {
+			l.Reportf("bad-entry", loc, "%s", err.Error())
+		}
+		for _, r := range greek.NonGreekLetters(f.word, ppKeywords...) {
+			l.Reportf("non-greek-char", loc,
+				"Non-Greek letter %s in '%s' field found%s, record %d: %q",
+				greek.DescribeRune(r), f.pptype, label, loc.Entry, f.word)
+		}
+	}
+	for _, f := range fields {
+		stem, ok := rec.Stems[f.pptype]
+		if !ok {
+			continue
+		}
+		if !reStem.MatchString(stem) {
+			l.Reportf("bad-stem", loc,
+				"Bad 'stems' entry for %q found%s, record %d: %q (want e.g. \"λαβ-\")",
+				f.pptype, label, loc.Entry, stem)
+		} else if f.word == "" {
+			l.Reportf("bad-stem", loc,
+				"Stem for missing %q part found%s, record %d: %q",
+				f.pptype, label, loc.Entry, stem)
+		} else if reStemNote.MatchString(f.word) {
+			l.Reportf("bad-stem", loc,
+				"Stem for %q given in both 'stems' and an inline (stem) annotation%s, record %d",
+				f.pptype, label, loc.Entry)
+		}
+	}
+	for key := range rec.Stems {
+		if !ppFieldKeys[key] {
+			l.Reportf("bad-stem", loc,
+				"Invalid 'stems' key %q found%s, record %d (want pr, fu, ao, pf, pm, or ap)",
+				key, label, loc.Entry)
+		}
+	}
+	if rec.VClass != "" {
+		if _, err := greek.ParseVerbClasses(rec.VClass); err != nil {
+			l.Reportf("invalid-vclass", loc, "Invalid 'vclass' value found%s, record %d: %s",
+				label, loc.Entry, err)
+		}
+	}
+}
+
+// LintID checks that the card id for rec (the present, or aorist if
+// there is no present) hasn't already been used, recording it in idmap
+func LintID(l *Linter, rec Record, loc Location, label string, idmap map[string]Location) {
+	id := rec.Pr
+	if id == "" {
+		id = rec.Ao
+	}
+	if id == "" {
+		return
+	}
+	prev, exists := idmap[id]
+	if !exists {
+		idmap[id] = loc
+		return
+	}
+	l.Reportf("duplicate-id", loc,
+		"Duplicate id %q found%s, record %d (first used for unit %q, record %d)",
+		id, label, loc.Entry, prev.Unit, prev.Entry)
+}
+
+// LintPP runs a series of checks on pp (or just unit number unit, if
+// non-zero), reporting any problems found to l
+func LintPP(l *Linter, pp []PPUnit, unit int, stats *map[string]int) {
+	idmap := make(map[string]Location)
+	prev := 0 // previous unit number
+	if len(pp) == 0 {
+		l.Reportf("empty-dataset", Location{Entry: NoEntry},
+			"Empty pp list!")
+		return
+	}
+
+	for _, u := range pp {
+		prevUnit := prev
+		if u.Unit != 0 {
+			prev = u.Unit
+		}
+		if unit > 0 && u.Unit != unit {
+			continue
+		}
+
+		(*stats)["units"]++
+		var label string
+		if u.Name != "" {
+			label = fmt.Sprintf(" for unit %q", u.Name)
+		} else if u.Unit >= 3 {
+			label = fmt.Sprintf(" for unit %d", u.Unit)
+		}
+		loc := Location{
+			File:  u.File,
+			Line:  u.Line,
+			Unit:  UnitLabel(u.Name, u.Unit),
+			Entry: NoEntry,
+		}
+		if u.Name == "" {
+			l.Reportf("unit-name", loc, "Empty unit 'name' field found%s", label)
+		}
+		if u.Unit == 0 {
+			l.Reportf("unit-number", loc, "Empty unit 'unit' field found%s", label)
+		} else if u.Unit < 5 || u.Unit > 42 {
+			l.Reportf("unit-number", loc, "Invalid unit 'unit' field found%s: %d",
+				label, u.Unit)
+		}
+		LintUnitSequence(l, u.Unit, prevUnit, loc, label)
+		if len(u.PP) == 0 {
+			l.Reportf("empty-unit", loc, "Empty unit 'pp' list found%s", label)
+			continue
+		}
+		if label == "" {
+			continue
+		}
+
+		for i, rec := range u.PP {
+			(*stats)["records"]++
+			loc.Entry = i
+			loc.Line = rec.Line
+			LintRecord(l, rec, loc, label)
+			LintID(l, rec, loc, label, idmap)
+		}
+	}
+}
+
+// ParsePP decodes the principal parts dataset data read from filename,
+// and returns its units and inline suppressions (for unit number unit
+// only, if non-zero)
+func ParsePP(data []byte, filename string, unit int) ([]PPUnit, []*Suppression, error) {
+	var units []PPUnit
+	err := dataset.Unmarshal(data, &units)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	for i := range units {
+		units[i].File = filename
+	}
+	sups, err := ParseSuppressions(data, filename, unit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return units, sups, nil
+}
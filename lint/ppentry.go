@@ -0,0 +1,102 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	reEntryForm = regexp.MustCompile(`^\(?-?\p{Greek}+\)?$`)
+	reEntryStem = regexp.MustCompile(`^\(stem \p{Greek}+-\)$`)
+)
+
+// wrapped reports whether s is wholly enclosed in a pair of parentheses
+// (e.g. "(ἔφθην or ἐφθάσθην)", but not "(ἔφθην) or (ἐφθάσθην)")
+func wrapped(s string) bool {
+	if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") {
+		return false
+	}
+	depth := 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 && i < len(s)-1 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
+
+// parseEntry parses the principal part entry s, which is one or more
+// forms (each optionally hyphen-prefixed or parenthesized) separated by
+// spaces or "or"/"and" (optionally followed by "(rare)"), and then an
+// optional "(stem X-)" annotation, all optionally parenthesized. It
+// returns a description of the first malformed component found, or ""
+// if s is well-formed.
+func parseEntry(s string) string {
+	if s != strings.TrimSpace(s) {
+		return "leading or trailing whitespace"
+	}
+	if wrapped(s) {
+		s = s[1 : len(s)-1]
+	}
+
+	if i := strings.Index(s, "(stem"); i >= 0 {
+		note := s[i:]
+		s = strings.TrimRight(s[:i], " ")
+		if !reEntryStem.MatchString(note) {
+			return fmt.Sprintf("malformed stem annotation %q (want e.g. \"(stem λαβ-)\")", note)
+		}
+		if s == "" {
+			return "stem annotation without a form"
+		}
+		if len(s) == i {
+			return fmt.Sprintf("stem annotation %q not preceded by a space", note)
+		}
+	}
+
+	tokens := strings.Fields(s)
+	if len(tokens) == 0 {
+		return "no forms"
+	}
+	depth := 0
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if i > 0 && (tok == "or" || tok == "and") {
+			if i+1 < len(tokens) && tokens[i+1] == "(rare)" {
+				i++
+			}
+			if i+1 == len(tokens) {
+				return fmt.Sprintf("missing form after %q", tok)
+			}
+			i++
+			tok = tokens[i]
+		}
+		switch {
+		case tok == "or" || tok == "and" || tok == "(rare)":
+			return fmt.Sprintf("misplaced %q", tok)
+		case strings.HasPrefix(tok, "(stem") || strings.HasSuffix(tok, "-)"):
+			return fmt.Sprintf("misplaced stem annotation at %q", tok)
+		case !reEntryForm.MatchString(tok):
+			return fmt.Sprintf("malformed form %q", tok)
+		}
+		if strings.HasPrefix(tok, "(") {
+			depth++
+		}
+		if strings.HasSuffix(tok, ")") {
+			depth--
+		}
+		if depth < 0 || depth > 1 {
+			return fmt.Sprintf("unbalanced parentheses at %q", tok)
+		}
+	}
+	if depth != 0 {
+		return "unclosed parenthesis"
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package lint
+
+// Rule describes a lint rule
+type Rule struct {
+	ID          string
+	Description string
+	Default     Severity
+}
+
+// Rules is the catalogue of lint rules used by the mag linters
+var Rules = []Rule{
+	{"empty-dataset", "dataset contains no units", Error},
+	{"unit-name", "unit 'name' field is missing", Error},
+	{"unit-number", "unit 'unit' field is missing or out of range", Error},
+	{"unit-order", "unit number is repeated or lower than the previous unit's", Error},
+	{"unit-gap", "unit number skips units after the previous unit's", Warning},
+	{"empty-unit", "unit has no entries", Error},
+	{"required-field", "a required entry field is empty", Error},
+	{"invalid-pos", "word 'pos' field is not a valid part of speech", Error},
+	{"invalid-vclass", "'vclass' field is not a list of valid verb classes", Error},
+	{"prep-case-marker", "preposition gloss case marker is missing or invalid", Error},
+	{"voice-marker", "word 'gr_mp' field and (mid.)/(pass.) gloss markers are inconsistent", Error},
+	{"plural-marker", "word 'gr_pl' field and (pl.) gloss markers are inconsistent", Error},
+	{"bad-entry", "principal part entry is malformed", Error},
+	{"bad-stem", "principal part 'stems' entry is malformed, misplaced, or duplicated", Error},
+	{"non-greek-char", "Greek field contains non-Greek letters", Error},
+	{"unknown-word", "sentence 'words' entry is not a vocab card id", Error},
+	{"duplicate-id", "card id is used by more than one entry", Error},
+	{"morpheus-mismatch", "word 'pos' or noun gender disagrees with its Morpheus analyses (from mag enrich --morpheus)", Warning},
+	{"cross-unit-duplicate", "Greek headword is introduced in more than one unit (ignoring accents)", Warning},
+	{"missing-media", "referenced media file does not exist", Error},
+	{"unused-suppression", "maglint:disable comment does not suppress any finding", Warning},
+}
+
+// LookupRule returns the rule with the given id
+func LookupRule(id string) (Rule, bool) {
+	for _, r := range Rules {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
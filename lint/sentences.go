@@ -0,0 +1,176 @@
+package lint
+
+import (
+	"fmt"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/gavincarr/mag/dataset"
+	"github.com/gavincarr/mag/greek"
+)
+
+// Sentence is a sentences dataset example sentence
+type Sentence struct {
+	Gr    string
+	En    string
+	Words []string // ids of the vocab words used
+	Line  int      `yaml:"-"` // source line number
+}
+
+// UnmarshalYAML decodes a Sentence, recording its source line
+func (s *Sentence) UnmarshalYAML(value *yaml.Node) error {
+	type sentence Sentence
+	err := value.Decode((*sentence)(s))
+	if err != nil {
+		return err
+	}
+	s.Line = value.Line
+	return nil
+}
+
+// SentenceUnit is a sentences dataset unit
+type SentenceUnit struct {
+	Name      string
+	Unit      int
+	Sentences []Sentence
+	Line      int    `yaml:"-"` // source line number
+	File      string `yaml:"-"` // dataset filename
+}
+
+// UnmarshalYAML decodes a SentenceUnit, recording its source line
+func (u *SentenceUnit) UnmarshalYAML(value *yaml.Node) error {
+	type sentenceUnit SentenceUnit
+	err := value.Decode((*sentenceUnit)(u))
+	if err != nil {
+		return err
+	}
+	u.Line = value.Line
+	return nil
+}
+
+// VocabIDs returns the set of card ids of the words in vocab, as
+// derived by export_anki_vocab (with homonyms numbered)
+func VocabIDs(vocab []VocabUnit) map[string]bool {
+	ids := make(map[string]bool)
+	homonyms := make(map[string]int)
+	for _, u := range vocab {
+		for _, w := range u.Vocab {
+			homonym := 0
+			if w.Homonym != "" {
+				homonyms[wordID(w)]++
+				homonym = homonyms[wordID(w)]
+			}
+			for _, id := range wordIDs(w, homonym) {
+				ids[id] = true
+			}
+		}
+	}
+	return ids
+}
+
+// LintSentences runs a series of checks on sentences (or just unit
+// number unit, if non-zero), reporting any problems found to l. The
+// vocab words each sentence uses are checked against vocabIDs, if set.
+func LintSentences(l *Linter, sentences []SentenceUnit, unit int, vocabIDs map[string]bool, stats *map[string]int) {
+	prev := 0 // previous unit number
+	if len(sentences) == 0 {
+		l.Reportf("empty-dataset", Location{Entry: NoEntry},
+			"Empty sentences list!")
+		return
+	}
+
+	for _, u := range sentences {
+		prevUnit := prev
+		if u.Unit != 0 {
+			prev = u.Unit
+		}
+		if unit > 0 && u.Unit != unit {
+			continue
+		}
+
+		(*stats)["units"]++
+		var label string
+		if u.Name != "" {
+			label = fmt.Sprintf(" for unit %q", u.Name)
+		} else if u.Unit >= 3 {
+			label = fmt.Sprintf(" for unit %d", u.Unit)
+		}
+		loc := Location{
+			File:  u.File,
+			Line:  u.Line,
+			Unit:  UnitLabel(u.Name, u.Unit),
+			Entry: NoEntry,
+		}
+		if u.Name == "" {
+			l.Reportf("unit-name", loc, "Empty unit 'name' field found%s", label)
+		}
+		if u.Unit == 0 {
+			l.Reportf("unit-number", loc, "Empty unit 'unit' field found%s", label)
+		} else if u.Unit < 3 || u.Unit > 42 {
+			l.Reportf("unit-number", loc, "Invalid unit 'unit' field found%s: %d",
+				label, u.Unit)
+		}
+		LintUnitSequence(l, u.Unit, prevUnit, loc, label)
+		if len(u.Sentences) == 0 {
+			l.Reportf("empty-unit", loc, "Empty unit 'sentences' list found%s", label)
+			continue
+		}
+		if label == "" {
+			continue
+		}
+
+		for i, s := range u.Sentences {
+			(*stats)["sentences"]++
+			loc.Entry = i
+			loc.Line = s.Line
+			LintSentence(l, s, loc, label, vocabIDs)
+		}
+	}
+}
+
+// LintSentence checks the sentence s, and that the vocab words it uses
+// are in vocabIDs (if set)
+func LintSentence(l *Linter, s Sentence, loc Location, label string, vocabIDs map[string]bool) {
+	if s.Gr == "" {
+		l.Reportf("required-field", loc,
+			"Empty 'gr' field found%s, sentence %d", label, loc.Entry)
+	}
+	if s.En == "" {
+		l.Reportf("required-field", loc,
+			"Empty 'en' field found%s, sentence %d: %q", label, loc.Entry, s.Gr)
+	}
+	for _, r := range greek.NonGreekLetters(s.Gr) {
+		l.Reportf("non-greek-char", loc,
+			"Non-Greek letter %s in 'gr' field found%s, sentence %d: %q",
+			greek.DescribeRune(r), label, loc.Entry, s.Gr)
+	}
+	if vocabIDs == nil {
+		return
+	}
+	for _, id := range s.Words {
+		if !vocabIDs[id] {
+			l.Reportf("unknown-word", loc,
+				"Unknown vocab id %q in 'words' found%s, sentence %d",
+				id, label, loc.Entry)
+		}
+	}
+}
+
+// ParseSentences decodes the sentences dataset data read from filename,
+// and returns its units and inline suppressions (for unit number unit
+// only, if non-zero)
+func ParseSentences(data []byte, filename string, unit int) ([]SentenceUnit, []*Suppression, error) {
+	var units []SentenceUnit
+	err := dataset.Unmarshal(data, &units)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	for i := range units {
+		units[i].File = filename
+	}
+	sups, err := ParseSuppressions(data, filename, unit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return units, sups, nil
+}
@@ -0,0 +1,179 @@
+package lint
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/gavincarr/mag/dataset"
+)
+
+// AllRules is the Suppression rule value used to suppress all rules
+const AllRules = "*"
+
+var (
+	reDisable = regexp.MustCompile(`maglint:disable\b([^#]*)`)
+)
+
+// Suppression is an inline "# maglint:disable rule-name ..." comment,
+// suppressing a rule for the unit or entry it is attached to
+type Suppression struct {
+	Location
+	Rule string
+	used bool
+}
+
+// UnitLabel returns the label used to identify a unit in findings:
+// its name if set, otherwise its unit number
+func UnitLabel(name string, unit int) string {
+	if name == "" && unit > 0 {
+		return strconv.Itoa(unit)
+	}
+	return name
+}
+
+// parseDisable returns the rules disabled by the comment c (AllRules
+// if the directive lists none)
+func parseDisable(c string) []string {
+	rules := []string{}
+	for _, line := range strings.Split(c, "\n") {
+		m := reDisable.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		fields := strings.FieldsFunc(m[1], func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		if len(fields) == 0 {
+			fields = []string{AllRules}
+		}
+		rules = append(rules, fields...)
+	}
+	return rules
+}
+
+// nodeComments returns the head and line comments of n, and of any
+// scalar keys and values of n if n is a mapping
+func nodeComments(n *yaml.Node) string {
+	comments := []string{n.HeadComment, n.LineComment}
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			comments = append(comments, key.HeadComment, key.LineComment)
+			if value.Kind == yaml.ScalarNode {
+				comments = append(comments, value.LineComment)
+			}
+		}
+	}
+	return strings.Join(comments, "\n")
+}
+
+// ParseSuppressions returns the inline suppressions in the YAML dataset
+// data read from file. Comments on a unit's own fields apply to
+// unit-level findings, and comments on (or immediately before) an entry
+// apply to that entry. If unit is non-zero, only suppressions in that
+// unit number are returned.
+func ParseSuppressions(data []byte, file string, unit int) ([]*Suppression, error) {
+	sups := []*Suppression{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return sups, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		units, err := dataset.UnitsNode(&doc)
+		if err != nil {
+			return nil, err
+		}
+		if units != nil {
+			sups = append(sups, docSuppressions(units, file, unit)...)
+		}
+	}
+}
+
+// docSuppressions returns the inline suppressions in the units list
+// node of a single dataset document
+func docSuppressions(units *yaml.Node, file string, unit int) []*Suppression {
+	sups := []*Suppression{}
+	add := func(c string, loc Location) {
+		for _, rule := range parseDisable(c) {
+			sups = append(sups, &Suppression{Location: loc, Rule: rule})
+		}
+	}
+	for _, u := range units.Content {
+		if u.Kind != yaml.MappingNode {
+			continue
+		}
+		var name string
+		var number int
+		var entries *yaml.Node
+		for i := 0; i+1 < len(u.Content); i += 2 {
+			key, value := u.Content[i], u.Content[i+1]
+			switch key.Value {
+			case "name":
+				name = value.Value
+			case "unit":
+				number, _ = strconv.Atoi(value.Value)
+			case "vocab", "pp", "paradigms", "sentences", "exercises":
+				entries = value
+			}
+		}
+		if unit > 0 && number != unit {
+			continue
+		}
+
+		label := UnitLabel(name, number)
+		add(nodeComments(u), Location{
+			File: file, Line: u.Line, Unit: label, Entry: NoEntry,
+		})
+		if entries == nil {
+			continue
+		}
+		for j, e := range entries.Content {
+			add(nodeComments(e), Location{
+				File: file, Line: e.Line, Unit: label, Entry: j,
+			})
+		}
+	}
+	return sups
+}
+
+// AddSuppressions registers inline suppressions with l
+func (l *Linter) AddSuppressions(sups []*Suppression) {
+	l.suppressions = append(l.suppressions, sups...)
+}
+
+// suppressed reports whether rule is suppressed at loc, marking the
+// matching suppression as used. Suppressions are matched on the line of
+// their unit or entry, since unit labels need not be unique.
+func (l *Linter) suppressed(rule string, loc Location) bool {
+	for _, s := range l.suppressions {
+		if s.File == loc.File && s.Line == loc.Line && s.Entry == loc.Entry &&
+			(s.Rule == rule || s.Rule == AllRules) {
+			s.used = true
+			return true
+		}
+	}
+	return false
+}
+
+// ReportUnusedSuppressions reports any suppressions that did not
+// suppress a finding (including those naming unknown rules)
+func (l *Linter) ReportUnusedSuppressions() {
+	for _, s := range l.suppressions {
+		if s.used {
+			continue
+		}
+		l.add("unused-suppression", s.Location,
+			"Unused maglint:disable suppression for %q", s.Rule)
+	}
+}
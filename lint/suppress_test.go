@@ -0,0 +1,106 @@
+package lint
+
+import (
+	"testing"
+)
+
+// findingRules returns the rules of the findings in l
+func findingRules(l *Linter) []string {
+	var rules []string
+	for _, f := range l.Findings {
+		rules = append(rules, f.Rule)
+	}
+	return rules
+}
+
+func TestSuppressSentence(t *testing.T) {
+	data := []byte(`- name: Unit 3
+  unit: 3
+  sentences:
+    # maglint:disable required-field
+    - gr: ὁ λόγος
+    - gr: τὸ δῶρον
+`)
+	units, sups, err := ParseSentences(data, "sentences.yml", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := New("test", nil)
+	l.AddSuppressions(sups)
+	LintSentences(l, units, 0, nil, &map[string]int{})
+	l.ReportUnusedSuppressions()
+
+	// Only the second sentence's missing 'en' is reported
+	rules := findingRules(l)
+	if len(rules) != 1 || rules[0] != "required-field" || l.Findings[0].Entry != 1 {
+		t.Errorf("got findings %v, want required-field for sentence 1 only", l.Findings)
+	}
+}
+
+func TestSuppressExercise(t *testing.T) {
+	data := []byte(`- name: Unit 3
+  unit: 3
+  exercises:
+    - number: "1"
+      prompt: λύω
+      answer: I loosen
+    - number: "1" # maglint:disable duplicate-id
+      prompt: λύεις
+      answer: you loosen
+`)
+	units, sups, err := ParseExercises(data, "exercises.yml", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := New("test", nil)
+	l.AddSuppressions(sups)
+	LintExercises(l, units, 0, &map[string]int{})
+	l.ReportUnusedSuppressions()
+
+	if rules := findingRules(l); len(rules) != 0 {
+		t.Errorf("got findings %v, want none", rules)
+	}
+}
+
+func TestParseSuppressionsParadigms(t *testing.T) {
+	data := []byte(`- name: Unit 3
+  unit: 3
+  paradigms:
+    - name: λόγος
+    # maglint:disable
+    - name: δῶρον
+`)
+	sups, err := ParseSuppressions(data, "paradigms.yml", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sups) != 1 || sups[0].Entry != 1 || sups[0].Rule != AllRules {
+		t.Errorf("got suppressions %+v, want all rules for entry 1", sups)
+	}
+}
+
+func TestSuppressSameUnitName(t *testing.T) {
+	data := []byte(`- name: Review
+  unit: 3
+  sentences:
+    - gr: ὁ λόγος # maglint:disable required-field
+- name: Review
+  unit: 4
+  sentences:
+    - gr: τὸ δῶρον
+`)
+	units, sups, err := ParseSentences(data, "sentences.yml", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := New("test", nil)
+	l.AddSuppressions(sups)
+	LintSentences(l, units, 0, nil, &map[string]int{})
+	l.ReportUnusedSuppressions()
+
+	// The suppression in the first unit does not apply to the second
+	rules := findingRules(l)
+	if len(rules) != 1 || rules[0] != "required-field" || l.Findings[0].Line != 8 {
+		t.Errorf("got findings %v, want required-field at line 8 only", l.Findings)
+	}
+}
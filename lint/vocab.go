@@ -0,0 +1,359 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/gavincarr/mag/dataset"
+	"github.com/gavincarr/mag/greek"
+)
+
+var (
+	reCommaStar    = regexp.MustCompile(`,.*$`)
+	reSemicolon    = regexp.MustCompile(`\pZ*;\pZ*`)
+	reCaseMarker   = regexp.MustCompile(`^\(\+\pZ*([^).]*)\.?\)`)
+	rePluralMarker = regexp.MustCompile(`^\(pl\.\)`)
+	reVoiceMarker  = regexp.MustCompile(`^\([^(]*(mid|pass)\.[^)]*\)`)
+
+	// validCases are the cases allowed in preposition case markers
+	validCases = map[string]bool{"acc": true, "gen": true, "dat": true}
+)
+
+// Word is a vocab dataset word entry
+type Word struct {
+	Gr      string
+	GrMP    string `yaml:"gr_mp"`
+	GrPl    string `yaml:"gr_pl"`
+	GrExt   string `yaml:"gr_ext"`
+	Id      string
+	Homonym string
+	En      string
+	Cog     string
+	Pos     string
+	VClass  string `yaml:"vclass"`
+	Img     string
+	Line    int `yaml:"-"` // source line number
+}
+
+// UnmarshalYAML decodes a Word, recording its source line
+func (w *Word) UnmarshalYAML(value *yaml.Node) error {
+	type word Word
+	err := value.Decode((*word)(w))
+	if err != nil {
+		return err
+	}
+	w.Line = value.Line
+	return nil
+}
+
+// VocabUnit is a vocab dataset unit
+type VocabUnit struct {
+	Name  string
+	Unit  int
+	Vocab []Word
+	Line  int    `yaml:"-"` // source line number
+	File  string `yaml:"-"` // dataset filename
+}
+
+// UnmarshalYAML decodes a VocabUnit, recording its source line
+func (u *VocabUnit) UnmarshalYAML(value *yaml.Node) error {
+	type vocabUnit VocabUnit
+	err := value.Decode((*vocabUnit)(u))
+	if err != nil {
+		return err
+	}
+	u.Line = value.Line
+	return nil
+}
+
+// LintWord checks the fields of the vocab word w
+func LintWord(l *Linter, w Word, loc Location, label string) {
+	i := loc.Entry
+	if w.Gr == "" {
+		l.Reportf("required-field", loc, "Empty 'gr' field found%s, word %d",
+			label, i)
+	}
+	if w.En == "" {
+		l.Reportf("required-field", loc, "Empty 'en' field found%s, word %d",
+			label, i)
+	}
+	if w.Pos == "" {
+		l.Reportf("required-field", loc, "Empty 'pos' field found%s, word %d",
+			label, i)
+	} else if _, ok := dataset.PartsOfSpeech[w.Pos]; !ok {
+		l.Reportf("invalid-pos", loc, "Invalid 'pos' value found%s, word %d: %q%s",
+			label, i, w.Pos,
+			dataset.FormatSuggestions(dataset.SuggestPOS(w.Pos)))
+	}
+	if w.VClass != "" {
+		if _, err := greek.ParseVerbClasses(w.VClass); err != nil {
+			l.Reportf("invalid-vclass", loc, "Invalid 'vclass' value found%s, word %d: %s",
+				label, i, err)
+		}
+	}
+	if w.Pos == "prep" && w.En != "" {
+		LintPrepGloss(l, w, loc, label)
+	}
+	if w.En != "" {
+		hasVoice := hasMarker(w.En, reVoiceMarker)
+		if w.GrMP != "" && !hasVoice {
+			l.Reportf("voice-marker", loc,
+				"Word with 'gr_mp' but no (mid.) or (pass.) gloss found%s, word %d: %q",
+				label, i, w.En)
+		} else if w.GrMP == "" && hasVoice {
+			l.Reportf("voice-marker", loc,
+				"Word with (mid.) or (pass.) gloss but no 'gr_mp' found%s, word %d: %q",
+				label, i, w.En)
+		}
+		hasPlural := hasMarker(w.En, rePluralMarker)
+		if w.GrPl != "" && !hasPlural {
+			l.Reportf("plural-marker", loc,
+				"Word with 'gr_pl' but no (pl.) gloss found%s, word %d: %q",
+				label, i, w.En)
+		} else if w.GrPl == "" && hasPlural {
+			l.Reportf("plural-marker", loc,
+				"Word with (pl.) gloss but no 'gr_pl' found%s, word %d: %q",
+				label, i, w.En)
+		}
+	}
+	for _, f := range []struct{ field, value string }{
+		{"gr", w.Gr}, {"gr_mp", w.GrMP}, {"gr_pl", w.GrPl},
+	} {
+		for _, r := range greek.NonGreekLetters(f.value) {
+			l.Reportf("non-greek-char", loc,
+				"Non-Greek letter %s in '%s' field found%s, word %d: %q",
+				greek.DescribeRune(r), f.field, label, i, f.value)
+		}
+	}
+}
+
+// hasMarker reports whether any semicolon-separated segment of gloss
+// starts with a marker matching re
+func hasMarker(gloss string, re *regexp.Regexp) bool {
+	for _, segment := range reSemicolon.Split(gloss, -1) {
+		if re.MatchString(segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// LintPrepGloss checks that the gloss for the preposition w starts with
+// a case marker (e.g. "(+ gen.)"), and that all case markers in its
+// semicolon-separated segments use a valid case
+func LintPrepGloss(l *Linter, w Word, loc Location, label string) {
+	for j, segment := range reSemicolon.Split(w.En, -1) {
+		matches := reCaseMarker.FindStringSubmatch(segment)
+		if matches == nil {
+			if j == 0 {
+				l.Reportf("prep-case-marker", loc,
+					"Preposition gloss without initial case marker found%s, word %d: %q",
+					label, loc.Entry, w.En)
+			}
+			continue
+		}
+		if !validCases[matches[1]] {
+			l.Reportf("prep-case-marker", loc,
+				"Invalid case %q in preposition case marker found%s, word %d: %q",
+				matches[1], label, loc.Entry, matches[0])
+		}
+	}
+}
+
+// wordID returns the base card id for w: its explicit id or headword
+func wordID(w Word) string {
+	if w.Id != "" {
+		return w.Id
+	}
+	return reCommaStar.ReplaceAllString(w.Gr, "")
+}
+
+// wordIDs returns the card ids export_anki_vocab derives for w: the
+// explicit id or headword (numbered if w is homonym number homonym),
+// plus the gr_mp and gr_pl forms if defined
+func wordIDs(w Word, homonym int) []string {
+	id := wordID(w)
+	if homonym > 0 {
+		id = fmt.Sprintf("%s (%d)", id, homonym)
+	}
+	ids := []string{id}
+	if w.GrMP != "" {
+		ids = append(ids, w.GrMP)
+	}
+	if w.GrPl != "" {
+		ids = append(ids, reCommaStar.ReplaceAllString(w.GrPl, ""))
+	}
+	return ids
+}
+
+// LintIDs checks that the card ids for w (homonym number homonym, if
+// non-zero) haven't already been used, recording them in idmap
+func LintIDs(l *Linter, w Word, homonym int, loc Location, label string, idmap map[string]Location) {
+	for _, id := range wordIDs(w, homonym) {
+		if id == "" {
+			continue
+		}
+		prev, exists := idmap[id]
+		if !exists {
+			idmap[id] = loc
+			continue
+		}
+		l.Reportf("duplicate-id", loc,
+			"Duplicate id %q found%s, word %d (first used for unit %q, word %d)",
+			id, label, loc.Entry, prev.Unit, prev.Entry)
+	}
+}
+
+// headwordEntry is the first word found with a given headword key
+type headwordEntry struct {
+	w   Word
+	loc Location
+}
+
+// headwordKey returns the accent- and case-insensitive headword of w
+func headwordKey(w Word) string {
+	return strings.ToLower(greek.StripDiacritics(reCommaStar.ReplaceAllString(w.Gr, "")))
+}
+
+// LintCrossUnit checks that the headword of w (ignoring accents) hasn't
+// already been introduced in another unit, recording it in headwords.
+// Words marked as homonyms are skipped, as intentional homographs.
+func LintCrossUnit(l *Linter, w Word, loc Location, label string, headwords map[string]headwordEntry) {
+	if w.Gr == "" || w.Homonym != "" {
+		return
+	}
+	key := headwordKey(w)
+	prev, exists := headwords[key]
+	if !exists {
+		headwords[key] = headwordEntry{w, loc}
+		return
+	}
+	if prev.loc.Unit == loc.Unit {
+		return
+	}
+	l.Reportf("cross-unit-duplicate", loc,
+		"Headword %q found%s, word %d, also introduced in unit %q, word %d: %q (%s: %s) vs. %q (%s: %s)",
+		w.Gr, label, loc.Entry, prev.loc.Unit, prev.loc.Entry,
+		w.Gr, w.Pos, w.En, prev.w.Gr, prev.w.Pos, prev.w.En)
+}
+
+// LintUnitSequence checks that the unit number of the unit at loc is
+// higher than prev, the previous unit's number in the dataset (if
+// any), and follows it without a gap
+func LintUnitSequence(l *Linter, unit, prev int, loc Location, label string) {
+	if unit == 0 || prev == 0 {
+		return
+	}
+	if unit <= prev {
+		l.Reportf("unit-order", loc,
+			"Out of sequence unit 'unit' field found%s: %d follows unit %d",
+			label, unit, prev)
+	} else if unit > prev+1 {
+		l.Reportf("unit-gap", loc,
+			"Unit numbering gap found%s: %d follows unit %d", label, unit, prev)
+	}
+}
+
+// LintVocab runs a series of checks on vocab (or just unit number
+// unit, if non-zero), reporting any problems found to l
+func LintVocab(l *Linter, vocab []VocabUnit, unit int, stats *map[string]int) {
+	idmap := make(map[string]Location)
+	homonyms := make(map[string]int) // homonym counts by base id
+	headwords := make(map[string]headwordEntry)
+	prev := 0 // previous unit number
+	if len(vocab) == 0 {
+		l.Reportf("empty-dataset", Location{Entry: NoEntry},
+			"Empty vocab list!")
+		return
+	}
+
+	for _, u := range vocab {
+		prevUnit := prev
+		if u.Unit != 0 {
+			prev = u.Unit
+		}
+		if unit > 0 && u.Unit != unit {
+			// Count skipped homonyms, to number them as export_anki_vocab
+			// does, and record headwords for cross-unit checks
+			for i, w := range u.Vocab {
+				if w.Homonym != "" {
+					homonyms[wordID(w)]++
+				} else if _, exists := headwords[headwordKey(w)]; !exists && w.Gr != "" {
+					headwords[headwordKey(w)] = headwordEntry{w, Location{
+						File: u.File, Line: w.Line,
+						Unit: UnitLabel(u.Name, u.Unit), Entry: i,
+					}}
+				}
+			}
+			continue
+		}
+
+		(*stats)["units"]++
+		var label string
+		if u.Name != "" {
+			label = fmt.Sprintf(" for unit %q", u.Name)
+		} else if u.Unit >= 3 {
+			label = fmt.Sprintf(" for unit %d", u.Unit)
+		}
+		loc := Location{
+			File:  u.File,
+			Line:  u.Line,
+			Unit:  UnitLabel(u.Name, u.Unit),
+			Entry: NoEntry,
+		}
+		if u.Name == "" {
+			l.Reportf("unit-name", loc, "Empty unit 'name' field found%s", label)
+		}
+		if u.Unit == 0 {
+			l.Reportf("unit-number", loc, "Empty unit 'unit' field found%s", label)
+		} else if u.Unit < 3 || u.Unit > 42 {
+			l.Reportf("unit-number", loc, "Invalid unit 'unit' field found%s: %d",
+				label, u.Unit)
+		}
+		LintUnitSequence(l, u.Unit, prevUnit, loc, label)
+		if len(u.Vocab) == 0 {
+			l.Reportf("empty-unit", loc, "Empty unit 'vocab' list found%s", label)
+			continue
+		}
+		if label == "" {
+			continue
+		}
+
+		for i, w := range u.Vocab {
+			(*stats)["words"]++
+			loc.Entry = i
+			loc.Line = w.Line
+			LintWord(l, w, loc, label)
+			homonym := 0
+			if w.Homonym != "" {
+				homonyms[wordID(w)]++
+				homonym = homonyms[wordID(w)]
+			}
+			LintIDs(l, w, homonym, loc, label, idmap)
+			LintImage(l, w, loc, label)
+			LintCrossUnit(l, w, loc, label, headwords)
+		}
+	}
+}
+
+// ParseVocab decodes the vocab dataset data read from filename, and
+// returns its units and inline suppressions (for unit number unit
+// only, if non-zero)
+func ParseVocab(data []byte, filename string, unit int) ([]VocabUnit, []*Suppression, error) {
+	var units []VocabUnit
+	err := dataset.Unmarshal(data, &units)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	for i := range units {
+		units[i].File = filename
+	}
+	sups, err := ParseSuppressions(data, filename, unit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return units, sups, nil
+}
@@ -0,0 +1,68 @@
+// Package logging configures structured logging (log/slog) for the mag
+// tools
+
+package logging
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+
+	"github.com/gavincarr/mag/dataset"
+)
+
+// Options are the logging options shared by the mag tools, for
+// embedding in their command line Options
+type Options struct {
+	LogLevel string `long:"log-level" choice:"debug" choice:"info" choice:"warn" choice:"error" default:"info" env:"MAG_LOG_LEVEL" description:"minimum level of log messages to write to stderr"`
+	LogJSON  bool   `long:"log-json" description:"write log messages as JSON"`
+}
+
+var levels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// Setup configures the default slog logger to write to stderr as
+// specified by opts. If verbose is set, debug messages are included.
+func Setup(opts Options, verbose bool) {
+	level, ok := levels[opts.LogLevel]
+	if !ok {
+		level = slog.LevelInfo
+	}
+	if verbose && level > slog.LevelDebug {
+		level = slog.LevelDebug
+	}
+
+	hopts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if opts.LogJSON {
+		handler = slog.NewJSONHandler(os.Stderr, hopts)
+	} else {
+		// Timestamps are just noise for interactive use
+		hopts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		}
+		handler = slog.NewTextHandler(os.Stderr, hopts)
+	}
+	slog.SetDefault(slog.New(handler))
+}
+
+// Fatal logs err at error level (logging each error separately for a
+// dataset.Errors) and exits with status 1
+func Fatal(err error) {
+	var errs dataset.Errors
+	if errors.As(err, &errs) {
+		for _, e := range errs {
+			slog.Error(e.Error())
+		}
+	} else {
+		slog.Error(err.Error())
+	}
+	os.Exit(1)
+}
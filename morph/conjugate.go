@@ -0,0 +1,363 @@
+package morph
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/gavincarr/mag/greek"
+)
+
+// VerbCells are the cells of a verb conjugation, in table order
+var VerbCells = []string{"1sg", "2sg", "3sg", "1pl", "2pl", "3pl"}
+
+// Conjugation names
+const (
+	presentActive = "present active indicative"
+	presentMiddle = "present middle/passive indicative"
+	futureActive  = "future active indicative"
+	futureMiddle  = "future middle indicative"
+	aoristActive  = "aorist active indicative"
+	aoristMiddle  = "aorist middle indicative"
+)
+
+var (
+	// Thematic endings, for omega verb presents and futures, and second
+	// aorists (after the augmented stem)
+	thematicActive = []ending{
+		{text: "ω", long: true}, {text: "εις", long: true}, {text: "ει", long: true},
+		{text: "ομεν"}, {text: "ετε"}, {text: "ουσι", nu: true},
+	}
+	thematicMiddle = []ending{
+		{text: "ομαι"}, {text: "ῃ", long: true}, {text: "εται"},
+		{text: "ομεθα"}, {text: "εσθε"}, {text: "ονται"},
+	}
+	secondAoristActive = []ending{
+		{text: "ον"}, {text: "ες"}, {text: "ε", nu: true},
+		{text: "ομεν"}, {text: "ετε"}, {text: "ον"},
+	}
+	secondAoristMiddle = []ending{
+		{text: "ομην", long: true}, {text: "ου", long: true}, {text: "ετο"},
+		{text: "ομεθα"}, {text: "εσθε"}, {text: "οντο"},
+	}
+	firstAoristActive = []ending{
+		{text: "α"}, {text: "ας"}, {text: "ε", nu: true},
+		{text: "αμεν"}, {text: "ατε"}, {text: "αν"},
+	}
+	firstAoristMiddle = []ending{
+		{text: "αμην", long: true}, {text: "ω", long: true}, {text: "ατο"},
+		{text: "αμεθα"}, {text: "ασθε"}, {text: "αντο"},
+	}
+
+	// Contract verb endings (with the contracted vowels accented, as
+	// they are in all the contracted indicative forms), by stem vowel
+	contractActive = map[string][]ending{
+		"α": accentedEndings("ῶ", "ᾷς", "ᾷ", "ῶμεν", "ᾶτε", "ῶσι"),
+		"ε": accentedEndings("ῶ", "εῖς", "εῖ", "οῦμεν", "εῖτε", "οῦσι"),
+		"ο": accentedEndings("ῶ", "οῖς", "οῖ", "οῦμεν", "οῦτε", "οῦσι"),
+	}
+	contractMiddle = map[string][]ending{
+		"α": accentedEndings("ῶμαι", "ᾷ", "ᾶται", "ώμεθα", "ᾶσθε", "ῶνται"),
+		"ε": accentedEndings("οῦμαι", "ῇ", "εῖται", "ούμεθα", "εῖσθε", "οῦνται"),
+		"ο": accentedEndings("οῦμαι", "οῖ", "οῦται", "ούμεθα", "οῦσθε", "οῦνται"),
+	}
+)
+
+// contractClasses are the contract verb classes and their stem vowels
+var contractClasses = [][2]string{
+	{greek.VerbContractA, "α"}, {greek.VerbContractE, "ε"}, {greek.VerbContractO, "ο"},
+}
+
+// irregulars are the forms of known irregular verbs, keyed by the
+// principal part they conjugate (whose name they take)
+var irregulars = map[string][]string{
+	// εἰμί, be
+	"εἰμί":   {"εἰμί", "εἶ", "ἐστί(ν)", "ἐσμέν", "ἐστέ", "εἰσί(ν)"},
+	"ἔσομαι": {"ἔσομαι", "ἔσῃ", "ἔσται", "ἐσόμεθα", "ἔσεσθε", "ἔσονται"},
+	// εἶμι, go
+	"εἶμι": {"εἶμι", "εἶ", "εἶσι(ν)", "ἴμεν", "ἴτε", "ἴασι(ν)"},
+	// φημί, say
+	"φημί": {"φημί", "φῄς", "φησί(ν)", "φαμέν", "φατέ", "φασί(ν)"},
+	// δίδωμι, give
+	"δίδωμι": {"δίδωμι", "δίδως", "δίδωσι(ν)", "δίδομεν", "δίδοτε", "διδόασι(ν)"},
+	"ἔδωκα":  {"ἔδωκα", "ἔδωκας", "ἔδωκε(ν)", "ἔδομεν", "ἔδοτε", "ἔδοσαν"},
+	// τίθημι, put
+	"τίθημι": {"τίθημι", "τίθης", "τίθησι(ν)", "τίθεμεν", "τίθετε", "τιθέασι(ν)"},
+	"ἔθηκα":  {"ἔθηκα", "ἔθηκας", "ἔθηκε(ν)", "ἔθεμεν", "ἔθετε", "ἔθεσαν"},
+	// ἵημι, send
+	"ἵημι": {"ἵημι", "ἵης", "ἵησι(ν)", "ἵεμεν", "ἵετε", "ἱᾶσι(ν)"},
+	"ἧκα":  {"ἧκα", "ἧκας", "ἧκε(ν)", "εἷμεν", "εἷτε", "εἷσαν"},
+	// ἵστημι, set up
+	"ἵστημι": {"ἵστημι", "ἵστης", "ἵστησι(ν)", "ἵσταμεν", "ἵστατε", "ἱστᾶσι(ν)"},
+	"ἔστην":  {"ἔστην", "ἔστης", "ἔστη", "ἔστημεν", "ἔστητε", "ἔστησαν"},
+	// δείκνυμι, show
+	"δείκνυμι": {"δείκνυμι", "δείκνυς", "δείκνυσι(ν)", "δείκνυμεν", "δείκνυτε", "δεικνύασι(ν)"},
+	// Root aorists
+	"ἔβην":  {"ἔβην", "ἔβης", "ἔβη", "ἔβημεν", "ἔβητε", "ἔβησαν"},
+	"ἔγνων": {"ἔγνων", "ἔγνως", "ἔγνω", "ἔγνωμεν", "ἔγνωτε", "ἔγνωσαν"},
+	"ἔδυν":  {"ἔδυν", "ἔδυς", "ἔδυ", "ἔδυμεν", "ἔδυτε", "ἔδυσαν"},
+}
+
+// accentedEndings returns endings for texts that carry their own accents
+// (the last taking a movable ν if it ends in -σι)
+func accentedEndings(texts ...string) []ending {
+	endings := make([]ending, len(texts))
+	for i, text := range texts {
+		endings[i] = ending{text: text, accented: true, nu: strings.HasSuffix(text, "σι")}
+	}
+	return endings
+}
+
+// Verb is a verb's principal parts, as used for its conjugations
+type Verb struct {
+	Pr      string   // present, e.g. λύω
+	Fu      string   // future, e.g. λύσω
+	Ao      string   // aorist, e.g. ἔλυσα
+	Classes []string // verb classes (see greek.VerbClasses), if known
+}
+
+// ParseVerb parses the principal parts entry gr, giving the present,
+// future, and aorist separated by commas (e.g. "λύω, λύσω, ἔλυσα"; later
+// parts may be omitted or empty). A full list of six principal parts, as
+// in pp.yml, is accepted too, ignoring the perfect, perfect middle, and
+// aorist passive.
+func ParseVerb(gr string) (Verb, error) {
+	var v Verb
+	parts := []*string{&v.Pr, &v.Fu, &v.Ao}
+	for i, part := range strings.Split(gr, ",") {
+		if i >= 6 {
+			return Verb{}, fmt.Errorf("%q: want at most six principal parts", gr)
+		}
+		if i < len(parts) {
+			*parts[i] = strings.TrimSpace(part)
+		}
+	}
+	if v.Pr == "" {
+		return Verb{}, fmt.Errorf("%q: want principal parts like \"λύω, λύσω, ἔλυσα\"", gr)
+	}
+	return v, nil
+}
+
+// firstForm returns the first form of the principal part entry part
+// (without any parentheses or "(stem X-)" annotation), or "" if it has
+// none
+func firstForm(part string) string {
+	words := strings.FieldsFunc(norm.NFC.String(part), func(r rune) bool {
+		return !greek.IsGreekLetter(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+	return words[0]
+}
+
+// cutEnding returns form without the ending (given without diacritics)
+// if it has it, keeping the breathings and subscripts of the rest, and
+// whether it did
+func cutEnding(form, ending string) (string, bool) {
+	if !strings.HasSuffix(greek.StripDiacritics(form), ending) {
+		return "", false
+	}
+	runes := []rune(greek.StripAccents(form))
+	return string(runes[:len(runes)-len([]rune(ending))]), true
+}
+
+// conjugation returns the paradigm name of stem plus each of endings,
+// with recessive accents (or none, if the principal part form is
+// unaccented)
+func conjugation(name, form, stem string, endings []ending) Paradigm {
+	want, _ := accentPosition(form)
+	p := Paradigm{Name: name}
+	for i, e := range endings {
+		f, _ := inflect(stem, e, 0, false, nil)
+		if want < 0 {
+			f = greek.StripAccents(f)
+		}
+		p.Forms = append(p.Forms, Form{Cell: VerbCells[i], Form: f})
+	}
+	return p
+}
+
+// irregular returns the paradigm name of the irregular form, if it is
+// one of irregulars
+func irregular(name, form string) (Paradigm, bool) {
+	forms, ok := irregulars[form]
+	if !ok {
+		return Paradigm{}, false
+	}
+	p := Paradigm{Name: name}
+	for i, f := range forms {
+		p.Forms = append(p.Forms, Form{Cell: VerbCells[i], Form: f})
+	}
+	return p, true
+}
+
+// middle reports whether the principal part form is middle (-μαι, -μην)
+func middle(form string) bool {
+	key := greek.StripDiacritics(form)
+	return strings.HasSuffix(key, "μαι") || strings.HasSuffix(key, "μην")
+}
+
+// contracted reports whether the -ω form is contracted, i.e. has a
+// circumflex on its ultima (e.g. τιμῶ or μενῶ)
+func contracted(form string) bool {
+	syl, circ := accentPosition(form)
+	return circ && syl == len(syllables(splitLetters(form)))-1
+}
+
+// withMiddle returns the active paradigm, followed by middle unless the
+// verb is deponent (whose active aorists and futures, e.g. ἦλθον, have
+// no middle counterparts)
+func withMiddle(deponent bool, active, middle Paradigm) []Paradigm {
+	if deponent {
+		return []Paradigm{active}
+	}
+	return []Paradigm{active, middle}
+}
+
+// conjugatePresent returns the present indicative paradigms of the
+// present form pr, of the verb classes classes
+func conjugatePresent(pr string, classes []string) ([]Paradigm, error) {
+	name := presentActive
+	if middle(pr) {
+		name = presentMiddle
+	}
+	if p, ok := irregular(name, pr); ok {
+		return []Paradigm{p}, nil
+	}
+	deponent := slices.Contains(classes, greek.VerbDeponent)
+	for _, c := range contractClasses {
+		class, vowel := c[0], c[1]
+		if !slices.Contains(classes, class) {
+			continue
+		}
+		if deponent {
+			if stem, ok := cutEnding(pr, vowel+"ομαι"); ok {
+				return []Paradigm{conjugation(presentMiddle, pr, stem, contractMiddle[vowel])}, nil
+			}
+		} else if stem, ok := cutEnding(pr, vowel+"ω"); ok {
+			return []Paradigm{
+				conjugation(presentActive, pr, stem, contractActive[vowel]),
+				conjugation(presentMiddle, pr, stem, contractMiddle[vowel]),
+			}, nil
+		}
+		return nil, fmt.Errorf("%s: contracted present forms can't be conjugated (give the uncontracted -%sω)",
+			pr, vowel)
+	}
+	if contracted(pr) {
+		return nil, fmt.Errorf("%s: contracted present forms can't be conjugated (give the uncontracted -άω, -έω, or -όω)", pr)
+	}
+	if slices.Contains(classes, greek.VerbOmega) {
+		if deponent {
+			if stem, ok := cutEnding(pr, "ομαι"); ok {
+				return []Paradigm{conjugation(presentMiddle, pr, stem, thematicMiddle)}, nil
+			}
+		} else if stem, ok := cutEnding(pr, "ω"); ok {
+			return []Paradigm{
+				conjugation(presentActive, pr, stem, thematicActive),
+				conjugation(presentMiddle, pr, stem, thematicMiddle),
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: unrecognised present", pr)
+}
+
+// conjugateFuture returns the future indicative paradigms of the future
+// form fu (including the contracted futures of liquid stems, e.g. μενῶ),
+// with middles for active futures unless the verb is deponent
+func conjugateFuture(fu string, deponent bool) ([]Paradigm, error) {
+	name := futureActive
+	if middle(fu) {
+		name = futureMiddle
+	}
+	if p, ok := irregular(name, fu); ok {
+		return []Paradigm{p}, nil
+	}
+	if stem, ok := cutEnding(fu, "ουμαι"); ok {
+		return []Paradigm{conjugation(futureMiddle, fu, stem, contractMiddle["ε"])}, nil
+	}
+	if stem, ok := cutEnding(fu, "ομαι"); ok {
+		return []Paradigm{conjugation(futureMiddle, fu, stem, thematicMiddle)}, nil
+	}
+	if stem, ok := cutEnding(fu, "ω"); ok {
+		if contracted(fu) {
+			return withMiddle(deponent,
+				conjugation(futureActive, fu, stem, contractActive["ε"]),
+				conjugation(futureMiddle, fu, stem, contractMiddle["ε"])), nil
+		}
+		return withMiddle(deponent,
+			conjugation(futureActive, fu, stem, thematicActive),
+			conjugation(futureMiddle, fu, stem, thematicMiddle)), nil
+	}
+	return nil, fmt.Errorf("%s: unrecognised future", fu)
+}
+
+// conjugateAorist returns the aorist indicative paradigms of the aorist
+// form ao: first (sigmatic) aorists in -α, second (thematic) aorists in
+// -ον, and the root aorists in irregulars, with middles for active
+// aorists unless the verb is deponent
+func conjugateAorist(ao string, deponent bool) ([]Paradigm, error) {
+	name := aoristActive
+	if middle(ao) {
+		name = aoristMiddle
+	}
+	if p, ok := irregular(name, ao); ok {
+		return []Paradigm{p}, nil
+	}
+	if stem, ok := cutEnding(ao, "αμην"); ok {
+		return []Paradigm{conjugation(aoristMiddle, ao, stem, firstAoristMiddle)}, nil
+	}
+	if stem, ok := cutEnding(ao, "ομην"); ok {
+		return []Paradigm{conjugation(aoristMiddle, ao, stem, secondAoristMiddle)}, nil
+	}
+	if stem, ok := cutEnding(ao, "α"); ok {
+		return withMiddle(deponent,
+			conjugation(aoristActive, ao, stem, firstAoristActive),
+			conjugation(aoristMiddle, ao, stem, firstAoristMiddle)), nil
+	}
+	if stem, ok := cutEnding(ao, "ον"); ok {
+		return withMiddle(deponent,
+			conjugation(aoristActive, ao, stem, secondAoristActive),
+			conjugation(aoristMiddle, ao, stem, secondAoristMiddle)), nil
+	}
+	return nil, fmt.Errorf("%s: unrecognised aorist", ao)
+}
+
+// Conjugate returns the present, future, and aorist indicative
+// paradigms of the verb v, for each of its principal parts given (using
+// the first of any alternates). Regular forms are accented recessively;
+// the irregular verbs in irregulars have their forms listed. The
+// paradigms of the parts that could be conjugated are returned even if
+// others couldn't, with an error listing the failures.
+func Conjugate(v Verb) ([]Paradigm, error) {
+	var paradigms []Paradigm
+	var errs []error
+	add := func(ps []Paradigm, err error) {
+		paradigms = append(paradigms, ps...)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	pr := firstForm(v.Pr)
+	classes := v.Classes
+	if len(classes) == 0 {
+		classes = greek.VerbClasses(pr)
+	}
+	deponent := slices.Contains(classes, greek.VerbDeponent)
+	if pr != "" {
+		add(conjugatePresent(pr, classes))
+	}
+	if fu := firstForm(v.Fu); fu != "" {
+		add(conjugateFuture(fu, deponent))
+	}
+	if ao := firstForm(v.Ao); ao != "" {
+		add(conjugateAorist(ao, deponent))
+	}
+	if len(paradigms) == 0 && len(errs) == 0 {
+		errs = append(errs, errors.New("no principal parts to conjugate"))
+	}
+	return paradigms, errors.Join(errs...)
+}
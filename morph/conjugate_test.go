@@ -0,0 +1,74 @@
+package morph
+
+import (
+	"testing"
+)
+
+func TestConjugate(t *testing.T) {
+	tests := []struct {
+		gr    string
+		forms map[string][]string // by conjugation name, in VerbCells order
+	}{
+		{"λύω, λύσω, ἔλυσα", map[string][]string{
+			presentActive: {"λύω", "λύεις", "λύει", "λύομεν", "λύετε", "λύουσι(ν)"},
+			presentMiddle: {"λύομαι", "λύῃ", "λύεται", "λυόμεθα", "λύεσθε", "λύονται"},
+			futureActive:  {"λύσω", "λύσεις", "λύσει", "λύσομεν", "λύσετε", "λύσουσι(ν)"},
+			futureMiddle:  {"λύσομαι", "λύσῃ", "λύσεται", "λυσόμεθα", "λύσεσθε", "λύσονται"},
+			aoristActive:  {"ἔλυσα", "ἔλυσας", "ἔλυσε(ν)", "ἐλύσαμεν", "ἐλύσατε", "ἔλυσαν"},
+			aoristMiddle:  {"ἐλυσάμην", "ἐλύσω", "ἐλύσατο", "ἐλυσάμεθα", "ἐλύσασθε", "ἐλύσαντο"},
+		}},
+		{"λείπω, λείψω, ἔλιπον", map[string][]string{
+			aoristActive: {"ἔλιπον", "ἔλιπες", "ἔλιπε(ν)", "ἐλίπομεν", "ἐλίπετε", "ἔλιπον"},
+			aoristMiddle: {"ἐλιπόμην", "ἐλίπου", "ἐλίπετο", "ἐλιπόμεθα", "ἐλίπεσθε", "ἐλίποντο"},
+		}},
+		{"τιμάω, τιμήσω, ἐτίμησα", map[string][]string{
+			presentActive: {"τιμῶ", "τιμᾷς", "τιμᾷ", "τιμῶμεν", "τιμᾶτε", "τιμῶσι(ν)"},
+			presentMiddle: {"τιμῶμαι", "τιμᾷ", "τιμᾶται", "τιμώμεθα", "τιμᾶσθε", "τιμῶνται"},
+		}},
+		// A full pp.yml entry, for a deponent with no active forms
+		{"βούλομαι, βουλήσομαι, —, —, βεβούλημαι, ἐβουλήθην", map[string][]string{
+			presentMiddle: {"βούλομαι", "βούλῃ", "βούλεται", "βουλόμεθα", "βούλεσθε", "βούλονται"},
+			presentActive: nil,
+			futureMiddle:  {"βουλήσομαι", "βουλήσῃ", "βουλήσεται", "βουλησόμεθα", "βουλήσεσθε", "βουλήσονται"},
+		}},
+	}
+
+	for _, tc := range tests {
+		v, err := ParseVerb(tc.gr)
+		if err != nil {
+			t.Errorf("ParseVerb(%q): %s", tc.gr, err)
+			continue
+		}
+		paradigms, err := Conjugate(v)
+		if err != nil {
+			t.Errorf("Conjugate(%q): %s", tc.gr, err)
+			continue
+		}
+		byName := make(map[string]Paradigm)
+		for _, p := range paradigms {
+			byName[p.Name] = p
+		}
+		for name, want := range tc.forms {
+			p, ok := byName[name]
+			if want == nil {
+				if ok {
+					t.Errorf("Conjugate(%q): unexpected %s", tc.gr, name)
+				}
+				continue
+			}
+			if !ok {
+				t.Errorf("Conjugate(%q): missing %s", tc.gr, name)
+				continue
+			}
+			checkForms(t, tc.gr+" "+name, p, VerbCells, want)
+		}
+	}
+}
+
+func TestParseVerbErrors(t *testing.T) {
+	for _, gr := range []string{"", "λύω, λύσω, ἔλυσα, λέλυκα, λέλυμαι, ἐλύθην, λυθήσομαι"} {
+		if _, err := ParseVerb(gr); err == nil {
+			t.Errorf("ParseVerb(%q): want error", gr)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package morph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gavincarr/mag/greek"
+)
+
+// NounCells are the cells of a noun declension, in table order
+var NounCells = []string{
+	"nom sg", "gen sg", "dat sg", "acc sg", "voc sg",
+	"nom pl", "gen pl", "dat pl", "acc pl", "voc pl",
+}
+
+// articleGenders maps the nominative articles (without diacritics) to
+// noun genders
+var articleGenders = map[string]string{
+	"ο": "masculine", "η": "feminine", "το": "neuter",
+}
+
+// declensionNames are the names of the declensions
+var declensionNames = map[string]string{
+	"1": "first declension", "2": "second declension", "3": "third declension",
+}
+
+// Noun is a noun's dictionary entry: its nominative and genitive
+// singular, and its gender(s)
+type Noun struct {
+	Nom     string
+	Gen     string
+	Genders []string // masculine, feminine, or neuter
+}
+
+// ParseNoun parses the noun dictionary entry gr (e.g. "λόγος, -ου, ὁ" or
+// "ἀγών, ἀγῶνος, ὁ"), whose genitive may be given in full or as an
+// ending to be joined to the nominative's stem
+func ParseNoun(gr string) (Noun, error) {
+	parts := strings.Split(gr, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	if len(parts) < 3 || parts[0] == "" || parts[1] == "" {
+		return Noun{}, fmt.Errorf("%q: want a noun entry like \"λόγος, -ου, ὁ\"", gr)
+	}
+	n := Noun{Nom: parts[0], Gen: parts[1]}
+	if ending, ok := strings.CutPrefix(n.Gen, "-"); ok {
+		n.Gen = joinEnding(n.Nom, ending)
+	}
+	for _, article := range parts[2:] {
+		gender, ok := articleGenders[greek.StripDiacritics(article)]
+		if !ok {
+			return Noun{}, fmt.Errorf("%q: invalid article %q", gr, article)
+		}
+		n.Genders = append(n.Genders, gender)
+	}
+	return n, nil
+}
+
+// joinEnding joins the genitive ending (e.g. "ατος") to the stem of
+// nom (e.g. σῶμα), overlapping at the last letter of nom matching the
+// ending's first (σώματος), or else replacing nom's final syllable
+// (πόλις, -εως: πόλεως). The result is unaccented unless the ending is.
+func joinEnding(nom, ending string) string {
+	nomKey := []rune(greek.StripDiacritics(nom))
+	endKey := []rune(greek.StripDiacritics(ending))
+	stem := []rune(greek.StripAccents(nom))
+	if len(endKey) == 0 || len(stem) != len(nomKey) {
+		return greek.StripAccents(nom) + ending
+	}
+	for i := len(nomKey) - 1; i > 0; i-- {
+		if nomKey[i] == endKey[0] {
+			return string(stem[:i]) + ending
+		}
+	}
+	i := len(nomKey)
+	for i > 1 && !isVowel(nomKey[i-1]) {
+		i--
+	}
+	for i > 1 && isVowel(nomKey[i-1]) {
+		i--
+	}
+	return string(stem[:i]) + ending
+}
+
+// Gender returns the (first) gender of n
+func (n Noun) Gender() string {
+	if len(n.Genders) == 0 {
+		return ""
+	}
+	return n.Genders[0]
+}
+
+// endings returns the stem, declension, and case endings of n (in
+// NounCells order, with nil for forms the same as the nominative
+// singular), or an error if its declension isn't recognised
+func (n Noun) endings() (string, string, []*ending, error) {
+	nom := greek.StripDiacritics(n.Nom)
+	gen := greek.StripDiacritics(n.Gen)
+	stemOf := func(s string, suffix int) string {
+		runes := []rune(greek.StripAccents(s))
+		return string(runes[:max(0, len(runes)-suffix)])
+	}
+	neuter := n.Gender() == "neuter"
+
+	switch {
+	// First declension feminines: τιμή, χώρα, θάλαττα
+	case (strings.HasSuffix(gen, "ης") || strings.HasSuffix(gen, "ας")) &&
+		(strings.HasSuffix(nom, "η") || strings.HasSuffix(nom, "α")):
+		long := strings.HasSuffix(nom, "η") || strings.HasSuffix(gen, "ας")
+		vowel := []rune(nom)[len([]rune(nom))-1]
+		genVowel := []rune(gen)[len([]rune(gen))-2]
+		return stemOf(n.Nom, 1), "1", []*ending{
+			nil,
+			{text: string(genVowel) + "ς", long: true, circ: true},
+			{text: string(genVowel) + string(iotaSub), long: true, circ: true},
+			{text: string(vowel) + "ν", long: long},
+			nil,
+			{text: "αι"},
+			{text: "ων", long: true, circ: true, ultima: true},
+			{text: "αις", long: true, circ: true},
+			{text: "ας", long: true},
+			{text: "αι"},
+		}, nil
+
+	// First declension masculines: πολίτης, νεανίας
+	case strings.HasSuffix(gen, "ου") &&
+		(strings.HasSuffix(nom, "ης") || strings.HasSuffix(nom, "ας")):
+		vowel := string([]rune(nom)[len([]rune(nom))-2])
+		voc := &ending{text: vowel, long: true}
+		if strings.HasSuffix(nom, "της") {
+			voc = &ending{text: "α"}
+		}
+		return stemOf(n.Nom, 2), "1", []*ending{
+			nil,
+			{text: "ου", long: true, circ: true},
+			{text: vowel + string(iotaSub), long: true, circ: true},
+			{text: vowel + "ν", long: true},
+			voc,
+			{text: "αι"},
+			{text: "ων", long: true, circ: true, ultima: true},
+			{text: "αις", long: true, circ: true},
+			{text: "ας", long: true},
+			{text: "αι"},
+		}, nil
+
+	// Second declension: λόγος, ὁδός, δῶρον
+	case strings.HasSuffix(gen, "ου") &&
+		(strings.HasSuffix(nom, "ος") || strings.HasSuffix(nom, "ον")):
+		if strings.HasSuffix(nom, "ον") {
+			plural := &ending{text: "α"}
+			return stemOf(n.Nom, 2), "2", []*ending{
+				nil,
+				{text: "ου", long: true, circ: true},
+				{text: "ω" + string(iotaSub), long: true, circ: true},
+				nil, nil,
+				plural,
+				{text: "ων", long: true, circ: true},
+				{text: "οις", long: true, circ: true},
+				plural, plural,
+			}, nil
+		}
+		return stemOf(n.Nom, 2), "2", []*ending{
+			nil,
+			{text: "ου", long: true, circ: true},
+			{text: "ω" + string(iotaSub), long: true, circ: true},
+			{text: "ον"},
+			{text: "ε"},
+			{text: "οι"},
+			{text: "ων", long: true, circ: true},
+			{text: "οις", long: true, circ: true},
+			{text: "ους", long: true},
+			{text: "οι"},
+		}, nil
+
+	// Third declension -ευς nouns: βασιλεύς, -έως
+	case strings.HasSuffix(nom, "ευς") && strings.HasSuffix(gen, "εως"):
+		return stemOf(n.Nom, 3), "3", []*ending{
+			nil,
+			{text: "έως", accented: true},
+			{text: "εῖ", accented: true},
+			{text: "έα", accented: true},
+			{text: "εῦ", accented: true},
+			{text: "ῆς", accented: true},
+			{text: "έων", accented: true},
+			{text: "εῦσι", accented: true, nu: true},
+			{text: "έας", accented: true},
+			{text: "ῆς", accented: true},
+		}, nil
+
+	// Third declension -ις/-υς, -εως nouns: πόλις, πῆχυς (whose -εως and
+	// -εων are accented as if short)
+	case (strings.HasSuffix(nom, "ις") || strings.HasSuffix(nom, "υς")) &&
+		strings.HasSuffix(gen, "εως") && stemOf(gen, 3) == stemOf(nom, 2):
+		vowel := string([]rune(nom)[len([]rune(nom))-2])
+		return stemOf(n.Nom, 2), "3", []*ending{
+			nil,
+			{text: "εως"},
+			{text: "ει", long: true},
+			{text: vowel + "ν"},
+			{text: vowel},
+			{text: "εις", long: true},
+			{text: "εων"},
+			{text: "εσι", nu: true},
+			{text: "εις", long: true},
+			{text: "εις", long: true},
+		}, nil
+
+	// Third declension neuter sigma stems: γένος, -ους
+	case neuter && strings.HasSuffix(nom, "ος") && strings.HasSuffix(gen, "ους"):
+		plural := &ending{text: "η", long: true}
+		return stemOf(n.Nom, 2), "3", []*ending{
+			nil,
+			{text: "ους", long: true},
+			{text: "ει", long: true},
+			nil, nil,
+			plural,
+			{text: "ων", long: true, circ: true, ultima: true},
+			{text: "εσι", nu: true},
+			plural, plural,
+		}, nil
+
+	// Third declension consonant stems: φύλαξ, -ακος; σῶμα, -ατος
+	case strings.HasSuffix(gen, "ος"):
+		stem := stemOf(n.Gen, 2)
+		if neuter {
+			plural := &ending{text: "α"}
+			return stem, "3", []*ending{
+				nil,
+				{text: "ος"},
+				{text: "ι"},
+				nil, nil,
+				plural,
+				{text: "ων", long: true, circ: true},
+				{text: "σι", nu: true},
+				plural, plural,
+			}, nil
+		}
+		// ντ-stems in -ων have the bare stem as their vocative (γέρων,
+		// γέρον)
+		var voc *ending
+		if strings.HasSuffix(nom, "ων") && strings.HasSuffix(stem, "οντ") {
+			voc = &ending{}
+		}
+		return stem, "3", []*ending{
+			nil,
+			{text: "ος"},
+			{text: "ι"},
+			{text: "α"},
+			voc,
+			{text: "ες"},
+			{text: "ων", long: true, circ: true},
+			{text: "σι", nu: true},
+			{text: "ας"},
+			{text: "ες"},
+		}, nil
+	}
+	return "", "", nil, fmt.Errorf("%s, %s: unrecognised declension", n.Nom, n.Gen)
+}
+
+// datPluralStem returns the third declension stem as changed before
+// the dative plural -σι: dentals and ν dropped (σώματ-: σώμα-σι), ντ
+// dropped with the vowel lengthened (γέροντ-: γέρου-σι), and labials
+// and velars combined into ψ and ξ (the σ of -σι is then dropped)
+func datPluralStem(stem string) (string, string) {
+	runes := []rune(stem)
+	if len(runes) < 2 {
+		return stem, "σι"
+	}
+	last := runes[len(runes)-1]
+	base := string(runes[:len(runes)-1])
+	switch last {
+	case 'τ', 'δ', 'θ':
+		if runes[len(runes)-2] == 'ν' && len(runes) > 2 {
+			base = string(runes[:len(runes)-2])
+			switch {
+			case strings.HasSuffix(base, "ο"):
+				base = strings.TrimSuffix(base, "ο") + "ου"
+			case strings.HasSuffix(base, "ε"):
+				base = strings.TrimSuffix(base, "ε") + "ει"
+			}
+		}
+		return base, "σι"
+	case 'ν':
+		return base, "σι"
+	case 'π', 'β', 'φ':
+		return base, "ψι"
+	case 'κ', 'γ', 'χ':
+		return base, "ξι"
+	}
+	return stem, "σι"
+}
+
+// Decline returns the declension of the noun n, with the forms for each
+// of NounCells. Its accents follow the nominative's as far as the
+// endings allow (with circumflexes on long accented genitive and dative
+// endings); vowels of ambiguous length (α, ι, υ) take their length
+// from the nominative's accent where it shows it, and are otherwise
+// taken as short, with the forms whose accent depends on it marked
+// Uncertain. Unaccented nominatives give unaccented forms.
+func Decline(n Noun) (Paradigm, error) {
+	stem, decl, endings, err := n.endings()
+	if err != nil {
+		return Paradigm{}, err
+	}
+	want, _ := accentPosition(n.Nom)
+	lengths := vowelLengths(n.Nom)
+	// Third declension monosyllables accent the genitive and dative
+	// endings (θήρ, θηρός)
+	mono := decl == "3" && len(syllables(splitLetters(n.Nom))) == 1
+
+	p := Paradigm{Name: declensionNames[decl]}
+	if len(n.Genders) > 0 {
+		p.Name += " " + strings.Join(n.Genders, "/")
+	}
+	for i, cell := range NounCells {
+		e := endings[i]
+		if e == nil {
+			p.Forms = append(p.Forms, Form{Cell: cell, Form: n.Nom})
+			continue
+		}
+		formStem, end := stem, *e
+		if cell == "dat pl" && decl == "3" && end.text == "σι" {
+			formStem, end.text = datPluralStem(stem)
+		}
+		if cell == "voc sg" && end.text == "" {
+			// A final τ can't end a word, so drops from the bare stem
+			formStem = strings.TrimSuffix(stem, "τ")
+		}
+		genDat := strings.HasPrefix(cell, "gen") || strings.HasPrefix(cell, "dat")
+		form, uncertain := inflect(formStem, end, want, mono && genDat, lengths)
+		if want < 0 {
+			form, uncertain = greek.StripAccents(form), false
+		}
+		p.Forms = append(p.Forms, Form{Cell: cell, Form: form, Uncertain: uncertain})
+	}
+	return p, nil
+}
@@ -0,0 +1,85 @@
+package morph
+
+import (
+	"testing"
+)
+
+// checkForms checks the forms of p against want, in table order
+func checkForms(t *testing.T, name string, p Paradigm, cells []string, want []string) {
+	t.Helper()
+	for i, cell := range cells {
+		if got := p.Form(cell); got != want[i] {
+			t.Errorf("%s %s: got %q, want %q", name, cell, got, want[i])
+		}
+	}
+}
+
+func TestDecline(t *testing.T) {
+	tests := []struct {
+		gr    string
+		name  string
+		forms []string // in NounCells order
+	}{
+		{"λόγος, -ου, ὁ", "second declension masculine", []string{
+			"λόγος", "λόγου", "λόγῳ", "λόγον", "λόγε",
+			"λόγοι", "λόγων", "λόγοις", "λόγους", "λόγοι",
+		}},
+		{"δῶρον, -ου, τό", "second declension neuter", []string{
+			"δῶρον", "δώρου", "δώρῳ", "δῶρον", "δῶρον",
+			"δῶρα", "δώρων", "δώροις", "δῶρα", "δῶρα",
+		}},
+		{"χώρα, -ας, ἡ", "first declension feminine", []string{
+			"χώρα", "χώρας", "χώρᾳ", "χώραν", "χώρα",
+			"χῶραι", "χωρῶν", "χώραις", "χώρας", "χῶραι",
+		}},
+		{"γέρων, -οντος, ὁ", "third declension masculine", []string{
+			"γέρων", "γέροντος", "γέροντι", "γέροντα", "γέρον",
+			"γέροντες", "γερόντων", "γέρουσι(ν)", "γέροντας", "γέροντες",
+		}},
+		{"λέων, λέοντος, ὁ", "third declension masculine", []string{
+			"λέων", "λέοντος", "λέοντι", "λέοντα", "λέον",
+			"λέοντες", "λεόντων", "λέουσι(ν)", "λέοντας", "λέοντες",
+		}},
+		{"φύλαξ, -ακος, ὁ", "third declension masculine", []string{
+			"φύλαξ", "φύλακος", "φύλακι", "φύλακα", "φύλαξ",
+			"φύλακες", "φυλάκων", "φύλαξι(ν)", "φύλακας", "φύλακες",
+		}},
+		{"θάλαττα, -ης, ἡ", "first declension feminine", []string{
+			"θάλαττα", "θαλάττης", "θαλάττῃ", "θάλατταν", "θάλαττα",
+			"θάλατται", "θαλαττῶν", "θαλάτταις", "θαλάττας", "θάλατται",
+		}},
+	}
+
+	for _, tc := range tests {
+		n, err := ParseNoun(tc.gr)
+		if err != nil {
+			t.Errorf("ParseNoun(%q): %s", tc.gr, err)
+			continue
+		}
+		p, err := Decline(n)
+		if err != nil {
+			t.Errorf("Decline(%q): %s", tc.gr, err)
+			continue
+		}
+		if p.Name != tc.name {
+			t.Errorf("Decline(%q): got name %q, want %q", tc.gr, p.Name, tc.name)
+		}
+		checkForms(t, tc.gr, p, NounCells, tc.forms)
+	}
+}
+
+func TestDeclineUncertain(t *testing.T) {
+	n, err := ParseNoun("πολίτης, -ου, ὁ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Decline(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range p.Forms {
+		if f.Cell == "voc sg" && !f.Uncertain {
+			t.Errorf("πολίτης voc sg %q: want uncertain accent", f.Form)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// Package morph generates Greek noun declensions and verb conjugations
+// from the forms given in the mag datasets
+
+package morph
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/gavincarr/mag/greek"
+)
+
+// Combining marks used in accentuation
+const (
+	acute      = '\u0301'
+	grave      = '\u0300'
+	circumflex = '\u0342'
+	diaeresis  = '\u0308'
+	iotaSub    = '\u0345'
+)
+
+// Form is a single form of a paradigm, keyed by its cell (e.g. "gen sg"
+// or "3pl")
+type Form struct {
+	Cell string
+	Form string
+	// Uncertain is set if the form's accent depends on the length of an
+	// α, ι, or υ that its lemma doesn't show (e.g. πολίτης, where the
+	// vocative is πολῖτα or πολίτα as ι is long or short)
+	Uncertain bool
+}
+
+// Paradigm is a generated declension or conjugation table
+type Paradigm struct {
+	Name  string // e.g. "second declension masculine"
+	Forms []Form // in table order
+}
+
+// Form returns the form of p for cell, or "" if it has none
+func (p Paradigm) Form(cell string) string {
+	for _, f := range p.Forms {
+		if f.Cell == cell {
+			return f.Form
+		}
+	}
+	return ""
+}
+
+// letter is a base letter with its combining marks, in NFD order
+type letter struct {
+	r     rune
+	marks []rune
+}
+
+// hasMark reports whether l has the combining mark m
+func (l letter) hasMark(m rune) bool {
+	for _, mark := range l.marks {
+		if mark == m {
+			return true
+		}
+	}
+	return false
+}
+
+// splitLetters returns the letters of s
+func splitLetters(s string) []letter {
+	var ls []letter
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) && len(ls) > 0 {
+			ls[len(ls)-1].marks = append(ls[len(ls)-1].marks, r)
+			continue
+		}
+		ls = append(ls, letter{r: r})
+	}
+	return ls
+}
+
+// joinLetters returns the NFC string of ls
+func joinLetters(ls []letter) string {
+	var b strings.Builder
+	for _, l := range ls {
+		b.WriteRune(l.r)
+		for _, m := range l.marks {
+			b.WriteRune(m)
+		}
+	}
+	return norm.NFC.String(b.String())
+}
+
+// isVowel reports whether r is a (lowercase) Greek vowel
+func isVowel(r rune) bool {
+	return strings.ContainsRune("αεηιουω", unicode.ToLower(r))
+}
+
+// syllable is a vowel nucleus of a word
+type syllable struct {
+	start  int  // index of the syllable's first vowel letter
+	accent int  // index of the letter taking the syllable's accent
+	long   bool // long by nature (η, ω, diphthongs, or marked long)
+}
+
+// syllables returns the syllables of the letters ls, in order
+func syllables(ls []letter) []syllable {
+	var syls []syllable
+	for i := 0; i < len(ls); i++ {
+		l := ls[i]
+		if !isVowel(l.r) {
+			continue
+		}
+		first := unicode.ToLower(l.r)
+		if i+1 < len(ls) && !l.hasMark(iotaSub) && !ls[i+1].hasMark(diaeresis) {
+			second := unicode.ToLower(ls[i+1].r)
+			if (second == 'ι' && strings.ContainsRune("αεου", first)) ||
+				(second == 'υ' && strings.ContainsRune("αεηο", first)) {
+				syls = append(syls, syllable{start: i, accent: i + 1, long: true})
+				i++
+				continue
+			}
+		}
+		long := first == 'η' || first == 'ω' || l.hasMark(iotaSub) || l.hasMark(circumflex)
+		syls = append(syls, syllable{start: i, accent: i, long: long})
+	}
+	return syls
+}
+
+// accentPosition returns the index of the accented syllable of word
+// (counting from its start), and whether it has a circumflex, or -1 if
+// word is unaccented
+func accentPosition(word string) (int, bool) {
+	ls := splitLetters(word)
+	for i, syl := range syllables(ls) {
+		for _, l := range ls[syl.start : syl.accent+1] {
+			if l.hasMark(circumflex) {
+				return i, true
+			}
+			if l.hasMark(acute) || l.hasMark(grave) {
+				return i, false
+			}
+		}
+	}
+	return -1, false
+}
+
+// ending is an inflectional ending, with the properties that determine
+// the accent of the forms it makes
+type ending struct {
+	text     string // the ending, without any movable ν
+	long     bool   // has a long final syllable, for accentuation
+	circ     bool   // takes a circumflex if accented (e.g. gen/dat -οῦ)
+	ultima   bool   // always accented on the ending (e.g. 1st decl -ῶν)
+	accented bool   // text carries its own accent (e.g. -εύς)
+	nu       bool   // takes a movable ν, written "(ν)"
+}
+
+// inflect returns stem plus the ending e, accented on syllable want
+// (counting from the start of the word, as in its lemma) as far as the
+// length of its ultima allows. Penults are treated as long if their
+// vowel is long by nature, or long in lengths (from the lemma's accent).
+// Accents on the ending (for mono, or e.ultima) are circumflex if e.circ.
+// It also reports whether the accent is uncertain: on a stem α, ι, or υ
+// in the penult, before a short ultima, whose length isn't in lengths.
+func inflect(stem string, e ending, want int, mono bool, lengths map[int]bool) (string, bool) {
+	stem = greek.StripAccents(stem)
+	word := stem + e.text
+	nu := ""
+	if e.nu {
+		nu = "(ν)"
+	}
+	if e.accented {
+		return norm.NFC.String(word) + nu, false
+	}
+	ls := splitLetters(word)
+	syls := syllables(ls)
+	n := len(syls)
+	if n == 0 {
+		return joinLetters(ls) + nu, false
+	}
+
+	idx := want
+	if mono || e.ultima || idx > n-1 {
+		idx = n - 1
+	}
+	if idx < n-3 {
+		idx = n - 3
+	}
+	if idx == n-3 && e.long {
+		idx = n - 2
+	}
+	if idx < 0 {
+		idx = 0
+	}
+
+	mark, uncertain := acute, false
+	switch {
+	case idx == n-1 && e.circ:
+		mark = circumflex
+	case idx == n-2 && n > 1 && !e.long:
+		if syls[idx].long || lengths[idx] {
+			mark = circumflex
+			break
+		}
+		_, known := lengths[idx]
+		uncertain = !known && syls[idx].start < len(splitLetters(stem)) &&
+			strings.ContainsRune("αιυ", unicode.ToLower(ls[syls[idx].start].r))
+	}
+	l := &ls[syls[idx].accent]
+	l.marks = append(l.marks, mark)
+	return joinLetters(ls) + nu, uncertain
+}
+
+// vowelLengths returns the lengths of the vowels of word shown by its
+// accent, by syllable index: long (true) if circumflexed (e.g. the α of
+// πρᾶξις), or short (false) if acute on the penult before a short ε or
+// ο ultima (e.g. the ι of βίος). Other vowels of ambiguous length (α, ι,
+// υ) are missing.
+func vowelLengths(word string) map[int]bool {
+	lengths := make(map[int]bool)
+	i, circ := accentPosition(word)
+	if circ {
+		lengths[i] = true
+		return lengths
+	}
+	ls := splitLetters(word)
+	syls := syllables(ls)
+	n := len(syls)
+	if i >= 0 && i == n-2 && !syls[n-1].long &&
+		strings.ContainsRune("εο", unicode.ToLower(ls[syls[n-1].start].r)) {
+		lengths[i] = false
+	}
+	return lengths
+}
@@ -0,0 +1,320 @@
+package pdf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultFonts are the font files tried by FindFont, in order: common
+// locations of fonts covering polytonic Greek
+var DefaultFonts = []string{
+	"/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf",
+	"/usr/share/fonts/dejavu/DejaVuSans.ttf",
+	"/usr/share/fonts/TTF/DejaVuSans.ttf",
+	"/usr/share/fonts/truetype/noto/NotoSans-Regular.ttf",
+	"/usr/share/fonts/noto/NotoSans-Regular.ttf",
+	"/usr/share/fonts/google-noto/NotoSans-Regular.ttf",
+	"/Library/Fonts/Arial Unicode.ttf",
+	"/System/Library/Fonts/Supplemental/Arial Unicode.ttf",
+}
+
+var reFontNameChars = regexp.MustCompile(`[^A-Za-z0-9+-]`)
+
+// Font is a TrueType or OpenType font, embedded whole in the documents
+// using it. Text is drawn by glyph id, so any characters the font has
+// glyphs for can be used.
+type Font struct {
+	Name       string // PostScript name
+	data       []byte
+	cff        bool // OpenType font with CFF outlines
+	unitsPerEm float64
+	ascent     float64 // in font units
+	descent    float64 // in font units, negative
+	bbox       [4]float64
+	glyphs     map[rune]uint16
+	advances   []uint16
+}
+
+// FindFont returns the first of DefaultFonts that exists, or "" if none
+// do
+func FindFont() string {
+	for _, filename := range DefaultFonts {
+		if _, err := os.Stat(filename); err == nil {
+			return filename
+		}
+	}
+	return ""
+}
+
+// LoadFont loads the TrueType or OpenType font file filename
+func LoadFont(filename string) (*Font, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	f, err := ParseFont(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return f, nil
+}
+
+// fontTables returns the tables of the sfnt font data, by tag
+func fontTables(data []byte) (map[string][]byte, error) {
+	if len(data) < 12 {
+		return nil, errors.New("not a TrueType or OpenType font")
+	}
+	switch string(data[:4]) {
+	case "\x00\x01\x00\x00", "true", "OTTO":
+	case "ttcf":
+		return nil, errors.New("font collections (.ttc) are not supported")
+	case "wOFF", "wOF2":
+		return nil, errors.New("web fonts (.woff/.woff2) are not supported")
+	default:
+		return nil, errors.New("not a TrueType or OpenType font")
+	}
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 12+16*numTables {
+		return nil, errors.New("truncated font table directory")
+	}
+	tables := make(map[string][]byte, numTables)
+	for i := 0; i < numTables; i++ {
+		entry := data[12+16*i:]
+		tag := string(entry[:4])
+		offset := int(binary.BigEndian.Uint32(entry[8:]))
+		length := int(binary.BigEndian.Uint32(entry[12:]))
+		if offset < 0 || length < 0 || offset+length > len(data) {
+			return nil, fmt.Errorf("font table %q is out of range", tag)
+		}
+		tables[tag] = data[offset : offset+length]
+	}
+	for _, tag := range []string{"head", "hhea", "hmtx", "maxp", "cmap"} {
+		if tables[tag] == nil {
+			return nil, fmt.Errorf("font has no %q table", tag)
+		}
+	}
+	return tables, nil
+}
+
+// ParseFont parses the TrueType or OpenType font data
+func ParseFont(data []byte) (*Font, error) {
+	tables, err := fontTables(data)
+	if err != nil {
+		return nil, err
+	}
+	f := &Font{data: data, cff: string(data[:4]) == "OTTO"}
+
+	head, hhea, maxp := tables["head"], tables["hhea"], tables["maxp"]
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 {
+		return nil, errors.New("truncated font header tables")
+	}
+	f.unitsPerEm = float64(binary.BigEndian.Uint16(head[18:]))
+	if f.unitsPerEm == 0 {
+		return nil, errors.New("font has no unitsPerEm")
+	}
+	for i := range f.bbox {
+		f.bbox[i] = float64(int16(binary.BigEndian.Uint16(head[36+2*i:])))
+	}
+	f.ascent = float64(int16(binary.BigEndian.Uint16(hhea[4:])))
+	f.descent = float64(int16(binary.BigEndian.Uint16(hhea[6:])))
+
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+	numMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	hmtx := tables["hmtx"]
+	if numMetrics == 0 || numMetrics > numGlyphs || len(hmtx) < 4*numMetrics {
+		return nil, errors.New("bad font hmtx table")
+	}
+	f.advances = make([]uint16, numGlyphs)
+	for g := range f.advances {
+		f.advances[g] = binary.BigEndian.Uint16(hmtx[4*min(g, numMetrics-1):])
+	}
+
+	f.glyphs, err = parseCmap(tables["cmap"], numGlyphs)
+	if err != nil {
+		return nil, err
+	}
+	f.Name = fontName(tables["name"])
+	return f, nil
+}
+
+// parseCmap returns the glyph ids by rune from the Unicode subtable of
+// the cmap table: format 12 (full Unicode) if present, else format 4 (the
+// Basic Multilingual Plane)
+func parseCmap(cmap []byte, numGlyphs int) (map[rune]uint16, error) {
+	if len(cmap) < 4 {
+		return nil, errors.New("truncated font cmap table")
+	}
+	var format4, format12 []byte
+	numTables := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < numTables && 4+8*i+8 <= len(cmap); i++ {
+		record := cmap[4+8*i:]
+		platform := binary.BigEndian.Uint16(record)
+		encoding := binary.BigEndian.Uint16(record[2:])
+		offset := int(binary.BigEndian.Uint32(record[4:]))
+		if platform != 0 && !(platform == 3 && (encoding == 1 || encoding == 10)) {
+			continue
+		}
+		if offset+2 > len(cmap) {
+			continue
+		}
+		switch binary.BigEndian.Uint16(cmap[offset:]) {
+		case 4:
+			format4 = cmap[offset:]
+		case 12:
+			format12 = cmap[offset:]
+		}
+	}
+
+	glyphs := make(map[rune]uint16)
+	add := func(r rune, g int) {
+		if g > 0 && g < numGlyphs {
+			glyphs[r] = uint16(g)
+		}
+	}
+	switch {
+	case len(format12) >= 16:
+		groups := int(binary.BigEndian.Uint32(format12[12:]))
+		for i := 0; i < groups && 16+12*i+12 <= len(format12); i++ {
+			group := format12[16+12*i:]
+			start := rune(binary.BigEndian.Uint32(group))
+			end := rune(binary.BigEndian.Uint32(group[4:]))
+			g := int(binary.BigEndian.Uint32(group[8:]))
+			for r := start; r <= end && r <= 0x10FFFF; r++ {
+				add(r, g+int(r-start))
+			}
+		}
+	case len(format4) >= 14:
+		segCount := int(binary.BigEndian.Uint16(format4[6:])) / 2
+		ends := 14
+		starts := ends + 2*segCount + 2
+		deltas := starts + 2*segCount
+		rangeOffsets := deltas + 2*segCount
+		if rangeOffsets+2*segCount > len(format4) {
+			return nil, errors.New("truncated font cmap subtable")
+		}
+		u16 := func(offset int) int {
+			if offset+2 > len(format4) {
+				return 0
+			}
+			return int(binary.BigEndian.Uint16(format4[offset:]))
+		}
+		for i := 0; i < segCount; i++ {
+			start, end := u16(starts+2*i), u16(ends+2*i)
+			delta, rangeOffset := u16(deltas+2*i), u16(rangeOffsets+2*i)
+			for c := start; c <= end && c < 0xFFFF; c++ {
+				if rangeOffset == 0 {
+					add(rune(c), (c+delta)&0xFFFF)
+					continue
+				}
+				g := u16(rangeOffsets + 2*i + rangeOffset + 2*(c-start))
+				if g != 0 {
+					add(rune(c), (g+delta)&0xFFFF)
+				}
+			}
+		}
+	default:
+		return nil, errors.New("font has no Unicode cmap subtable")
+	}
+	return glyphs, nil
+}
+
+// fontName returns the PostScript name from the name table, or a
+// generic name if it has none
+func fontName(name []byte) string {
+	if len(name) >= 6 {
+		count := int(binary.BigEndian.Uint16(name[2:]))
+		storage := int(binary.BigEndian.Uint16(name[4:]))
+		for i := 0; i < count && 6+12*i+12 <= len(name); i++ {
+			record := name[6+12*i:]
+			platform := binary.BigEndian.Uint16(record)
+			if binary.BigEndian.Uint16(record[6:]) != 6 {
+				continue
+			}
+			length := int(binary.BigEndian.Uint16(record[8:]))
+			offset := storage + int(binary.BigEndian.Uint16(record[10:]))
+			if offset+length > len(name) {
+				continue
+			}
+			raw := name[offset : offset+length]
+			s := string(raw)
+			if platform == 0 || platform == 3 {
+				units := make([]uint16, len(raw)/2)
+				for j := range units {
+					units[j] = binary.BigEndian.Uint16(raw[2*j:])
+				}
+				s = string(utf16.Decode(units))
+			}
+			if s = reFontNameChars.ReplaceAllString(s, ""); s != "" {
+				return s
+			}
+		}
+	}
+	return "MagFont"
+}
+
+// scale returns v in font units as thousandths of an em, the PDF glyph
+// space units
+func (f *Font) scale(v float64) float64 {
+	return v * 1000 / f.unitsPerEm
+}
+
+// Ascent returns the height of the font's ascender at size points
+func (f *Font) Ascent(size float64) float64 {
+	return f.ascent / f.unitsPerEm * size
+}
+
+// Descent returns the depth of the font's descender at size points, as
+// a positive number
+func (f *Font) Descent(size float64) float64 {
+	return -f.descent / f.unitsPerEm * size
+}
+
+// Width returns the width of s in the font at size points
+func (f *Font) Width(s string, size float64) float64 {
+	units := 0
+	for _, r := range norm.NFC.String(s) {
+		units += int(f.advances[f.glyphs[r]])
+	}
+	return float64(units) / f.unitsPerEm * size
+}
+
+// Missing returns the characters of s that the font has no glyphs for,
+// ignoring whitespace
+func (f *Font) Missing(s string) []rune {
+	var missing []rune
+	for _, r := range norm.NFC.String(s) {
+		if _, ok := f.glyphs[r]; !ok && !strings.ContainsRune(" \t\n", r) {
+			missing = append(missing, r)
+		}
+	}
+	return missing
+}
+
+// Wrap splits s into lines at most width wide in the font at size
+// points, breaking at spaces. Words wider than width get a line of their
+// own.
+func (f *Font) Wrap(s string, size, width float64) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		if line != "" && f.Width(line+" "+word, size) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
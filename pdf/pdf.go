@@ -0,0 +1,258 @@
+// Package pdf writes simple PDF documents of text and lines, in a single
+// embedded Unicode font, for printing the datasets.
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Points per millimetre, for converting page measurements
+const MM = 72 / 25.4
+
+// PageSizes maps paper names to their portrait width and height, in
+// points
+var PageSizes = map[string][2]float64{
+	"a4":     {210 * MM, 297 * MM},
+	"letter": {612, 792},
+}
+
+// LineStyle is the style of lines drawn with Page.Line
+type LineStyle struct {
+	Width float64 // line width, in points
+	Gray  float64 // 0 (black) to 1 (white)
+	Dash  float64 // dash length, in points, or 0 for a solid line
+}
+
+// Document is a PDF document of pages of the same size, with text in a
+// single font. Coordinates are in points from the bottom left corner of
+// the page, as in PDF.
+type Document struct {
+	Width, Height float64
+	Title         string
+	font          *Font
+	pages         []*Page
+	used          map[uint16]rune // glyphs used, with their characters
+}
+
+// New returns a new document with width x height pages, using font
+func New(width, height float64, font *Font) *Document {
+	return &Document{
+		Width: width, Height: height,
+		font: font, used: make(map[uint16]rune),
+	}
+}
+
+// Font returns the document font
+func (d *Document) Font() *Font {
+	return d.font
+}
+
+// Page is a page of a Document
+type Page struct {
+	doc     *Document
+	content bytes.Buffer
+}
+
+// AddPage adds a new blank page to the document, and returns it
+func (d *Document) AddPage() *Page {
+	p := &Page{doc: d}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// Pages returns the number of pages in the document
+func (d *Document) Pages() int {
+	return len(d.pages)
+}
+
+// Text draws s at size points, starting at x on the baseline y, in gray
+// (0 for black to 1 for white)
+func (p *Page) Text(x, y, size, gray float64, s string) {
+	var hex strings.Builder
+	for _, r := range norm.NFC.String(s) {
+		g := p.doc.font.glyphs[r]
+		if _, ok := p.doc.used[g]; !ok && g != 0 {
+			p.doc.used[g] = r
+		}
+		fmt.Fprintf(&hex, "%04X", g)
+	}
+	fmt.Fprintf(&p.content, "BT %s g /F1 %s Tf %s %s Td <%s> Tj ET\n",
+		num(gray), num(size), num(x), num(y), hex.String())
+}
+
+// TextCentered draws s at size points, centred on x on the baseline y
+func (p *Page) TextCentered(x, y, size, gray float64, s string) {
+	p.Text(x-p.doc.font.Width(s, size)/2, y, size, gray, s)
+}
+
+// Line draws a line from (x1, y1) to (x2, y2) in style
+func (p *Page) Line(x1, y1, x2, y2 float64, style LineStyle) {
+	dash := "[] 0 d"
+	if style.Dash > 0 {
+		dash = fmt.Sprintf("[%s] 0 d", num(style.Dash))
+	}
+	fmt.Fprintf(&p.content, "q %s G %s w %s %s %s m %s %s l S Q\n",
+		num(style.Gray), num(style.Width), dash,
+		num(x1), num(y1), num(x2), num(y2))
+}
+
+// num formats v as a PDF number, with at most 2 decimal places
+func num(v float64) string {
+	s := strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", v), "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// utf16Hex returns s as UTF-16BE, in hex
+func utf16Hex(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r > 0xFFFF {
+			r -= 0x10000
+			fmt.Fprintf(&b, "%04X%04X", 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+			continue
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	return b.String()
+}
+
+// pdfString returns s as a PDF text string: UTF-16BE with a byte order
+// mark, in hex
+func pdfString(s string) string {
+	return "<FEFF" + utf16Hex(s) + ">"
+}
+
+// pdfWriter writes numbered PDF objects, recording their offsets for
+// the cross-reference table
+type pdfWriter struct {
+	w       *bufio.Writer
+	n       int64
+	offsets []int64 // by object number - 1
+}
+
+func (pw *pdfWriter) printf(format string, args ...any) {
+	n, _ := fmt.Fprintf(pw.w, format, args...)
+	pw.n += int64(n)
+}
+
+// object writes object num with the dictionary or other value body
+func (pw *pdfWriter) object(num int, body string) {
+	pw.offsets[num-1] = pw.n
+	pw.printf("%d 0 obj\n%s\nendobj\n", num, body)
+}
+
+// stream writes object num as a Flate-compressed stream of data, with
+// any extra dictionary entries
+func (pw *pdfWriter) stream(num int, data []byte, extra string) {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	zw.Write(data)
+	zw.Close()
+	pw.offsets[num-1] = pw.n
+	pw.printf("%d 0 obj\n<< /Length %d /Filter /FlateDecode%s >>\nstream\n",
+		num, z.Len(), extra)
+	n, _ := pw.w.Write(z.Bytes())
+	pw.n += int64(n)
+	pw.printf("\nendstream\nendobj\n")
+}
+
+// toUnicode returns a ToUnicode CMap for the used glyphs, so text can
+// be copied and searched
+func (d *Document) toUnicode(gids []uint16) []byte {
+	var b bytes.Buffer
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	for i := 0; i < len(gids); i += 100 {
+		chunk := gids[i:min(i+100, len(gids))]
+		fmt.Fprintf(&b, "%d beginbfchar\n", len(chunk))
+		for _, g := range chunk {
+			fmt.Fprintf(&b, "<%04X> <%s>\n", g, utf16Hex(string(d.used[g])))
+		}
+		b.WriteString("endbfchar\n")
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return b.Bytes()
+}
+
+// WriteTo writes the document to w as a PDF file
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	f := d.font
+	gids := make([]uint16, 0, len(d.used))
+	for g := range d.used {
+		gids = append(gids, g)
+	}
+	sort.Slice(gids, func(i, j int) bool { return gids[i] < gids[j] })
+
+	// Objects: catalog, pages, info, Type0 font, CIDFont, descriptor,
+	// font file, ToUnicode CMap, then each page and its contents
+	const (
+		catalogObj = iota + 1
+		pagesObj
+		infoObj
+		fontObj
+		cidFontObj
+		descriptorObj
+		fontFileObj
+		toUnicodeObj
+		firstPageObj
+	)
+	pw := &pdfWriter{w: bufio.NewWriter(w)}
+	pw.offsets = make([]int64, firstPageObj-1+2*len(d.pages))
+	pw.printf("%%PDF-1.7\n%%\xe2\xe3\xcf\xd3\n")
+
+	pw.object(catalogObj, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObj))
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPageObj+2*i)
+	}
+	pw.object(pagesObj, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /MediaBox [0 0 %s %s] >>",
+		strings.Join(kids, " "), len(d.pages), num(d.Width), num(d.Height)))
+	pw.object(infoObj, fmt.Sprintf("<< /Title %s /Producer (mag) >>", pdfString(d.Title)))
+
+	pw.object(fontObj, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		f.Name, cidFontObj, toUnicodeObj))
+	var widths strings.Builder
+	for _, g := range gids {
+		fmt.Fprintf(&widths, "%d [%s] ", g, num(f.scale(float64(f.advances[g]))))
+	}
+	subtype, cidToGID, fontFile, fontFileExtra := "CIDFontType2", " /CIDToGIDMap /Identity", "FontFile2", fmt.Sprintf(" /Length1 %d", len(f.data))
+	if f.cff {
+		subtype, cidToGID, fontFile, fontFileExtra = "CIDFontType0", "", "FontFile3", " /Subtype /OpenType"
+	}
+	pw.object(cidFontObj, fmt.Sprintf("<< /Type /Font /Subtype /%s /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /DW %s /W [%s]%s >>",
+		subtype, f.Name, descriptorObj, num(f.scale(float64(f.advances[0]))), widths.String(), cidToGID))
+	pw.object(descriptorObj, fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%s %s %s %s] /ItalicAngle 0 /Ascent %s /Descent %s /CapHeight %s /StemV 80 /%s %d 0 R >>",
+		f.Name, num(f.scale(f.bbox[0])), num(f.scale(f.bbox[1])), num(f.scale(f.bbox[2])), num(f.scale(f.bbox[3])),
+		num(f.scale(f.ascent)), num(f.scale(f.descent)), num(f.scale(f.ascent)), fontFile, fontFileObj))
+	pw.stream(fontFileObj, f.data, fontFileExtra)
+	pw.stream(toUnicodeObj, d.toUnicode(gids), "")
+
+	for i, p := range d.pages {
+		pageObj := firstPageObj + 2*i
+		pw.object(pageObj, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+			pagesObj, fontObj, pageObj+1))
+		pw.stream(pageObj+1, p.content.Bytes(), "")
+	}
+
+	xref := pw.n
+	pw.printf("xref\n0 %d\n0000000000 65535 f \n", len(pw.offsets)+1)
+	for _, offset := range pw.offsets {
+		pw.printf("%010d 00000 n \n", offset)
+	}
+	pw.printf("trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(pw.offsets)+1, catalogObj, infoObj, xref)
+	return pw.n, pw.w.Flush()
+}
@@ -0,0 +1,117 @@
+package tts
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gavincarr/mag/httpclient"
+)
+
+// Azure Speech configuration environment variables
+const (
+	AzureKeyEnv    = "AZURE_SPEECH_KEY"
+	AzureRegionEnv = "AZURE_SPEECH_REGION"
+	AzureFormatEnv = "AZURE_SPEECH_FORMAT"
+)
+
+const (
+	// AzureVoice is the default Azure Speech voice (Modern Greek)
+	AzureVoice = "el-GR-NestorasNeural"
+	// azureFormat is the default Azure Speech output format (WAV)
+	azureFormat = "riff-24khz-16bit-mono-pcm"
+)
+
+// Azure is an Engine using the Azure Cognitive Services Speech REST
+// API, configured by the AZURE_SPEECH_* environment variables
+type Azure struct {
+	Key    string
+	Region string
+	Voice  string
+	Format string // X-Microsoft-OutputFormat audio format
+	Client *http.Client
+}
+
+// NewAzure returns an Azure engine for voice, configured from the
+// environment
+func NewAzure(voice string) (*Azure, error) {
+	a := &Azure{
+		Key:    os.Getenv(AzureKeyEnv),
+		Region: os.Getenv(AzureRegionEnv),
+		Voice:  voice,
+		Format: os.Getenv(AzureFormatEnv),
+		Client: httpclient.New(httpclient.DefaultConfig),
+	}
+	if a.Key == "" || a.Region == "" {
+		return nil, fmt.Errorf("the azure tts engine requires $%s and $%s",
+			AzureKeyEnv, AzureRegionEnv)
+	}
+	if a.Voice == "" {
+		a.Voice = AzureVoice
+	}
+	if a.Format == "" {
+		a.Format = azureFormat
+	}
+	return a, nil
+}
+
+// ssml returns the SSML request document for text
+func (a *Azure) ssml(text string) []byte {
+	// The voice name starts with its locale e.g. el-GR
+	lang := a.Voice
+	if parts := strings.SplitN(a.Voice, "-", 3); len(parts) == 3 {
+		lang = parts[0] + "-" + parts[1]
+	}
+	var b bytes.Buffer
+	b.WriteString(`<speak version="1.0" xmlns="http://www.w3.org/2001/10/synthesis" xml:lang="`)
+	xml.EscapeText(&b, []byte(lang))
+	b.WriteString(`"><voice name="`)
+	xml.EscapeText(&b, []byte(a.Voice))
+	b.WriteString(`">`)
+	xml.EscapeText(&b, []byte(text))
+	b.WriteString("</voice></speak>")
+	return b.Bytes()
+}
+
+// Synthesize requests audio of text from Azure and writes it to path
+func (a *Azure) Synthesize(text, path string) error {
+	url := fmt.Sprintf("https://%s.tts.speech.microsoft.com/cognitiveservices/v1",
+		a.Region)
+	req, err := http.NewRequest(http.MethodPost, url,
+		bytes.NewReader(a.ssml(text)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Ocp-Apim-Subscription-Key", a.Key)
+	req.Header.Set("Content-Type", "application/ssml+xml")
+	req.Header.Set("X-Microsoft-OutputFormat", a.Format)
+	req.Header.Set("User-Agent", "mag-utils")
+
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("azure tts: %s: %s", resp.Status,
+			bytes.TrimSpace(msg))
+	}
+
+	fh, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fh, resp.Body)
+	if cerr := fh.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
@@ -0,0 +1,29 @@
+package tts
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestAzureSSML(t *testing.T) {
+	a := &Azure{Voice: `el-GR-Athina"Neural`}
+	var doc struct {
+		XMLName xml.Name `xml:"speak"`
+		Lang    string   `xml:"lang,attr"`
+		Voice   struct {
+			Name string `xml:"name,attr"`
+			Text string `xml:",chardata"`
+		} `xml:"voice"`
+	}
+	err := xml.Unmarshal(a.ssml("λόγος & <ἔργον>"), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.XMLName.Space != "http://www.w3.org/2001/10/synthesis" {
+		t.Errorf("ssml: got namespace %q", doc.XMLName.Space)
+	}
+	if doc.Lang != "el-GR" || doc.Voice.Name != a.Voice || doc.Voice.Text != "λόγος & <ἔργον>" {
+		t.Errorf("ssml: got lang %q, voice %q, text %q",
+			doc.Lang, doc.Voice.Name, doc.Voice.Text)
+	}
+}
@@ -0,0 +1,15 @@
+package tts
+
+// EspeakVoice is the default espeak-ng voice (Ancient Greek)
+const EspeakVoice = "grc"
+
+// Espeak is an offline Engine using the espeak-ng command, which writes
+// WAV audio
+type Espeak struct {
+	Voice string
+}
+
+// Synthesize runs espeak-ng to write audio of text to path
+func (e *Espeak) Synthesize(text, path string) error {
+	return run("espeak-ng", "-v", e.Voice, "-w", path, "--", text)
+}
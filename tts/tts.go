@@ -0,0 +1,94 @@
+// Package tts provides text-to-speech engines for generating card audio
+
+package tts
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+)
+
+// Engine is a text-to-speech engine
+type Engine interface {
+	// Synthesize writes audio of text to the file path
+	Synthesize(text, path string) error
+}
+
+// Engines are the names of the available engines
+var Engines = []string{"espeak", "azure", "command"}
+
+// NetworkEngines are the names of the engines synthesizing speech over
+// the network (which can't be used offline)
+var NetworkEngines = []string{"azure"}
+
+// Config configures an Engine
+type Config struct {
+	Engine  string       // engine name
+	Voice   string       // engine-specific voice name (default: engine default)
+	Command string       // command line, for the command engine
+	Client  *http.Client // HTTP client, for network engines (default: httpclient.DefaultConfig)
+}
+
+// New returns the Engine configured by cfg
+func New(cfg Config) (Engine, error) {
+	switch cfg.Engine {
+	case "espeak":
+		voice := cfg.Voice
+		if voice == "" {
+			voice = EspeakVoice
+		}
+		return &Espeak{Voice: voice}, nil
+	case "azure":
+		a, err := NewAzure(cfg.Voice)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Client != nil {
+			a.Client = cfg.Client
+		}
+		return a, nil
+	case "command":
+		if cfg.Command == "" {
+			return nil, fmt.Errorf("the command tts engine requires a command")
+		}
+		return &Command{Command: cfg.Command}, nil
+	}
+	return nil, fmt.Errorf("unknown tts engine %q (want one of %s)",
+		cfg.Engine, strings.Join(Engines, ", "))
+}
+
+// run runs the command args, including its stderr in any error
+func run(args ...string) error {
+	cmd := exec.Command(args[0], args[1:]...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		if msg := bytes.TrimSpace(stderr.Bytes()); len(msg) > 0 {
+			return fmt.Errorf("%s: %w: %s", args[0], err, msg)
+		}
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	return nil
+}
+
+// Command is an Engine running an arbitrary command line, in which
+// {text} is replaced by the text to speak and {file} by the output path
+type Command struct {
+	Command string
+}
+
+// Synthesize runs the command to write audio of text to path
+func (c *Command) Synthesize(text, path string) error {
+	args := strings.Fields(c.Command)
+	if len(args) == 0 {
+		return fmt.Errorf("empty tts command")
+	}
+	for i, arg := range args {
+		arg = strings.ReplaceAll(arg, "{text}", text)
+		args[i] = strings.ReplaceAll(arg, "{file}", path)
+	}
+	return run(args...)
+}
@@ -0,0 +1,98 @@
+// Package watch re-runs a command whenever its input datasets change,
+// for a quick edit/preview loop while authoring
+
+package watch
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/gavincarr/mag/dataset"
+)
+
+// debounce is how long to wait for further changes before re-running,
+// since editors often write a file in several steps
+const debounce = 200 * time.Millisecond
+
+// Run calls fn, and then calls it again whenever any of the dataset
+// files or directories in paths change. Errors from fn are logged
+// rather than returned; Run only returns if watching fails.
+func Run(paths []string, fn func() error) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	// Watch the parent directories of files rather than the files
+	// themselves, so that files replaced by editors are still seen
+	files := make(map[string]bool)
+	dirs := make(map[string]bool)
+	for _, path := range paths {
+		if path == dataset.Stdin {
+			return errors.New("cannot watch stdin")
+		}
+		path = filepath.Clean(path)
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		dir := path
+		if !info.IsDir() {
+			dir = filepath.Dir(path)
+			files[path] = true
+		} else {
+			dirs[path] = true
+		}
+		err = w.Add(dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	// relevant reports whether a change to name should trigger a re-run
+	relevant := func(name string) bool {
+		name = filepath.Clean(name)
+		if files[name] {
+			return true
+		}
+		ext := filepath.Ext(name)
+		return dirs[filepath.Dir(name)] && (ext == ".yml" || ext == ".yaml")
+	}
+
+	run := func() {
+		if err := fn(); err != nil {
+			slog.Error(err.Error())
+		}
+		slog.Info("watching for changes", "paths", paths)
+	}
+	run()
+
+	var timer <-chan time.Time
+	for {
+		select {
+		case event, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op == fsnotify.Chmod || !relevant(event.Name) {
+				continue
+			}
+			slog.Debug("change detected", "file", event.Name, "op", event.Op.String())
+			timer = time.After(debounce)
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		case <-timer:
+			timer = nil
+			run()
+		}
+	}
+}